@@ -6,12 +6,17 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"asteria/internal/executor"
+	"asteria/internal/packs"
 	"asteria/internal/preview"
+	"asteria/internal/sandbox"
 	"asteria/internal/session"
 	"asteria/internal/skills"
 	"asteria/internal/storage"
+	"asteria/internal/tools"
 
 	"github.com/wailsapp/wails/v3/pkg/application"
 	"github.com/wailsapp/wails/v3/pkg/events"
@@ -28,6 +33,12 @@ type App struct {
 	settingsStore *storage.SettingsStore
 	usageStore    *storage.UsageStore
 	trustStore    *storage.TrustStore
+	searchLog     *storage.SearchLog
+	overlayStore  *storage.OverlayStore
+	packStore     *storage.PackStore
+	keyringStore  *storage.KeyringStore
+	policy        skills.Policy
+	policyErr     error
 }
 
 // NewApp creates a new App application struct
@@ -35,6 +46,23 @@ func NewApp() *App {
 	settingsStore, _ := storage.NewSettingsStore()
 	usageStore, _ := storage.NewUsageStore()
 	trustStore, _ := storage.NewTrustStore()
+	searchLog, _ := storage.NewSearchLog()
+	overlayStore, _ := storage.NewOverlayStore()
+	packStore, _ := storage.NewPackStore()
+	keyringStore, _ := storage.NewKeyringStore()
+	var publishers []skills.Publisher
+	if keyringStore != nil {
+		if keyring, err := keyringStore.Load(); err == nil {
+			publishers = keyring.Publishers
+		}
+	}
+	policy, policyErr := storage.LoadPolicy()
+	var disabledPacks []string
+	if packStore != nil {
+		if state, err := packStore.Load(); err == nil {
+			disabledPacks = state.DisabledPacks
+		}
+	}
 	settings, _ := settingsStore.Load()
 	sessionState, _ := session.NewState(session.SessionSnapshot{
 		Mode:          session.ModeBatch,
@@ -44,20 +72,48 @@ func NewApp() *App {
 	})
 	skillsDir, _ := storage.SkillsDir()
 	registry := skills.NewRegistry(skills.RegistryOptions{
-		EmbeddedFS:    assets,
-		EmbeddedRoot:  "skills/core",
-		DiskCoreRoot:  "skills/core",
-		CommunityRoot: skillsDir,
+		EmbeddedFS:        assets,
+		EmbeddedRoot:      "skills/core",
+		DiskCoreRoot:      "skills/core",
+		CommunityRoot:     skillsDir,
+		AppVersion:        appVersion,
+		DisabledPacks:     disabledPacks,
+		TrustedPublishers: publishers,
+		Policy:            policy,
 	})
+	registry.SetRankerOverrides(settings.Ranking)
+	registry.SetLocale(settings.Locale)
+	if overlayStore != nil {
+		if overlay, err := overlayStore.Load(); err == nil {
+			registry.SetOverlay(overlay)
+		}
+	}
 	exec := executor.NewExecutor(registry, sessionState, usageStore)
-	return &App{
+	if cli := exec.CLI(); cli != nil {
+		cli.Sandbox = sandbox.ParseMode(settings.Sandbox)
+		cli.MaxLimits = settings.Limits
+		cli.SetTools(settings.Tools)
+		cli.AllowedCommands = policy.AllowedCommands
+		_ = registry.SetToolChecker(cli)
+	}
+	app := &App{
 		registry:      registry,
 		session:       sessionState,
 		executor:      exec,
 		settingsStore: settingsStore,
 		usageStore:    usageStore,
 		trustStore:    trustStore,
+		searchLog:     searchLog,
+		overlayStore:  overlayStore,
+		packStore:     packStore,
+		keyringStore:  keyringStore,
+		policy:        policy,
+		policyErr:     policyErr,
 	}
+	// Skills re-run when a file is rebuilt pass the same trust checks as
+	// when they were applied.
+	exec.SetAuthorizer(app.authorizeSkill)
+	return app
 }
 
 // initWithApp is called after the app is created
@@ -78,6 +134,34 @@ func (a *App) initWithApp(app *application.App, window *application.WebviewWindo
 		}()
 	}
 
+	// Report skill progress per file.
+	if a.executor != nil {
+		a.executor.SetProgressHandler(func(fileID string, skillID string, value float64) {
+			window.EmitEvent("asteria:skill-progress", map[string]any{
+				"fileId":   fileID,
+				"skillId":  skillID,
+				"progress": value,
+			})
+		})
+	}
+
+	// Tell the user once per skill when it runs without the sandbox it
+	// would have used.
+	if a.executor != nil {
+		if cli := a.executor.CLI(); cli != nil {
+			var warned sync.Map
+			cli.OnUnsandboxed = func(skill skills.Skill, reason error) {
+				if _, seen := warned.LoadOrStore(skill.ID, true); seen {
+					return
+				}
+				window.EmitEvent("asteria:sandbox-unavailable", map[string]any{
+					"skillId": skill.ID,
+					"reason":  reason.Error(),
+				})
+			}
+		}
+	}
+
 	// Handle file drops via window events
 	window.OnWindowEvent(events.Common.WindowFilesDropped, func(event *application.WindowEvent) {
 		// Files are passed in the event context - emit to frontend
@@ -85,18 +169,249 @@ func (a *App) initWithApp(app *application.App, window *application.WebviewWindo
 	})
 }
 
-func (a *App) GetSkillTrust(skillID string) (bool, error) {
-	if a.trustStore == nil {
-		return false, nil
+// GetSkillTrust reports which elevated permissions of a skill are granted
+// and which are outstanding, and what changed since a stale approval.
+func (a *App) GetSkillTrust(skillID string) (storage.TrustReview, error) {
+	skill, ok := a.registry.GetByID(skillID)
+	if !ok {
+		return storage.TrustReview{}, fmt.Errorf("unknown skill")
 	}
-	return a.trustStore.IsTrusted(skillID)
+	return a.reviewSkillTrust(skill)
 }
 
+// SetSkillTrust allows every elevated permission of the skill always, or
+// revokes its grants.
 func (a *App) SetSkillTrust(skillID string, trusted bool) error {
+	if !trusted {
+		if a.trustStore == nil {
+			return nil
+		}
+		return a.trustStore.RevokeSkill(skillID)
+	}
+	return a.GrantSkillPermissions(skillID, nil, string(storage.GrantAlways))
+}
+
+// GrantSkillPermissions allows perms (all elevated ones when empty) for this
+// skill, "once" for its next run or "always".
+func (a *App) GrantSkillPermissions(skillID string, perms []string, scope string) error {
+	if a.trustStore == nil {
+		return fmt.Errorf("trust store unavailable")
+	}
+	skill, ok := a.registry.GetByID(skillID)
+	if !ok {
+		return fmt.Errorf("unknown skill")
+	}
+	grantScope, err := parseGrantScope(scope)
+	if err != nil {
+		return err
+	}
+	return a.trustStore.GrantSkill(skill, perms, grantScope)
+}
+
+// SetPackTrust allows every elevated permission the pack declares always, or
+// revokes the pack's grants.
+func (a *App) SetPackTrust(packID string, trusted bool) error {
+	if !trusted {
+		if a.trustStore == nil {
+			return nil
+		}
+		return a.trustStore.RevokePack(packID)
+	}
+	return a.GrantPackPermissions(packID, nil, string(storage.GrantAlways))
+}
+
+// GrantPackPermissions allows perms (all the pack declares when empty) for
+// every skill of a pack not signed by a trusted publisher.
+func (a *App) GrantPackPermissions(packID string, perms []string, scope string) error {
+	if a.trustStore == nil {
+		return fmt.Errorf("trust store unavailable")
+	}
+	pack, ok := a.registry.GetPack(packID)
+	if !ok {
+		return fmt.Errorf("unknown pack: %s", packID)
+	}
+	grantScope, err := parseGrantScope(scope)
+	if err != nil {
+		return err
+	}
+	return a.trustStore.GrantPack(pack, perms, grantScope)
+}
+
+func parseGrantScope(scope string) (storage.GrantScope, error) {
+	switch storage.GrantScope(strings.ToLower(strings.TrimSpace(scope))) {
+	case storage.GrantOnce:
+		return storage.GrantOnce, nil
+	case storage.GrantAlways, "":
+		return storage.GrantAlways, nil
+	default:
+		return "", fmt.Errorf("unknown grant scope: %s", scope)
+	}
+}
+
+// reviewSkillTrust decides whether a skill may use its elevated permissions.
+// Core skills and pack skills from a trusted publisher always may; others
+// need grants for the skill or its pack. A pipeline also needs those of
+// every step it runs.
+func (a *App) reviewSkillTrust(skill skills.Skill) (storage.TrustReview, error) {
+	review, err := a.reviewOwnTrust(skill)
+	if err != nil {
+		return storage.TrustReview{}, err
+	}
+	for _, step := range a.registry.PipelineSkills(skill)[1:] {
+		stepReview, err := a.reviewOwnTrust(step)
+		if err != nil {
+			return storage.TrustReview{}, err
+		}
+		if stepReview.Trusted {
+			continue
+		}
+		if review.Steps == nil {
+			review.Steps = make(map[string]storage.TrustReview)
+		}
+		review.Steps[step.ID] = stepReview
+		review.Outstanding = skills.NormalizePermissions(append(review.Outstanding, stepReview.Outstanding...))
+		review.Stale = review.Stale || stepReview.Stale
+		review.Trusted = false
+	}
+	return review, nil
+}
+
+// reviewOwnTrust reviews skill's own elevated permissions.
+func (a *App) reviewOwnTrust(skill skills.Skill) (storage.TrustReview, error) {
+	if skill.Source != skills.SkillSourceCommunity {
+		return storage.TrustReview{Trusted: true}, nil
+	}
+	if skill.PackID != "" && skill.Verification == skills.VerificationVerified {
+		return storage.TrustReview{Trusted: true, Granted: skills.ElevatedPermissions(skill.Permissions)}, nil
+	}
 	if a.trustStore == nil {
+		elevated := skills.ElevatedPermissions(skill.Permissions)
+		return storage.TrustReview{Trusted: len(elevated) == 0, Outstanding: elevated}, nil
+	}
+	return a.trustStore.Review(skill, a.skillPack(skill))
+}
+
+// authorizeSkill checks that skills may run, and for pipelines every step
+// they run. Chrome-like trust model: base permissions are allowed; elevated
+// permissions require a trusted publisher or an explicit user grant for
+// community skills, and packs modified after signing never run. The "allow
+// once" grants the run uses are reserved; release them with whether the run
+// happened.
+func (a *App) authorizeSkill(roots ...skills.Skill) (func(ran bool), error) {
+	none := func(bool) {}
+	isRoot := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		isRoot[root.ID] = true
+	}
+	seen := make(map[string]bool)
+	var subjects []storage.TrustSubject
+	for _, root := range roots {
+		for _, skill := range a.registry.PipelineSkills(root) {
+			if seen[skill.ID] || skill.Source != skills.SkillSourceCommunity {
+				continue
+			}
+			seen[skill.ID] = true
+			step := !isRoot[skill.ID]
+			if skill.Verification == skills.VerificationTampered {
+				return none, trustError(skill, step, fmt.Errorf("skill pack %s was modified after it was signed", skill.PackID))
+			}
+			if !skill.RequiresTrust() || (skill.PackID != "" && skill.Verification == skills.VerificationVerified) {
+				continue
+			}
+			if a.trustStore == nil {
+				return none, trustError(skill, step, fmt.Errorf("skill requires trust: %s", strings.Join(skills.ElevatedPermissions(skill.Permissions), ", ")))
+			}
+			subjects = append(subjects, storage.TrustSubject{Skill: skill, Pack: a.skillPack(skill)})
+		}
+	}
+	if len(subjects) == 0 {
+		return none, nil
+	}
+	reviews, release, err := a.trustStore.Reserve(subjects...)
+	if err != nil {
+		return none, err
+	}
+	for i, review := range reviews {
+		skill := subjects[i].Skill
+		switch {
+		case review.Trusted:
+			continue
+		case review.Stale:
+			return none, trustError(skill, !isRoot[skill.ID], fmt.Errorf("skill changed since it was trusted, approve it again: %s", reviewSummary(review)))
+		default:
+			return none, trustError(skill, !isRoot[skill.ID], fmt.Errorf("skill requires trust: %s", strings.Join(review.Outstanding, ", ")))
+		}
+	}
+	return release, nil
+}
+
+// trustError names the pipeline step err is about.
+func trustError(skill skills.Skill, step bool, err error) error {
+	if !step {
+		return err
+	}
+	return fmt.Errorf("pipeline step %s: %w", skill.ID, err)
+}
+
+func (a *App) skillPack(skill skills.Skill) *skills.Pack {
+	if skill.PackID == "" {
+		return nil
+	}
+	pack, ok := a.registry.GetPack(skill.PackID)
+	if !ok {
+		return nil
+	}
+	return &pack
+}
+
+// PolicyInfo is the effective administrator policy and the skills it blocks.
+type PolicyInfo struct {
+	Policy  skills.Policy         `json:"policy"`
+	Blocked []skills.BlockedSkill `json:"blocked"`
+	// Error is set when a policy.json could not be read.
+	Error string `json:"error,omitempty"`
+}
+
+// GetPolicy returns the merged policy.json and why skills are blocked.
+func (a *App) GetPolicy() PolicyInfo {
+	info := PolicyInfo{Policy: a.policy, Blocked: a.registry.Blocked()}
+	if a.policyErr != nil {
+		info.Error = a.policyErr.Error()
+	}
+	return info
+}
+
+// GetPublishers lists the trusted publisher keys.
+func (a *App) GetPublishers() []skills.Publisher {
+	if a.keyringStore == nil {
 		return nil
 	}
-	return a.trustStore.SetTrusted(skillID, trusted)
+	keyring, _ := a.keyringStore.Load()
+	return keyring.Publishers
+}
+
+// TrustPublisher adds a base64 ed25519 public key to the keyring and
+// re-verifies installed packs.
+func (a *App) TrustPublisher(name string, publicKey string) error {
+	if a.keyringStore == nil {
+		return fmt.Errorf("keyring unavailable")
+	}
+	keyring, err := a.keyringStore.AddPublisher(name, publicKey)
+	if err != nil {
+		return err
+	}
+	return a.registry.SetTrustedPublishers(keyring.Publishers)
+}
+
+func (a *App) RemovePublisher(publicKey string) error {
+	if a.keyringStore == nil {
+		return fmt.Errorf("keyring unavailable")
+	}
+	keyring, err := a.keyringStore.RemovePublisher(publicKey)
+	if err != nil {
+		return err
+	}
+	return a.registry.SetTrustedPublishers(keyring.Publishers)
 }
 
 // startup is called when the app starts (for v2 compatibility, but we use initWithApp now)
@@ -110,7 +425,353 @@ func (a *App) GetSession() session.SessionSnapshot {
 
 func (a *App) GetSkills(query string, inputTypes []string) ([]skills.Skill, error) {
 	usage := a.usageStore.All()
-	return a.registry.Search(query, inputTypes, usage), nil
+	chain := a.chainContext(inputTypes)
+	return a.registry.Search(query, inputTypes, usage, chain), nil
+}
+
+// ListPacks returns installed skill packs, including disabled ones.
+func (a *App) ListPacks() []skills.Pack {
+	return a.registry.Packs()
+}
+
+func (a *App) EnablePack(packID string) error {
+	return a.setPackEnabled(packID, true)
+}
+
+func (a *App) DisablePack(packID string) error {
+	return a.setPackEnabled(packID, false)
+}
+
+func (a *App) setPackEnabled(packID string, enabled bool) error {
+	if _, ok := a.registry.GetPack(packID); !ok {
+		return fmt.Errorf("unknown pack: %s", packID)
+	}
+	if a.packStore == nil {
+		return fmt.Errorf("pack store unavailable")
+	}
+	disabled, err := a.packStore.SetEnabled(packID, enabled)
+	if err != nil {
+		return err
+	}
+	return a.registry.SetDisabledPacks(disabled)
+}
+
+// RemovePack deletes a community pack folder from the skills directory.
+func (a *App) RemovePack(packID string) error {
+	pack, ok := a.registry.GetPack(packID)
+	if !ok {
+		return fmt.Errorf("unknown pack: %s", packID)
+	}
+	if pack.Source != skills.SkillSourceCommunity || pack.Dir == "" {
+		return fmt.Errorf("only community packs can be removed")
+	}
+	skillsDir, err := storage.SkillsDir()
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(skillsDir, pack.Dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("pack is outside the skills directory")
+	}
+	if err := os.RemoveAll(pack.Dir); err != nil {
+		return err
+	}
+	if a.packStore != nil {
+		_, _ = a.packStore.SetEnabled(packID, true)
+	}
+	return a.registry.Reload()
+}
+
+// InspectPack validates a pack archive without installing it so the user can
+// review its skills and permissions first.
+func (a *App) InspectPack(archivePath string) (packs.Inspection, error) {
+	installer, err := a.packInstaller()
+	if err != nil {
+		return packs.Inspection{}, err
+	}
+	return installer.Inspect(archivePath)
+}
+
+// InstallPack installs a .zip or .tar.gz pack into the skills directory,
+// replacing any installed version of the same pack. It is the second step
+// after InspectPack: acceptedPermissions are the elevated permissions the
+// user confirmed, and a pack asking for more is refused.
+func (a *App) InstallPack(archivePath string, acceptedPermissions []string) (packs.Inspection, error) {
+	installer, err := a.packInstaller()
+	if err != nil {
+		return packs.Inspection{}, err
+	}
+	insp, err := installer.Install(archivePath, acceptedPermissions)
+	if err != nil {
+		return packs.Inspection{}, err
+	}
+	return insp, a.registry.Reload()
+}
+
+// GetCatalog lists packs from the configured catalogs with their installed
+// versions.
+func (a *App) GetCatalog() ([]packs.CatalogEntry, error) {
+	return packs.NewCatalogClient().Entries(a.catalogSources(), a.registry.Packs())
+}
+
+// SetCatalogs replaces the configured catalog sources.
+func (a *App) SetCatalogs(sources []string) error {
+	var cleaned []string
+	for _, source := range sources {
+		if source = strings.TrimSpace(source); source != "" {
+			if err := packs.CheckSource(source); err != nil {
+				return err
+			}
+			cleaned = append(cleaned, source)
+		}
+	}
+	return a.updateSettings(func(s *storage.Settings) {
+		s.Catalogs = cleaned
+	})
+}
+
+// GetTools returns the registered tools CLI skills are run with.
+func (a *App) GetTools() tools.Tools {
+	if a.settingsStore == nil {
+		return tools.Tools{}
+	}
+	settings, err := a.settingsStore.Load()
+	if err != nil || settings.Tools == nil {
+		return tools.Tools{}
+	}
+	return settings.Tools
+}
+
+// SetTool registers the executable to use for a tool after checking it
+// exists and matches the given version constraint and checksum.
+func (a *App) SetTool(name string, tool tools.Tool) error {
+	name = tools.Name(strings.TrimSpace(name))
+	if name == "" || name == "." {
+		return fmt.Errorf("tool name is required")
+	}
+	tool.Path = strings.TrimSpace(tool.Path)
+	if _, err := (tools.Tools{name: tool}).Resolve(name); err != nil {
+		return err
+	}
+	return a.updateTools(func(t tools.Tools) { t[name] = tool })
+}
+
+// RemoveTool unregisters a tool; it is looked up on PATH again.
+func (a *App) RemoveTool(name string) error {
+	return a.updateTools(func(t tools.Tools) { delete(t, tools.Name(name)) })
+}
+
+func (a *App) updateTools(update func(tools.Tools)) error {
+	var registered tools.Tools
+	err := a.updateSettings(func(s *storage.Settings) {
+		if s.Tools == nil {
+			s.Tools = tools.Tools{}
+		}
+		update(s.Tools)
+		registered = s.Tools
+	})
+	if err != nil {
+		return err
+	}
+	if cli := a.executor.CLI(); cli != nil && registered != nil {
+		cli.SetTools(registered)
+		_ = a.registry.Reload()
+	}
+	return nil
+}
+
+// InstallCatalogPack downloads and installs a pack version from the catalogs.
+// An empty version installs the latest; an older one rolls the pack back.
+// acceptedPermissions are the elevated permissions the user confirmed from
+// the catalog listing; an archive asking for more is refused.
+func (a *App) InstallCatalogPack(packID string, version string, acceptedPermissions []string) (packs.Inspection, error) {
+	staging, err := storage.PackStagingDir()
+	if err != nil {
+		return packs.Inspection{}, err
+	}
+	archivePath, err := packs.NewCatalogClient().Download(a.catalogSources(), packID, version, staging)
+	if err != nil {
+		return packs.Inspection{}, err
+	}
+	defer os.Remove(archivePath)
+	return a.InstallPack(archivePath, acceptedPermissions)
+}
+
+func (a *App) packInstaller() (*packs.Installer, error) {
+	skillsDir, err := storage.SkillsDir()
+	if err != nil {
+		return nil, err
+	}
+	staging, err := storage.PackStagingDir()
+	if err != nil {
+		return nil, err
+	}
+	return &packs.Installer{
+		SkillsDir:         skillsDir,
+		StagingDir:        staging,
+		AppVersion:        appVersion,
+		TrustedPublishers: a.GetPublishers(),
+		Policy:            a.policy,
+	}, nil
+}
+
+func (a *App) catalogSources() []string {
+	if a.settingsStore == nil {
+		return nil
+	}
+	settings, err := a.settingsStore.Load()
+	if err != nil {
+		return nil
+	}
+	return settings.Catalogs
+}
+
+// SetLocale switches the language used to show and match skills.
+func (a *App) SetLocale(locale string) error {
+	a.registry.SetLocale(locale)
+	return a.updateSettings(func(s *storage.Settings) {
+		s.Locale = strings.TrimSpace(locale)
+	})
+}
+
+// GetCategories returns the declared skill categories, highest priority first.
+func (a *App) GetCategories() []skills.CategoryDef {
+	return a.registry.Categories()
+}
+
+// GetSkillsExplained is GetSkills with the per-component score of every
+// result, used by the ranking debug overlay.
+func (a *App) GetSkillsExplained(query string, inputTypes []string) ([]skills.ExplainedSkill, error) {
+	usage := a.usageStore.All()
+	chain := a.chainContext(inputTypes)
+	return a.registry.SearchExplained(query, inputTypes, usage, chain), nil
+}
+
+func (a *App) GetOverlay() (skills.Overlay, error) {
+	if a.overlayStore == nil {
+		return skills.Overlay{}, nil
+	}
+	return a.overlayStore.Load()
+}
+
+// AddSkillAlias adds a personal alias for a skill; pipelines are skills
+// too.
+func (a *App) AddSkillAlias(skillID string, alias string) (skills.Overlay, error) {
+	if _, ok := a.registry.GetByID(skillID); !ok {
+		return skills.Overlay{}, fmt.Errorf("unknown skill: %s", skillID)
+	}
+	return a.applyOverlay(func(store *storage.OverlayStore) (skills.Overlay, error) {
+		return store.AddAlias(skillID, alias)
+	})
+}
+
+func (a *App) RemoveSkillAlias(skillID string, alias string) (skills.Overlay, error) {
+	return a.applyOverlay(func(store *storage.OverlayStore) (skills.Overlay, error) {
+		return store.RemoveAlias(skillID, alias)
+	})
+}
+
+// SetSkillPinned pins a skill to the top of empty-query results.
+func (a *App) SetSkillPinned(skillID string, pinned bool) (skills.Overlay, error) {
+	return a.applyOverlay(func(store *storage.OverlayStore) (skills.Overlay, error) {
+		return store.SetPinned(skillID, pinned)
+	})
+}
+
+// SetSkillHidden hides a skill from search results.
+func (a *App) SetSkillHidden(skillID string, hidden bool) (skills.Overlay, error) {
+	return a.applyOverlay(func(store *storage.OverlayStore) (skills.Overlay, error) {
+		return store.SetHidden(skillID, hidden)
+	})
+}
+
+func (a *App) applyOverlay(change func(store *storage.OverlayStore) (skills.Overlay, error)) (skills.Overlay, error) {
+	if a.overlayStore == nil {
+		return skills.Overlay{}, fmt.Errorf("overlay store unavailable")
+	}
+	overlay, err := change(a.overlayStore)
+	if err != nil {
+		return skills.Overlay{}, err
+	}
+	a.registry.SetOverlay(overlay)
+	return overlay, nil
+}
+
+// RecordSearchChoice logs which result the user picked for a query so the
+// ranker can be evaluated offline (see cmd/rankeval). Only the day is kept,
+// and the query only when it starts a skill name or alias.
+func (a *App) RecordSearchChoice(query string, inputTypes []string, skillID string, position int) error {
+	if a.searchLog == nil {
+		return nil
+	}
+	event := skills.SearchEvent{
+		Time:            time.Now().UTC().Truncate(24 * time.Hour),
+		InputTypes:      inputTypes,
+		PreviousSkillID: a.chainContext(inputTypes).PreviousSkillID,
+		ChosenSkillID:   skillID,
+		Position:        position,
+	}
+	if a.registry.IsNamePrefix(query) {
+		event.Query = query
+	} else {
+		event.Redacted = true
+	}
+	return a.searchLog.Append(event)
+}
+
+// GetParamPresets returns the Tab-cycling presets for each param of a skill,
+// with the user's pinned and most used values ahead of the static ones.
+func (a *App) GetParamPresets(skillID string) (map[string][]skills.ParamPreset, error) {
+	var learned skills.ParamUsage
+	if a.usageStore != nil {
+		learned = a.usageStore.ParamUsage(skillID)
+	}
+	return a.registry.ParamPresets(skillID, learned), nil
+}
+
+func (a *App) PinParamPreset(skillID string, param string, value any, pinned bool) error {
+	if a.usageStore == nil {
+		return nil
+	}
+	return a.usageStore.PinParamValue(skillID, param, value, pinned)
+}
+
+func (a *App) ForgetParamPreset(skillID string, param string, value any) error {
+	if a.usageStore == nil {
+		return nil
+	}
+	return a.usageStore.ForgetParamValue(skillID, param, value)
+}
+
+// chainContext finds the most recently applied skill among files matching
+// inputTypes so empty-query results can suggest the usual next step.
+func (a *App) chainContext(inputTypes []string) skills.ChainContext {
+	chain := skills.ChainContext{Transitions: a.usageStore.Chains(), InputExt: skills.ChainInputExt(inputTypes)}
+	latest := ""
+	for _, file := range a.session.ListFiles() {
+		if len(inputTypes) > 0 && !containsFold(inputTypes, file.CurrentExtension) {
+			continue
+		}
+		n := len(file.AppliedSkills)
+		if n == 0 {
+			continue
+		}
+		last := file.AppliedSkills[n-1]
+		if last.AppliedAt >= latest {
+			latest = last.AppliedAt
+			chain.PreviousSkillID = last.SkillID
+		}
+	}
+	return chain
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
 }
 
 func (a *App) OpenFilesDialog() ([]string, error) {
@@ -128,6 +789,7 @@ func (a *App) OpenFilesDialog() ([]string, error) {
 
 func (a *App) AddFiles(paths []string) ([]session.WorkingFile, error) {
 	added := make([]session.WorkingFile, 0, len(paths))
+	var pending []*session.FileState
 	for _, path := range paths {
 		file, err := a.session.AddFile(path)
 		if err != nil {
@@ -137,49 +799,73 @@ func (a *App) AddFiles(paths []string) ([]session.WorkingFile, error) {
 			if previewURL, err := preview.ImagePreview(file.WorkingPath, 520); err == nil {
 				fileState.SetPreview(previewURL)
 			}
+			pending = append(pending, fileState)
 			added = append(added, fileState.Data())
 		} else {
 			added = append(added, file)
 		}
 	}
+	// Metadata can take a while (ffprobe, ImageMagick); files show up first
+	// and their metadata follows in asteria:file-metadata events.
+	if len(pending) > 0 {
+		go a.readMetadata(pending)
+	}
 	return added, nil
 }
 
+// readMetadata extracts the metadata of files one after another and emits
+// each result.
+func (a *App) readMetadata(files []*session.FileState) {
+	for _, fileState := range files {
+		if !a.executor.RefreshMetadata(a.ctx, fileState) || a.window == nil {
+			continue
+		}
+		data := fileState.Data()
+		a.window.EmitEvent("asteria:file-metadata", map[string]any{
+			"fileId":   data.ID,
+			"metadata": data.Metadata,
+		})
+	}
+}
+
 func (a *App) ExecuteSkill(fileIDs []string, skillID string, params map[string]any) (executor.SkillResult, error) {
+	if reason := a.registry.PolicyBlocks(skillID); reason != "" {
+		return executor.SkillResult{}, fmt.Errorf("skill blocked: %s", reason)
+	}
 	skill, ok := a.registry.GetByID(skillID)
 	if !ok {
 		return executor.SkillResult{}, fmt.Errorf("unknown skill")
 	}
 
-	// Chrome-like trust model: base permissions are allowed; elevated permissions
-	// require an explicit user trust decision for community skills.
-	if skill.Source == skills.SkillSourceCommunity && skill.RequiresTrust() {
-		trusted := false
-		if a.trustStore != nil {
-			if v, err := a.trustStore.IsTrusted(skillID); err == nil {
-				trusted = v
-			}
-		}
-		if !trusted {
-			elevated := skills.ElevatedPermissions(skill.Permissions)
-			return executor.SkillResult{}, fmt.Errorf("skill requires trust: %s", strings.Join(elevated, ", "))
-		}
+	release, err := a.authorizeSkill(skill)
+	if err != nil {
+		return executor.SkillResult{}, err
+	}
+	ran := false
+	defer func() { release(ran) }()
+
+	if !skill.Available() {
+		return executor.SkillResult{}, fmt.Errorf("skill unavailable: %s. %s", skill.Unavailable, skill.InstallHint)
 	}
 
 	if skill.IsMeta {
-		return a.executeMetaSkill(skillID, params, fileIDs)
+		result, err := a.executeMetaSkill(skillID, params, fileIDs)
+		ran = err == nil
+		return result, err
 	}
 
 	if len(fileIDs) == 0 {
 		return executor.SkillResult{Session: a.session.Snapshot()}, nil
 	}
-	updated, err := a.executor.ApplySkill(a.ctx, fileIDs, skillID, params)
+	updated, failures, err := a.executor.ApplySkill(a.ctx, fileIDs, skillID, params)
 	if err != nil {
 		return executor.SkillResult{}, err
 	}
+	ran = true
 	return executor.SkillResult{
 		UpdatedFiles: updated,
 		Session:      a.session.Snapshot(),
+		Errors:       failures,
 	}, nil
 }
 
@@ -222,12 +908,28 @@ func (a *App) ExportFiles(fileIDs []string) ([]session.ExportResult, error) {
 			last := data.AppliedSkills[len(data.AppliedSkills)-1]
 			skillName = last.SkillID
 		}
-		baseName := session.ExportName(a.session.NamingPattern(), data.Name, data.CurrentExtension, skillName)
+		var values map[string]string
+		if data.Metadata != nil {
+			values = data.Metadata.Values()
+		}
+		baseName := session.ExportName(a.session.NamingPattern(), data.Name, data.CurrentExtension, skillName, values)
 		outputPath := resolveOutputPath(outputFolder, baseName)
 		if err := session.CopyFile(data.WorkingPath, outputPath); err != nil {
 			return results, err
 		}
-		results = append(results, session.ExportResult{FileID: id, OutputPath: outputPath})
+		result := session.ExportResult{FileID: id, OutputPath: outputPath}
+		// Additional outputs follow the main file as <name>-2, <name>-3, ...
+		stem := strings.TrimSuffix(filepath.Base(outputPath), filepath.Ext(outputPath))
+		width := len(fmt.Sprint(len(data.Outputs) + 1))
+		for i, extra := range data.Outputs {
+			name := fmt.Sprintf("%s-%0*d%s", stem, width, i+2, filepath.Ext(extra))
+			extraPath := resolveOutputPath(outputFolder, name)
+			if err := session.CopyFile(extra, extraPath); err != nil {
+				return results, err
+			}
+			result.ExtraOutputs = append(result.ExtraOutputs, extraPath)
+		}
+		results = append(results, result)
 	}
 	return results, nil
 }
@@ -255,12 +957,10 @@ func (a *App) executeMetaSkill(skillID string, params map[string]any, fileIDs []
 		}
 		if folder != "" {
 			a.session.SetOutputFolder(folder)
-			if a.settingsStore != nil {
-				_ = a.settingsStore.Save(storage.Settings{
-					OutputFolder:  folder,
-					NamingPattern: a.session.NamingPattern(),
-					AccentColor:   a.session.AccentColor(),
-				})
+			if err := a.updateSettings(func(s *storage.Settings) {
+				s.OutputFolder = folder
+			}); err != nil {
+				return executor.SkillResult{}, err
 			}
 		}
 	case "set_naming_pattern":
@@ -268,12 +968,10 @@ func (a *App) executeMetaSkill(skillID string, params map[string]any, fileIDs []
 			pattern, _ := value.(string)
 			if strings.TrimSpace(pattern) != "" {
 				a.session.SetNamingPattern(pattern)
-				if a.settingsStore != nil {
-					_ = a.settingsStore.Save(storage.Settings{
-						OutputFolder:  a.session.OutputFolder(),
-						NamingPattern: pattern,
-						AccentColor:   a.session.AccentColor(),
-					})
+				if err := a.updateSettings(func(s *storage.Settings) {
+					s.NamingPattern = pattern
+				}); err != nil {
+					return executor.SkillResult{}, err
 				}
 			}
 		}
@@ -282,12 +980,10 @@ func (a *App) executeMetaSkill(skillID string, params map[string]any, fileIDs []
 			color, _ := value.(string)
 			if strings.TrimSpace(color) != "" {
 				a.session.SetAccentColor(color)
-				if a.settingsStore != nil {
-					_ = a.settingsStore.Save(storage.Settings{
-						OutputFolder:  a.session.OutputFolder(),
-						NamingPattern: a.session.NamingPattern(),
-						AccentColor:   color,
-					})
+				if err := a.updateSettings(func(s *storage.Settings) {
+					s.AccentColor = color
+				}); err != nil {
+					return executor.SkillResult{}, err
 				}
 				return executor.SkillResult{Session: a.session.Snapshot(), Message: "Accent updated"}, nil
 			}
@@ -309,6 +1005,45 @@ func (a *App) executeMetaSkill(skillID string, params map[string]any, fileIDs []
 	return executor.SkillResult{Session: a.session.Snapshot()}, nil
 }
 
+// reviewSummary condenses a stale trust review into one line for errors.
+func reviewSummary(review storage.TrustReview) string {
+	var parts []string
+	for _, p := range review.AddedPermissions {
+		parts = append(parts, "+"+p)
+	}
+	for _, p := range review.RemovedPermissions {
+		parts = append(parts, "-"+p)
+	}
+	for _, c := range review.Changes {
+		if c.Field != "permissions" {
+			parts = append(parts, c.Field)
+		}
+	}
+	if len(parts) == 0 {
+		return "contents changed"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// updateSettings persists a change to the settings file without dropping
+// fields the session does not track (such as ranking overrides). A settings
+// file that can't be read is reported rather than overwritten, so what it
+// holds is not lost.
+func (a *App) updateSettings(change func(s *storage.Settings)) error {
+	if a.settingsStore == nil {
+		return nil
+	}
+	settings, err := a.settingsStore.Load()
+	if err != nil {
+		return fmt.Errorf("settings not saved: %w", err)
+	}
+	settings.OutputFolder = a.session.OutputFolder()
+	settings.NamingPattern = a.session.NamingPattern()
+	settings.AccentColor = a.session.AccentColor()
+	change(&settings)
+	return a.settingsStore.Save(settings)
+}
+
 func resolveOutputPath(folder string, filename string) string {
 	outputPath := filepath.Join(folder, filename)
 	if _, err := os.Stat(outputPath); os.IsNotExist(err) {
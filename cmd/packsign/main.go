@@ -0,0 +1,84 @@
+// Command packsign creates publisher keys and signs skill packs.
+//
+// Usage:
+//
+//	go run ./cmd/packsign -keygen -key publisher.key
+//	go run ./cmd/packsign -key publisher.key -publisher "Acme" path/to/pack
+//
+// -keygen writes a base64 ed25519 private key to -key and prints the public
+// key users add to their keyring. Signing writes _signature.json into the
+// pack folder; sign again after any change to the pack's files.
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"asteria/internal/skills"
+)
+
+func main() {
+	keyPath := flag.String("key", "", "private key file")
+	keygen := flag.Bool("keygen", false, "generate a new key into -key")
+	publisher := flag.String("publisher", "", "publisher name recorded in the signature")
+	flag.Parse()
+
+	if *keyPath == "" {
+		log.Fatal("-key is required")
+	}
+	if *keygen {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*keyPath, []byte(base64.StdEncoding.EncodeToString(priv)+"\n"), 0o600); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(base64.StdEncoding.EncodeToString(pub))
+		return
+	}
+	if flag.NArg() != 1 {
+		log.Fatal("usage: packsign -key publisher.key -publisher NAME PACK_DIR")
+	}
+
+	key, err := readKey(*keyPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir := flag.Arg(0)
+	sig, err := skills.SignPack(os.DirFS(dir), ".", *publisher, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, skills.SignatureFilename), data, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("signed %s as %s (%s)\n", dir, *publisher, sig.PublicKey)
+}
+
+func readKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(raw) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%s: not an ed25519 private key", path)
+	}
+	return ed25519.PrivateKey(raw), nil
+}
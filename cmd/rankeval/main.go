@@ -0,0 +1,113 @@
+// Command rankeval replays the recorded command bar choices against the
+// skill ranker and prints MRR and top-k accuracy.
+//
+// Usage:
+//
+//	go run ./cmd/rankeval [-log search_events.jsonl] [-weights candidate.json]
+//
+// The "current" row ranks the way the app does: with the "ranking" overrides
+// and locale of settings.json and the user's aliases, pins and hidden skills.
+// -weights takes the same JSON as that "ranking" block and is reported next
+// to it, so a change can be compared on real behaviour.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"asteria/internal/skills"
+	"asteria/internal/storage"
+)
+
+func main() {
+	logPath := flag.String("log", "", "search event log (default: the app's search_events.jsonl)")
+	coreRoot := flag.String("core", "skills/core", "core skills directory")
+	communityRoot := flag.String("community", "", "community skills directory (default: the app's skills dir)")
+	weightsPath := flag.String("weights", "", "JSON ranker overrides to evaluate against the defaults")
+	flag.Parse()
+
+	searchLog, err := openLog(*logPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	events, err := searchLog.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(events) == 0 {
+		fmt.Printf("no events in %s\n", searchLog.Path())
+		return
+	}
+
+	community := *communityRoot
+	if community == "" {
+		if dir, err := storage.SkillsDir(); err == nil {
+			community = dir
+		}
+	}
+	registry := skills.NewRegistry(skills.RegistryOptions{
+		DiskCoreRoot:  *coreRoot,
+		CommunityRoot: community,
+	})
+	if err := configure(registry); err != nil {
+		log.Fatal(err)
+	}
+
+	base := registry.Evaluate(registry.Ranker(), events)
+	fmt.Printf("%d events from %s, %d without a query\n\n", len(events), searchLog.Path(), base.Redacted)
+	fmt.Printf("%-10s %8s %8s %8s %8s\n", "ranker", "MRR", "top1", "top3", "missing")
+	printReport("current", base)
+
+	if *weightsPath != "" {
+		data, err := os.ReadFile(*weightsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var overrides skills.RankerOverrides
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			log.Fatalf("parse %s: %v", *weightsPath, err)
+		}
+		printReport("candidate", registry.Evaluate(skills.DefaultRanker().WithOverrides(overrides), events))
+	}
+
+	fmt.Printf("\nMRR of the positions shown at the time: %.3f\n", base.RecordedMRR)
+}
+
+// configure applies the user's settings and overlay as the app does.
+func configure(registry *skills.Registry) error {
+	settingsStore, err := storage.NewSettingsStore()
+	if err != nil {
+		return err
+	}
+	settings, err := settingsStore.Load()
+	if err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	registry.SetRankerOverrides(settings.Ranking)
+	registry.SetLocale(settings.Locale)
+
+	overlayStore, err := storage.NewOverlayStore()
+	if err != nil {
+		return err
+	}
+	overlay, err := overlayStore.Load()
+	if err != nil {
+		return fmt.Errorf("overlay: %w", err)
+	}
+	registry.SetOverlay(overlay)
+	return nil
+}
+
+func openLog(path string) (*storage.SearchLog, error) {
+	if path != "" {
+		return storage.OpenSearchLog(path), nil
+	}
+	return storage.NewSearchLog()
+}
+
+func printReport(name string, r skills.EvalReport) {
+	fmt.Printf("%-10s %8.3f %8.3f %8.3f %8d\n", name, r.MRR, r.Top1, r.Top3, r.Missing)
+}
@@ -1,15 +1,61 @@
 // Wails v3 bindings
 import { App } from '../../bindings/asteria'
 import { Events } from '@wailsio/runtime'
+import type { Tool } from '../../bindings/asteria/internal/tools/models'
 
 // Re-export types from generated bindings
-export type { Skill, ParamDef } from '../../bindings/asteria/internal/skills/models'
+export type { Skill, ParamDef, ParamPreset, ExplainedSkill, ScoreBreakdown, CategoryDef, Overlay, Pack, PackManifest, Publisher } from '../../bindings/asteria/internal/skills/models'
 export type { SessionSnapshot, WorkingFile, ExportResult, AppliedSkill } from '../../bindings/asteria/internal/session/models'
+export type { Inspection, CatalogEntry, CatalogVersion } from '../../bindings/asteria/internal/packs/models'
+export type { TrustReview } from '../../bindings/asteria/internal/storage/models'
 export type { SkillResult } from '../../bindings/asteria/internal/executor/models'
+export type { FileError } from '../../bindings/asteria/internal/executor/models'
+export type { Error as SkillError } from '../../bindings/asteria/internal/drivers/models'
+export type { Tool } from '../../bindings/asteria/internal/tools/models'
+export type { Metadata as FileMetadata } from '../../bindings/asteria/internal/metadata/models'
 
 export const api = {
   getSession: () => App.GetSession(),
   getSkills: (query: string, inputTypes: string[]) => App.GetSkills(query, inputTypes),
+  listPacks: () => App.ListPacks(),
+  enablePack: (packId: string) => App.EnablePack(packId),
+  disablePack: (packId: string) => App.DisablePack(packId),
+  removePack: (packId: string) => App.RemovePack(packId),
+  inspectPack: (archivePath: string) => App.InspectPack(archivePath),
+  installPack: (archivePath: string, acceptedPermissions: string[]) =>
+    App.InstallPack(archivePath, acceptedPermissions),
+  getCatalog: () => App.GetCatalog(),
+  setCatalogs: (sources: string[]) => App.SetCatalogs(sources),
+  getTools: () => App.GetTools(),
+  setTool: (name: string, tool: Tool) => App.SetTool(name, tool),
+  removeTool: (name: string) => App.RemoveTool(name),
+  getPolicy: () => App.GetPolicy(),
+  getPublishers: () => App.GetPublishers(),
+  trustPublisher: (name: string, publicKey: string) => App.TrustPublisher(name, publicKey),
+  removePublisher: (publicKey: string) => App.RemovePublisher(publicKey),
+  getSkillTrust: (skillId: string) => App.GetSkillTrust(skillId),
+  setSkillTrust: (skillId: string, trusted: boolean) => App.SetSkillTrust(skillId, trusted),
+  grantSkillPermissions: (skillId: string, permissions: string[], scope: 'once' | 'always') =>
+    App.GrantSkillPermissions(skillId, permissions, scope),
+  grantPackPermissions: (packId: string, permissions: string[], scope: 'once' | 'always') =>
+    App.GrantPackPermissions(packId, permissions, scope),
+  setPackTrust: (packId: string, trusted: boolean) => App.SetPackTrust(packId, trusted),
+  installCatalogPack: (packId: string, version: string, acceptedPermissions: string[]) =>
+    App.InstallCatalogPack(packId, version, acceptedPermissions),
+  setLocale: (locale: string) => App.SetLocale(locale),
+  getCategories: () => App.GetCategories(),
+  getSkillsExplained: (query: string, inputTypes: string[]) => App.GetSkillsExplained(query, inputTypes),
+  recordSearchChoice: (query: string, inputTypes: string[], skillId: string, position: number) =>
+    App.RecordSearchChoice(query, inputTypes, skillId, position),
+  getOverlay: () => App.GetOverlay(),
+  addSkillAlias: (skillId: string, alias: string) => App.AddSkillAlias(skillId, alias),
+  removeSkillAlias: (skillId: string, alias: string) => App.RemoveSkillAlias(skillId, alias),
+  setSkillPinned: (skillId: string, pinned: boolean) => App.SetSkillPinned(skillId, pinned),
+  setSkillHidden: (skillId: string, hidden: boolean) => App.SetSkillHidden(skillId, hidden),
+  getParamPresets: (skillId: string) => App.GetParamPresets(skillId),
+  pinParamPreset: (skillId: string, param: string, value: unknown, pinned: boolean) =>
+    App.PinParamPreset(skillId, param, value, pinned),
+  forgetParamPreset: (skillId: string, param: string, value: unknown) => App.ForgetParamPreset(skillId, param, value),
   openFilesDialog: () => App.OpenFilesDialog(),
   addFiles: (paths: string[]) => App.AddFiles(paths),
   executeSkill: (fileIds: string[], skillId: string, params: Record<string, unknown>) =>
@@ -23,6 +69,17 @@ export const api = {
 // Wails v3 uses events for file drops: "common:WindowFilesDropped"
 export const FILE_DROP_EVENT = 'common:WindowFilesDropped'
 
+// Emitted while skills run: { fileId, skillId, progress } with progress in 0..1
+export const SKILL_PROGRESS_EVENT = 'asteria:skill-progress'
+
+// Emitted when a file's metadata has been read after it was added:
+// { fileId, metadata }
+export const FILE_METADATA_EVENT = 'asteria:file-metadata'
+
+// Emitted once per skill that runs unsandboxed because the sandbox is
+// unavailable: { skillId, reason }
+export const SANDBOX_UNAVAILABLE_EVENT = 'asteria:sandbox-unavailable'
+
 export const AppEvents = {
   on: (eventName: string, callback: (ev: { name: string; data: any }) => void): (() => void) => {
     return Events.On(eventName, callback)
@@ -10,6 +10,13 @@ export type ParamDef = {
   unit?: string
 }
 
+export type ParamPreset = {
+  value: unknown
+  label?: string
+  learned?: boolean
+  pinned?: boolean
+}
+
 export type Skill = {
   id: string
   name: string
@@ -17,11 +24,170 @@ export type Skill = {
   category: string
   description: string
   inputTypes: string[]
+  // Detected content types the skill accepts ("image/png", "image/*").
+  inputMimeTypes?: string[]
   outputType: string
   params: ParamDef[]
   driver: string
   isMeta: boolean
   dangerLevel: number
+  packId?: string
+  verification?: Verification
+  definitionHash?: string
+  requires?: ToolRequirement[]
+  // Set when a required tool is missing; the skill is listed but can't run.
+  unavailable?: string
+  installHint?: string
+}
+
+export type ToolRequirement = {
+  tool: string
+  version?: string
+  hint?: string
+}
+
+export type DefinitionChange = {
+  field: string
+  before?: string
+  after?: string
+}
+
+export type GrantScope = 'once' | 'always'
+
+export type Tool = {
+  path: string
+  version?: string
+  sha256?: string
+}
+
+export type TrustReview = {
+  trusted: boolean
+  outstanding?: string[]
+  granted?: string[]
+  stale: boolean
+  changes?: DefinitionChange[]
+  addedPermissions?: string[]
+  removedPermissions?: string[]
+  approvedVersion?: string
+  steps?: Record<string, TrustReview>
+}
+
+export type Verification = 'verified' | 'unverified' | 'tampered'
+
+export type Publisher = {
+  name: string
+  publicKey: string
+}
+
+export type PackManifest = {
+  id: string
+  name?: string
+  version: string
+  author?: string
+  description?: string
+  minAppVersion?: string
+  permissions?: string[]
+  skills?: string[]
+  categories?: CategoryDef[]
+}
+
+export type Pack = {
+  manifest: PackManifest
+  source: string
+  dir?: string
+  enabled: boolean
+  skillIds: string[]
+  verification: Verification
+  publisher?: string
+  publisherKey?: string
+  contentHash?: string
+  error?: string
+}
+
+export type Inspection = {
+  pack: Pack
+  elevatedPermissions: string[]
+  installedVersion?: string
+  warnings?: string
+}
+
+export type CatalogVersion = {
+  version: string
+  url: string
+  sha256?: string
+  minAppVersion?: string
+  permissions?: string[]
+}
+
+export type CatalogEntry = {
+  id: string
+  name?: string
+  author?: string
+  description?: string
+  versions: CatalogVersion[]
+  source: string
+  latestVersion: string
+  installedVersion?: string
+  updateAvailable: boolean
+}
+
+export type RuleSet = {
+  allow?: string[]
+  deny?: string[]
+}
+
+export type Policy = {
+  skills: RuleSet
+  packs: RuleSet
+  publishers: RuleSet
+  permissions: RuleSet
+  commands: RuleSet
+  allowedCommands?: string[]
+  maxDangerLevel?: number
+  coreOnly?: boolean
+}
+
+export type BlockedSkill = {
+  id: string
+  name: string
+  packId?: string
+  reason: string
+}
+
+export type PolicyInfo = {
+  policy: Policy
+  blocked: BlockedSkill[]
+  error?: string
+}
+
+export type CategoryDef = {
+  id: string
+  priority: number
+  icon?: string
+  description?: string
+}
+
+export type ScoreBreakdown = {
+  category: number
+  meta: number
+  fuzzy: number
+  alias: number
+  inputMatch: number
+  frecency: number
+  nextStep: number
+  total: number
+}
+
+export type ExplainedSkill = {
+  skill: Skill
+  score: ScoreBreakdown
+  pinned?: boolean
+}
+
+export type Overlay = {
+  aliases: Record<string, string[]>
+  pinned: string[]
+  hidden: string[]
 }
 
 export type AppliedSkill = {
@@ -40,6 +206,33 @@ export type WorkingFile = {
   size: number
   previewDataUrl: string
   appliedSkills: AppliedSkill[]
+  // Additional files the last skill produced (frames, pages, segments).
+  outputs?: string[]
+  // Content type detected from the file's bytes.
+  mimeType?: string
+  metadata?: FileMetadata
+}
+
+// What is known about a file's current content; unknown fields are omitted.
+export type FileMetadata = {
+  kind?: string
+  width?: number
+  height?: number
+  colorModel?: string
+  dpi?: number
+  cameraMake?: string
+  cameraModel?: string
+  // Capture time as "2006-01-02T15:04:05".
+  takenAt?: string
+  orientation?: number
+  // Seconds.
+  duration?: number
+  videoCodec?: string
+  audioCodec?: string
+  sampleRate?: number
+  channels?: number
+  pages?: number
+  extra?: Record<string, string>
 }
 
 export type SessionSnapshot = {
@@ -48,13 +241,39 @@ export type SessionSnapshot = {
   namingPattern: string
 }
 
+export type SkillError = {
+  category: 'missing_tool' | 'bad_input' | 'invalid_params' | 'timeout' | 'canceled' | 'permission_denied' | 'decode_failed' | 'resource_limit' | 'failed'
+  skillId?: string
+  // Pipeline step skill that failed.
+  step?: string
+  message: string
+  // Tail of the tool's output.
+  stderr?: string
+  fix?: string
+}
+
+export type FileError = {
+  fileId: string
+  name: string
+  error: SkillError
+}
+
 export type SkillResult = {
   updatedFiles: WorkingFile[]
   session: SessionSnapshot
   message?: string
+  // Files the skill failed on; they keep their previous state.
+  errors?: FileError[]
+}
+
+export type SkillProgress = {
+  fileId: string
+  skillId: string
+  progress: number
 }
 
 export type ExportResult = {
   fileId: string
   outputPath: string
+  extraOutputs?: string[]
 }
@@ -3,13 +3,20 @@ package drivers
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"asteria/internal/sandbox"
 	"asteria/internal/skills"
+	"asteria/internal/tools"
 )
 
 // CLIDriver executes declarative CLI skills.
@@ -19,9 +26,26 @@ import (
 type CLIDriver struct {
 	// Allowlist for untrusted community skills (base permission: tools.exec).
 	// Anything outside this list requires elevated permission tools.exec.any.
+	// Nil means the default list; an empty list allows no commands.
 	AllowedCommands []string
+
+	// Sandbox selects how commands are isolated; the zero value is
+	// sandbox.ModeAuto.
+	Sandbox sandbox.Mode
+	// MaxLimits are app-wide caps on the limits skills declare.
+	MaxLimits skills.ResourceLimits
+
+	// OnUnsandboxed, if set, is told when a command that should have been
+	// sandboxed runs directly because the sandbox is unavailable.
+	OnUnsandboxed func(skill skills.Skill, reason error)
+
+	toolsMu sync.RWMutex
+	tools   tools.Tools
 }
 
+// maxCapturedOutput bounds how much stdout/stderr is kept for errors.
+const maxCapturedOutput = 64 << 10
+
 func (d *CLIDriver) ID() string {
 	return "cli"
 }
@@ -32,23 +56,18 @@ func (d *CLIDriver) Supports(skill skills.Skill) bool {
 
 func (d *CLIDriver) Execute(ctx context.Context, inputPath string, outputPath string, skill skills.Skill, params map[string]any, progress ProgressFunc) error {
 	if skill.Executor.Type != "cli" {
-		return fmt.Errorf("cli driver requires executor.type=cli")
+		return newError(ErrorFailed, nil, "cli driver requires executor.type=cli")
 	}
 	if strings.TrimSpace(skill.Executor.Command) == "" {
-		return fmt.Errorf("cli driver requires executor.command")
+		return newError(ErrorFailed, nil, "cli driver requires executor.command")
 	}
 	if !hasPermission(skill.Permissions, skills.PermToolsExec) {
-		return fmt.Errorf("skill missing required permission: %s", skills.PermToolsExec)
+		return newError(ErrorPermissionDenied, nil, "skill missing required permission: %s", skills.PermToolsExec)
 	}
 
-	cmdName := skill.Executor.Command
-	base := strings.ToLower(filepath.Base(cmdName))
-	allowAny := hasPermission(skill.Permissions, skills.PermToolsExecAny)
-	if skill.Source == skills.SkillSourceCommunity && !allowAny {
-		allowed := d.allowed(base)
-		if !allowed {
-			return fmt.Errorf("community skill requires %s to run %q", skills.PermToolsExecAny, base)
-		}
+	cmdPath, err := d.resolve(skill, skill.Executor.Command)
+	if err != nil {
+		return err
 	}
 
 	ctxToUse := ctx
@@ -58,60 +77,392 @@ func (d *CLIDriver) Execute(ctx context.Context, inputPath string, outputPath st
 		defer cancel()
 	}
 
-	args := make([]string, 0, len(skill.Executor.Args))
-	for _, a := range skill.Executor.Args {
-		args = append(args, renderTemplate(a, inputPath, outputPath, params))
+	outputDir := filepath.Dir(outputPath)
+	captureDir := outputDir
+	if skill.Executor.Outputs != "" {
+		dir, err := os.MkdirTemp(outputDir, ".capture-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		captureDir = dir
+	}
+	workDir := outputDir
+	switch skill.Executor.WorkingDir {
+	case "input":
+		workDir = filepath.Dir(inputPath)
+	case "temp":
+		dir, err := os.MkdirTemp(outputDir, ".work-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		workDir = dir
 	}
 
-	cmd := exec.CommandContext(ctxToUse, cmdName, args...)
-	var stdout, stderr bytes.Buffer
+	builtins := map[string]string{"input": inputPath, "output": outputPath, "outputDir": captureDir}
+	tmpl, err := skills.ParseArgs(skill.Executor.Args, skill.Params)
+	if err != nil {
+		return newError(ErrorInvalidParams, err, "%v", err)
+	}
+	args, err := tmpl.Render(builtins, params, skill.Params)
+	if err != nil {
+		return newError(ErrorInvalidParams, err, "%v", err)
+	}
+	env, err := renderEnv(skill, builtins, params)
+	if err != nil {
+		return newError(ErrorInvalidParams, err, "%v", err)
+	}
+
+	limits := skill.Executor.Limits.Within(d.MaxLimits)
+	sbLimits := sandboxLimits(limits)
+	cmd, cleanup, err := d.command(ctxToUse, invocation{
+		path:   cmdPath,
+		args:   args,
+		dir:    workDir,
+		env:    env,
+		input:  inputPath,
+		output: outputPath,
+	}, skill, sbLimits)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	var stdout, stderr tailBuffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	var tracker *progressTracker
+	if spec := skill.Executor.Progress; spec != nil && progress != nil {
+		total := spec.Total
+		if total <= 0 && spec.TotalProbe != nil {
+			// Progress is best effort: without a total it stays indeterminate.
+			total, _ = d.probeTotal(ctxToUse, skill, invocation{
+				dir:    workDir,
+				env:    env,
+				input:  inputPath,
+				output: outputPath,
+			}, builtins, params, sbLimits)
+		}
+		tracker = newProgressTracker(*spec, total, progress)
+		if spec.Stream == "stdout" {
+			tracker.inner = &stdout
+			cmd.Stdout = tracker
+		} else {
+			tracker.inner = &stderr
+			cmd.Stderr = tracker
+		}
+	}
+	if skill.Executor.Stdin {
+		in, err := os.Open(inputPath)
+		if err != nil {
+			return newError(ErrorBadInput, err, "%v", err)
+		}
+		defer in.Close()
+		cmd.Stdin = in
+	}
+	// stdoutPath collects stdout next to the output and replaces it only once
+	// the command succeeded: the output may be the input it is still reading.
+	var stdoutFile *os.File
+	stdoutPath := ""
+	if skill.Executor.Stdout {
+		// The command writes the file itself, so the file size limit applies.
+		out, err := os.CreateTemp(outputDir, ".stdout-*"+filepath.Ext(outputPath))
+		if err != nil {
+			return err
+		}
+		stdoutFile, stdoutPath = out, out.Name()
+		defer os.Remove(stdoutPath)
+		defer out.Close()
+		cmd.Stdout = out
+	}
 
-	if progress != nil {
+	if progress != nil && tracker == nil {
 		progress(0.2)
 	}
-	err := cmd.Run()
+	err = cmd.Run()
 	if progress != nil {
 		progress(1.0)
 	}
 	if err != nil {
-		errText := strings.TrimSpace(stderr.String())
-		if errText == "" {
-			errText = strings.TrimSpace(stdout.String())
+		if errors.Is(ctxToUse.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return newError(ErrorTimeout, err, "timed out after %s", time.Duration(skill.Executor.TimeoutMs)*time.Millisecond)
+		}
+		if ctx.Err() != nil {
+			return newError(ErrorCanceled, ctx.Err(), "canceled")
+		}
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+			e := newError(ErrorMissingTool, err, "%v", err)
+			e.Fix = tools.InstallHint(skill.Executor.Command)
+			return e
+		}
+		if errors.Is(err, fs.ErrPermission) {
+			return newError(ErrorPermissionDenied, err, "%v", err)
 		}
-		if errText != "" {
-			return fmt.Errorf("cli skill failed: %s", errText)
+		if reason := sandbox.Exceeded(err, sbLimits, stderr.String()); reason != "" {
+			removeOutput(inputPath, outputPath)
+			return newError(ErrorResourceLimit, err, "%s", reason)
+		}
+		output := stderr.String()
+		if strings.TrimSpace(output) == "" {
+			output = stdout.String()
+		}
+		return classifyFailure(output, err)
+	}
+	if stdoutFile != nil {
+		if err := stdoutFile.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(stdoutPath, outputPath); err != nil {
+			return err
+		}
+	}
+	if skill.Executor.Outputs != "" {
+		if err := collectOutputs(captureDir, skill.Executor.Outputs, outputPath); err != nil {
+			return err
+		}
+	}
+	// Platforms without rlimits still get the output size checked.
+	if limits.OutputMB > 0 {
+		for _, path := range append([]string{outputPath}, Outputs(outputPath)...) {
+			if info, err := os.Stat(path); err == nil && info.Size() > int64(limits.OutputMB)<<20 {
+				removeOutput(inputPath, outputPath)
+				_ = os.RemoveAll(OutputsDir(outputPath))
+				return newError(ErrorResourceLimit, nil, "exceeded the output size limit of %d MB", limits.OutputMB)
+			}
 		}
-		return fmt.Errorf("cli skill failed: %w", err)
 	}
 	return nil
 }
 
-func (d *CLIDriver) allowed(cmdBase string) bool {
-	if len(d.AllowedCommands) == 0 {
-		// Conservative default allowlist.
-		d.AllowedCommands = []string{"ffmpeg", "magick", "convert", "identify"}
+// removeOutput deletes a partial output, unless it is the input: a step that
+// keeps the extension may be given its own working file as both.
+func removeOutput(inputPath string, outputPath string) {
+	if filepath.Clean(outputPath) == filepath.Clean(inputPath) {
+		return
 	}
-	for _, a := range d.AllowedCommands {
-		if strings.EqualFold(a, cmdBase) {
-			return true
+	_ = os.Remove(outputPath)
+}
+
+// renderEnv returns the skill's extra environment as KEY=value pairs.
+func renderEnv(skill skills.Skill, builtins map[string]string, params map[string]any) ([]string, error) {
+	if len(skill.Executor.Env) == 0 {
+		return nil, nil
+	}
+	env := make([]string, 0, len(skill.Executor.Env))
+	for name, tmpl := range skill.Executor.Env {
+		value, err := skills.RenderValue(tmpl, builtins, params, skill.Params)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", name, err)
 		}
+		env = append(env, name+"="+value)
 	}
-	return false
+	sort.Strings(env)
+	return env, nil
 }
 
-func renderTemplate(s string, inputPath string, outputPath string, params map[string]any) string {
-	out := strings.ReplaceAll(s, "{{input}}", inputPath)
-	out = strings.ReplaceAll(out, "{{output}}", outputPath)
-	if params == nil {
-		return out
+func sandboxLimits(l skills.ResourceLimits) sandbox.Limits {
+	return sandbox.Limits{
+		MemoryBytes:   uint64(l.MemoryMB) << 20,
+		CPUSeconds:    uint64(l.CPUSeconds),
+		FileSizeBytes: uint64(l.OutputMB) << 20,
+		Processes:     uint64(l.Processes),
 	}
-	for k, v := range params {
-		key := "{{" + k + "}}"
-		out = strings.ReplaceAll(out, key, fmt.Sprint(v))
+}
+
+// cappedBuffer keeps the first maxCapturedOutput bytes written to it and
+// discards the rest, so a chatty command cannot exhaust memory.
+type cappedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := maxCapturedOutput - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
 	}
-	return out
+	return len(p), nil
+}
+
+// tailBuffer keeps the last maxCapturedOutput bytes written to it, where
+// commands print the error that ended them, and discards older output.
+type tailBuffer struct {
+	buf  []byte
+	next int
+	full bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.buf == nil {
+		b.buf = make([]byte, maxCapturedOutput)
+	}
+	if len(p) > len(b.buf) {
+		p = p[len(p)-len(b.buf):]
+	}
+	for len(p) > 0 {
+		c := copy(b.buf[b.next:], p)
+		p = p[c:]
+		b.next += c
+		if b.next == len(b.buf) {
+			b.next, b.full = 0, true
+		}
+	}
+	return n, nil
+}
+
+func (b *tailBuffer) String() string {
+	if !b.full {
+		return string(b.buf[:b.next])
+	}
+	return string(b.buf[b.next:]) + string(b.buf[:b.next])
+}
+
+// invocation is a resolved command line and where it runs.
+type invocation struct {
+	path string
+	args []string
+	dir  string
+	// env holds KEY=value pairs added to the base environment.
+	env    []string
+	input  string
+	output string
+}
+
+// command builds the process for a skill. Unless the skill holds
+// files.anywhere, network and system, it runs sandboxed: it sees only the
+// input file, the output directory and system directories, has no network
+// without the network permission, and gets a scrubbed environment without
+// the system permission. Limits apply from the start either way.
+func (d *CLIDriver) command(ctx context.Context, inv invocation, skill skills.Skill, limits sandbox.Limits) (*exec.Cmd, func(), error) {
+	anywhere := hasPermission(skill.Permissions, skills.PermFilesAnywhere)
+	network := hasPermission(skill.Permissions, skills.PermNetwork)
+	system := hasPermission(skill.Permissions, skills.PermSystem)
+	direct := func() (*exec.Cmd, func(), error) {
+		// Without the sandbox the environment is still scrubbed unless the
+		// skill holds system.
+		env := sandbox.DirectEnv()
+		if system {
+			env = os.Environ()
+		}
+		cmd, err := sandbox.Direct(ctx, sandbox.Spec{
+			Command: inv.path,
+			Args:    inv.args,
+			Dir:     inv.dir,
+			Env:     append(env, inv.env...),
+			Limits:  limits,
+		})
+		return cmd, func() {}, err
+	}
+	if d.Sandbox == sandbox.ModeOff || (anywhere && network && system) {
+		return direct()
+	}
+	if err := sandbox.Available(); err != nil {
+		if d.Sandbox == sandbox.ModeRequired {
+			e := newError(ErrorFailed, err, "cannot be sandboxed: %v", err)
+			e.Fix = "Allow unprivileged user namespaces, or set sandbox to auto in settings."
+			return nil, nil, e
+		}
+		if d.OnUnsandboxed != nil {
+			d.OnUnsandboxed(skill, err)
+		}
+		return direct()
+	}
+
+	outputDir := filepath.Dir(inv.output)
+	spec := sandbox.Spec{
+		Command:        inv.path,
+		Args:           inv.args,
+		Dir:            inv.dir,
+		Env:            sandbox.Env(),
+		Writable:       []string{outputDir},
+		Network:        network,
+		HostFilesystem: anywhere,
+		Limits:         limits,
+	}
+	switch {
+	case inv.dir == filepath.Dir(inv.input) && inv.dir != outputDir:
+		spec.ReadOnly = []string{inv.dir}
+	case filepath.Dir(inv.input) != outputDir:
+		spec.ReadOnly = []string{inv.input}
+	}
+	if system {
+		spec.Env = os.Environ()
+	}
+	spec.Env = append(spec.Env, inv.env...)
+	return sandbox.Command(ctx, spec)
+}
+
+// SetTools replaces the tools registry used to resolve commands.
+func (d *CLIDriver) SetTools(t tools.Tools) {
+	d.toolsMu.Lock()
+	defer d.toolsMu.Unlock()
+	d.tools = t
+}
+
+// Tools returns the tools registry used to resolve commands.
+func (d *CLIDriver) Tools() tools.Tools {
+	d.toolsMu.RLock()
+	defer d.toolsMu.RUnlock()
+	return d.tools
+}
+
+// resolve checks a community skill may run command and returns the
+// executable to use.
+func (d *CLIDriver) resolve(skill skills.Skill, command string) (string, error) {
+	base := tools.Name(command)
+	allowAny := hasPermission(skill.Permissions, skills.PermToolsExecAny)
+	if skill.Source == skills.SkillSourceCommunity && !allowAny {
+		if filepath.IsAbs(command) {
+			return "", newError(ErrorPermissionDenied, nil, "community skill requires %s to run %s by path", skills.PermToolsExecAny, command)
+		}
+		if !d.allowed(base) {
+			return "", newError(ErrorPermissionDenied, nil, "community skill requires %s to run %q", skills.PermToolsExecAny, base)
+		}
+	}
+	cmdPath, err := d.Tools().Resolve(command)
+	if err != nil {
+		e := newError(ErrorMissingTool, err, "%v", err)
+		e.Fix = tools.InstallHint(command)
+		return "", e
+	}
+	return cmdPath, nil
+}
+
+// CheckTool reports whether a skill's required tool resolves and meets the
+// version constraint, so the registry can flag skills that would fail.
+//
+// Checking a version runs the tool, and skills are checked when they load,
+// before anyone trusted them. So only registered tools and allowed commands
+// found on PATH are probed; for other commands, such as an absolute path a
+// skill names, the constraint is left to fail at run time.
+func (d *CLIDriver) CheckTool(name string, version string) error {
+	t := d.Tools()
+	if _, registered := t.Lookup(name); !registered && (filepath.IsAbs(name) || !d.allowed(tools.Name(name))) {
+		return t.Locate(name)
+	}
+	return t.Check(name, version)
+}
+
+func (d *CLIDriver) InstallHint(name string) string {
+	return tools.InstallHint(name)
+}
+
+// allowed reports whether community skills may run cmdBase without
+// tools.exec.any. Without an explicit allowlist (set by policy), that is the
+// default list plus the tools the user registered.
+func (d *CLIDriver) allowed(cmdBase string) bool {
+	list := d.AllowedCommands
+	if list == nil {
+		if _, ok := d.Tools().Lookup(cmdBase); ok {
+			return true
+		}
+		list = skills.DefaultAllowedCommands
+	}
+	for _, a := range list {
+		if strings.EqualFold(a, cmdBase) {
+			return true
+		}
+	}
+	return false
 }
 
 func hasPermission(perms []string, perm string) bool {
@@ -0,0 +1,96 @@
+package drivers
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"asteria/internal/tools"
+)
+
+func TestTailBuffer(t *testing.T) {
+	line := strings.Repeat("x", 1000) + "\n"
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{name: "empty", want: ""},
+		{name: "short", writes: []string{"a", "bc"}, want: "abc"},
+		{
+			name:   "exactly full",
+			writes: []string{strings.Repeat("a", maxCapturedOutput)},
+			want:   strings.Repeat("a", maxCapturedOutput),
+		},
+		{
+			name:   "wraps around",
+			writes: []string{strings.Repeat("a", maxCapturedOutput-1), "bcd"},
+			want:   strings.Repeat("a", maxCapturedOutput-3) + "bcd",
+		},
+		{
+			name:   "one large write",
+			writes: []string{"old" + strings.Repeat("b", maxCapturedOutput)},
+			want:   strings.Repeat("b", maxCapturedOutput),
+		},
+		{
+			name:   "many writes",
+			writes: append(slices.Repeat([]string{line}, 100), "error: the end"),
+			want:   strings.Repeat(line, 100)[100*len(line)+len("error: the end")-maxCapturedOutput:] + "error: the end",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b tailBuffer
+			for _, w := range tt.writes {
+				n, err := b.Write([]byte(w))
+				if err != nil || n != len(w) {
+					t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(w))
+				}
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("String() is %d bytes ending %q, want %d bytes ending %q",
+					len(got), tail(got), len(tt.want), tail(tt.want))
+			}
+		})
+	}
+}
+
+func tail(s string) string {
+	return s[max(0, len(s)-20):]
+}
+
+func TestCheckToolDoesNotRunUntrustedCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	payload := filepath.Join(dir, "payload")
+	script := "#!/bin/sh\ntouch " + marker + "\necho 9.9.9\n"
+	if err := os.WriteFile(payload, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &CLIDriver{}
+	if err := d.CheckTool(payload, ">=1"); err != nil {
+		t.Errorf("CheckTool(%s) = %v, want the file to be found", payload, err)
+	}
+	if err := d.CheckTool(filepath.Join(dir, "missing"), ""); err == nil {
+		t.Error("CheckTool of a missing file succeeded")
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("CheckTool ran an unregistered absolute command")
+	}
+
+	// Registered tools are trusted by the user and probed.
+	d.SetTools(tools.Tools{"payload": {Path: payload, Version: ">=10"}})
+	if err := d.CheckTool("payload", ""); err == nil {
+		t.Error("CheckTool accepted a registered tool below its version constraint")
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Error("CheckTool did not probe a registered tool")
+	}
+}
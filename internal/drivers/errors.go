@@ -0,0 +1,177 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorCategory classifies why a skill failed so the UI can explain it.
+type ErrorCategory string
+
+const (
+	ErrorMissingTool      ErrorCategory = "missing_tool"
+	ErrorBadInput         ErrorCategory = "bad_input"
+	ErrorInvalidParams    ErrorCategory = "invalid_params"
+	ErrorTimeout          ErrorCategory = "timeout"
+	ErrorCanceled         ErrorCategory = "canceled"
+	ErrorPermissionDenied ErrorCategory = "permission_denied"
+	ErrorDecodeFailed     ErrorCategory = "decode_failed"
+	ErrorResourceLimit    ErrorCategory = "resource_limit"
+	ErrorFailed           ErrorCategory = "failed"
+)
+
+// maxErrorStderr bounds the command output kept on an Error; the end is
+// kept since tools print the cause last.
+const maxErrorStderr = 2 << 10
+
+// Error is a skill failure with enough context for the UI: what kind of
+// failure, which skill (and pipeline step) failed, the tail of the tool's
+// output and a suggested fix.
+type Error struct {
+	Category ErrorCategory `json:"category"`
+	// SkillID is the skill that was applied; Step is the pipeline step
+	// skill that failed, if it was a pipeline.
+	SkillID string `json:"skillId,omitempty"`
+	Step    string `json:"step,omitempty"`
+	Message string `json:"message"`
+	Stderr  string `json:"stderr,omitempty"`
+	Fix     string `json:"fix,omitempty"`
+
+	err error
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.SkillID != "" && e.Step != "":
+		return fmt.Sprintf("%s (step %s): %s", e.SkillID, e.Step, e.Message)
+	case e.SkillID != "":
+		return fmt.Sprintf("%s: %s", e.SkillID, e.Message)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// newError builds an Error with the default fix for its category.
+func newError(category ErrorCategory, err error, format string, args ...any) *Error {
+	return &Error{
+		Category: category,
+		Message:  fmt.Sprintf(format, args...),
+		Fix:      defaultFixes[category],
+		err:      err,
+	}
+}
+
+var defaultFixes = map[ErrorCategory]string{
+	ErrorMissingTool:      "Install the tool, or register its path under tools in settings.",
+	ErrorBadInput:         "Check that the file is a supported type and not damaged.",
+	ErrorInvalidParams:    "Adjust the skill's parameters and try again.",
+	ErrorTimeout:          "Try a smaller file, or raise the skill's timeout.",
+	ErrorPermissionDenied: "Grant the skill the permission it needs, or check file access.",
+	ErrorDecodeFailed:     "The file could not be read; convert it to a common format first.",
+	ErrorResourceLimit:    "Try a smaller file, or raise the limits in settings.",
+}
+
+// Errorf builds an Error outside a driver, such as for a pipeline step that
+// can't be found.
+func Errorf(category ErrorCategory, format string, args ...any) *Error {
+	return newError(category, nil, format, args...)
+}
+
+// AsError returns err as an *Error, wrapping plain errors as failures.
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if errors.Is(err, context.Canceled) {
+		return newError(ErrorCanceled, err, "canceled")
+	}
+	return newError(ErrorFailed, err, "%s", err.Error())
+}
+
+// WithSkill records which skill failed: the first call sets the skill, and
+// an enclosing pipeline moves it to Step and sets itself as the skill.
+func WithSkill(err error, skillID string) error {
+	if err == nil {
+		return nil
+	}
+	e := AsError(err)
+	out := *e
+	switch {
+	case out.SkillID == "":
+		out.SkillID = skillID
+	case out.SkillID != skillID:
+		if out.Step == "" {
+			out.Step = out.SkillID
+		}
+		out.SkillID = skillID
+	}
+	return &out
+}
+
+// stderrPatterns map typical tool messages to a category. They are checked
+// in order against the lowercased output.
+var stderrPatterns = []struct {
+	category ErrorCategory
+	needles  []string
+}{
+	{ErrorPermissionDenied, []string{"permission denied", "operation not permitted", "not authorized", "security policy"}},
+	{ErrorDecodeFailed, []string{"no decode delegate", "invalid data found", "could not find codec", "decoder", "corrupt", "improper image header", "not a jpeg file", "unsupported file format"}},
+	{ErrorBadInput, []string{"no such file", "does not exist", "unable to open image", "could not open", "unrecognized file format"}},
+	{ErrorInvalidParams, []string{"unrecognized option", "invalid argument", "invalid option", "unknown option", "error parsing"}},
+	{ErrorResourceLimit, []string{"cannot allocate memory", "out of memory", "resource temporarily unavailable", "cache resources exhausted"}},
+}
+
+// classifyFailure turns a failed command's output into an Error.
+func classifyFailure(output string, err error) *Error {
+	tail := tailLines(output, maxErrorStderr)
+	category := ErrorFailed
+	lower := strings.ToLower(tail)
+	for _, p := range stderrPatterns {
+		for _, needle := range p.needles {
+			if strings.Contains(lower, needle) {
+				category = p.category
+				break
+			}
+		}
+		if category != ErrorFailed {
+			break
+		}
+	}
+	message := lastLine(tail)
+	if message == "" {
+		message = err.Error()
+	}
+	e := newError(category, err, "%s", message)
+	e.Stderr = tail
+	return e
+}
+
+// tailLines keeps at most limit bytes from the end of s, starting at a line.
+func tailLines(s string, limit int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= limit {
+		return s
+	}
+	s = s[len(s)-limit:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 && i < len(s)-1 {
+		s = s[i+1:]
+	}
+	return "…" + s
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexAny(s, "\r\n"); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(s)
+}
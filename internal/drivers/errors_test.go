@@ -0,0 +1,84 @@
+package drivers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	exit := errors.New("exit status 1")
+	tests := []struct {
+		name        string
+		output      string
+		want        ErrorCategory
+		wantMessage string
+	}{
+		{
+			name:        "permission",
+			output:      "convert: attempt to perform an operation not allowed by the security policy `PDF'",
+			want:        ErrorPermissionDenied,
+			wantMessage: "convert: attempt to perform an operation not allowed by the security policy `PDF'",
+		},
+		{
+			name:        "decode",
+			output:      "[mov,mp4] moov atom not found\nin.mp4: Invalid data found when processing input\n",
+			want:        ErrorDecodeFailed,
+			wantMessage: "in.mp4: Invalid data found when processing input",
+		},
+		{
+			name:   "bad input",
+			output: "magick: unable to open image 'in.png': No such file or directory",
+			want:   ErrorBadInput,
+		},
+		{
+			name:   "invalid params",
+			output: "Unrecognized option 'crf2'.\nError splitting the argument list: Option not found",
+			want:   ErrorInvalidParams,
+		},
+		{
+			name:   "resource limit",
+			output: "convert: cache resources exhausted `big.tif'",
+			want:   ErrorResourceLimit,
+		},
+		{
+			name:        "earlier pattern wins",
+			output:      "corrupt header\npermission denied",
+			want:        ErrorPermissionDenied,
+			wantMessage: "permission denied",
+		},
+		{
+			name:        "unknown",
+			output:      "something went wrong\n",
+			want:        ErrorFailed,
+			wantMessage: "something went wrong",
+		},
+		{
+			name:        "no output",
+			want:        ErrorFailed,
+			wantMessage: "exit status 1",
+		},
+		{
+			name:   "cause past the error limit",
+			output: "permission denied\n" + strings.Repeat("progress line\n", maxErrorStderr/8) + "done",
+			want:   ErrorFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyFailure(tt.output, exit)
+			if got.Category != tt.want {
+				t.Errorf("Category = %q, want %q", got.Category, tt.want)
+			}
+			if tt.wantMessage != "" && got.Message != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", got.Message, tt.wantMessage)
+			}
+			if len(got.Stderr) > maxErrorStderr+len("…") {
+				t.Errorf("Stderr is %d bytes, want at most %d", len(got.Stderr), maxErrorStderr)
+			}
+			if !errors.Is(got, exit) {
+				t.Errorf("error does not wrap %v", exit)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"image/png"
+	"io/fs"
 	"math"
 	"path/filepath"
 	"strconv"
@@ -30,13 +31,16 @@ func (d *ImageDriver) Execute(ctx context.Context, inputPath string, outputPath
 	}
 	img, err := imaging.Open(inputPath)
 	if err != nil {
-		return err
+		if errors.Is(err, fs.ErrNotExist) {
+			return newError(ErrorBadInput, err, "%v", err)
+		}
+		return newError(ErrorDecodeFailed, err, "cannot decode image: %v", err)
 	}
 	switch skill.ID {
 	case "resize":
 		percent := readFloat(params, "percent", 100)
 		if percent <= 0 {
-			return errors.New("resize percent must be greater than 0")
+			return newError(ErrorInvalidParams, nil, "resize percent must be greater than 0")
 		}
 		bounds := img.Bounds()
 		width := int(math.Max(1, float64(bounds.Dx())*percent/100))
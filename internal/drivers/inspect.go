@@ -0,0 +1,64 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"asteria/internal/skills"
+	"asteria/internal/tools"
+)
+
+// inspectTimeout bounds a single Inspect call.
+const inspectTimeout = 10 * time.Second
+
+// inspectSkill is what Inspect runs tools as: a core skill with only the
+// base permission, so the tool sees the file read-only and nothing else.
+var inspectSkill = skills.Skill{
+	ID:          "inspect",
+	Source:      skills.SkillSourceCoreEmbedded,
+	Permissions: []string{skills.PermToolsExec},
+}
+
+// Inspect runs a read-only tool such as ffprobe on path and returns what it
+// printed to stdout. The tool is resolved and isolated like a core skill's
+// command; args are passed as-is and should name path.
+func (d *CLIDriver) Inspect(ctx context.Context, command string, args []string, path string) ([]byte, error) {
+	cmdPath, err := d.resolve(inspectSkill, command)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "asteria-inspect-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, inspectTimeout)
+	defer cancel()
+	limits := sandboxLimits(skills.ResourceLimits{}.Within(d.MaxLimits))
+	cmd, cleanup, err := d.command(ctx, invocation{
+		path:   cmdPath,
+		args:   args,
+		dir:    dir,
+		input:  path,
+		output: filepath.Join(dir, "out"),
+	}, inspectSkill, limits)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	var stdout cappedBuffer
+	var stderr tailBuffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := lastLine(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", tools.Name(command), msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
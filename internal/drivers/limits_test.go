@@ -0,0 +1,73 @@
+package drivers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"asteria/internal/sandbox"
+	"asteria/internal/skills"
+)
+
+// TestMain lets the test binary stand in for the sandbox helper and for the
+// commands limits are tested with.
+func TestMain(m *testing.M) {
+	sandbox.MaybeRunHelper()
+	if len(os.Args) > 1 && os.Args[1] == "allocate" {
+		allocate()
+	}
+	os.Exit(m.Run())
+}
+
+// allocate touches 4 GB, far past the memory limit of the test.
+func allocate() {
+	var held [][]byte
+	for range 64 {
+		b := make([]byte, 64<<20)
+		for i := 0; i < len(b); i += 4096 {
+			b[i] = 1
+		}
+		held = append(held, b)
+	}
+	os.Exit(len(held) - 64)
+}
+
+func TestExecuteReportsMemoryLimit(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("limits are only enforced on Linux")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.bin")
+	if err := os.WriteFile(input, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	skill := skills.Skill{
+		ID:          "allocate",
+		Source:      skills.SkillSourceCoreEmbedded,
+		Permissions: []string{skills.PermToolsExec},
+		Executor: skills.Executor{
+			Type:    "cli",
+			Command: exe,
+			Args:    []string{"allocate"},
+			// The Go runtime reserves a few hundred MB of address space to start.
+			Limits: skills.ResourceLimits{MemoryMB: 1024},
+		},
+	}
+	d := &CLIDriver{Sandbox: sandbox.ModeOff}
+	err = d.Execute(context.Background(), input, filepath.Join(dir, "out.bin"), skill, nil, nil)
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("Execute() = %v, want an *Error", err)
+	}
+	if e.Category != ErrorResourceLimit || !strings.Contains(e.Message, "memory limit of 1024 MB") {
+		t.Errorf("Execute() = %q (%s), want the memory limit reported", e.Category, e.Message)
+	}
+}
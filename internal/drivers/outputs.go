@@ -0,0 +1,108 @@
+package drivers
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OutputsDir is where the additional outputs of a run that wrote outputPath
+// are kept.
+func OutputsDir(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "-outputs"
+}
+
+// Outputs lists the additional outputs kept for outputPath, in natural name
+// order.
+func Outputs(outputPath string) []string {
+	dir := OutputsDir(outputPath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			names = append(names, e.Name())
+		}
+	}
+	sortNatural(names)
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		out = append(out, filepath.Join(dir, name))
+	}
+	return out
+}
+
+// collectOutputs moves the files in dir matching pattern into place: the
+// first becomes outputPath, the rest go to OutputsDir(outputPath).
+func collectOutputs(dir string, pattern string, outputPath string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		if ok, _ := path.Match(pattern, e.Name()); ok && e.Type().IsRegular() {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) == 0 {
+		return newError(ErrorFailed, nil, "produced no files matching %s", pattern)
+	}
+	sortNatural(names)
+	if err := os.Rename(filepath.Join(dir, names[0]), outputPath); err != nil {
+		return err
+	}
+	if len(names) == 1 {
+		return nil
+	}
+	extras := OutputsDir(outputPath)
+	if err := os.MkdirAll(extras, 0o755); err != nil {
+		return err
+	}
+	for _, name := range names[1:] {
+		if err := os.Rename(filepath.Join(dir, name), filepath.Join(extras, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortNatural orders names with digit runs compared by value, so frame-2
+// sorts before frame-10.
+func sortNatural(names []string) {
+	sort.Slice(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+}
+
+func naturalLess(a string, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func digitPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
@@ -0,0 +1,193 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"asteria/internal/sandbox"
+	"asteria/internal/skills"
+)
+
+// maxProgressLine bounds how much of one output line is kept for matching.
+const maxProgressLine = 4096
+
+// probeTimeout bounds a progress total probe such as ffprobe.
+const probeTimeout = 30 * time.Second
+
+// progressTracker reads a command's output stream line by line (ffmpeg ends
+// status lines with \r) and reports the fraction done. Output is still
+// passed on to inner for error messages.
+type progressTracker struct {
+	spec    skills.ProgressSpec
+	pattern *regexp.Regexp
+	totalRe *regexp.Regexp
+	total   float64
+	report  ProgressFunc
+	last    float64
+	line    []byte
+	inner   io.Writer
+}
+
+func newProgressTracker(spec skills.ProgressSpec, total float64, report ProgressFunc) *progressTracker {
+	t := &progressTracker{spec: spec, total: total, report: report}
+	// Patterns were validated when the skill loaded.
+	if spec.Type == "regex" {
+		t.pattern, _ = regexp.Compile(spec.Pattern)
+	}
+	if spec.TotalPattern != "" {
+		t.totalRe, _ = regexp.Compile(spec.TotalPattern)
+	}
+	return t
+}
+
+func (t *progressTracker) Write(p []byte) (int, error) {
+	if t.inner != nil {
+		_, _ = t.inner.Write(p)
+	}
+	for _, b := range p {
+		if b == '\n' || b == '\r' {
+			t.handle(string(t.line))
+			t.line = t.line[:0]
+		} else if len(t.line) < maxProgressLine {
+			t.line = append(t.line, b)
+		}
+	}
+	return len(p), nil
+}
+
+func (t *progressTracker) handle(line string) {
+	if t.totalRe != nil && t.total <= 0 {
+		if m := t.totalRe.FindStringSubmatch(line); len(m) > 1 {
+			if v, ok := parseProgressValue(m[1], t.spec.Unit); ok && v > 0 {
+				t.total = v
+			}
+		}
+	}
+	var raw string
+	switch t.spec.Type {
+	case "keyvalue":
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != t.spec.Key {
+			return
+		}
+		raw = strings.TrimSpace(value)
+	default:
+		if t.pattern == nil {
+			return
+		}
+		m := t.pattern.FindStringSubmatch(line)
+		if len(m) < 2 {
+			return
+		}
+		raw = m[1]
+		if len(m) > 2 && m[2] != "" {
+			if total, ok := parseProgressValue(m[2], t.spec.Unit); ok && total > 0 {
+				t.total = total
+			}
+		}
+	}
+	v, ok := parseProgressValue(raw, t.spec.Unit)
+	if !ok {
+		return
+	}
+	var frac float64
+	switch {
+	case t.spec.Unit == "percent":
+		frac = v / 100
+	case t.total > 0:
+		frac = v / t.total
+	default:
+		return
+	}
+	// 1.0 is reported once the command has exited.
+	frac = min(max(frac, 0), 0.99)
+	if frac-t.last >= 0.01 {
+		t.last = frac
+		t.report(frac)
+	}
+}
+
+// parseProgressValue reads a value in unit; time units become seconds.
+func parseProgressValue(s string, unit string) (float64, bool) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "%")
+	if unit == "duration" && strings.Contains(s, ":") {
+		var seconds float64
+		for _, part := range strings.Split(s, ":") {
+			v, err := strconv.ParseFloat(strings.TrimPrefix(part, "-"), 64)
+			if err != nil {
+				return 0, false
+			}
+			seconds = seconds*60 + v
+		}
+		if strings.HasPrefix(s, "-") {
+			seconds = -seconds
+		}
+		return seconds, true
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	switch unit {
+	case "milliseconds":
+		v /= 1e3
+	case "microseconds":
+		v /= 1e6
+	}
+	return v, true
+}
+
+// probeTotal runs the skill's total probe (under the same sandbox as the
+// skill) and reads the total from its output.
+func (d *CLIDriver) probeTotal(ctx context.Context, skill skills.Skill, inv invocation, builtins map[string]string, params map[string]any, limits sandbox.Limits) (float64, error) {
+	probe := skill.Executor.Progress.TotalProbe
+	cmdPath, err := d.resolve(skill, probe.Command)
+	if err != nil {
+		return 0, err
+	}
+	tmpl, err := skills.ParseArgs(probe.Args, skill.Params)
+	if err != nil {
+		return 0, err
+	}
+	args, err := tmpl.Render(builtins, params, skill.Params)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	inv.path, inv.args = cmdPath, args
+	cmd, cleanup, err := d.command(ctx, inv, skill, limits)
+	if err != nil {
+		return 0, err
+	}
+	defer cleanup()
+	var out cappedBuffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return 0, err
+	}
+
+	pattern := probe.Pattern
+	if pattern == "" {
+		pattern = `(\d+(?::\d+)*(?:\.\d+)?)`
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return 0, err
+	}
+	m := re.FindStringSubmatch(out.String())
+	if len(m) < 2 {
+		return 0, fmt.Errorf("progress probe printed no total")
+	}
+	total, ok := parseProgressValue(m[1], probe.Unit)
+	if !ok || total <= 0 {
+		return 0, fmt.Errorf("progress probe printed an invalid total %q", m[1])
+	}
+	return total, nil
+}
@@ -0,0 +1,201 @@
+package drivers
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"asteria/internal/skills"
+)
+
+// ffmpegStderr is ffmpeg's default output: the header, then status lines
+// ending in \r and a final one in \n.
+const ffmpegStderr = `ffmpeg version 7.0 Copyright (c) 2000-2024 the FFmpeg developers
+Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'in.mp4':
+  Duration: 00:00:10.00, start: 0.000000, bitrate: 1205 kb/s
+Stream mapping:
+  Stream #0:0 -> #0:0 (h264 (native) -> h264 (libx264))
+Press [q] to stop, [?] for help
+frame=    0 fps=0.0 q=0.0 size=       0kB time=N/A bitrate=N/A speed=N/A    ` + "\r" +
+	`frame=   60 fps=0.0 q=28.0 size=       0kB time=00:00:02.50 bitrate=   0.2kbits/s speed=4.98x    ` + "\r" +
+	`frame=  120 fps=119 q=28.0 size=     256kB time=00:00:05.00 bitrate= 419.4kbits/s speed=4.96x    ` + "\r" +
+	`frame=  180 fps=119 q=28.0 size=     512kB time=00:00:07.50 bitrate= 559.2kbits/s speed=4.97x    ` + "\r" +
+	`frame=  240 fps=118 q=-1.0 Lsize=     781kB time=00:00:10.00 bitrate= 639.8kbits/s speed=4.95x    ` + "\n" +
+	`video:771kB audio:0kB subtitle:0kB other streams:0kB global headers:0kB muxing overhead: 1.2%` + "\n"
+
+// ffmpegProgress is what -progress pipe:1 prints: key=value blocks.
+const ffmpegProgress = `frame=0
+fps=0.00
+out_time_us=N/A
+out_time_ms=N/A
+out_time=N/A
+progress=continue
+frame=120
+fps=119.00
+out_time_us=5000000
+out_time_ms=5000000
+out_time=00:00:05.000000
+progress=continue
+frame=240
+fps=118.00
+out_time_us=10000000
+out_time_ms=10000000
+out_time=00:00:10.000000
+progress=end
+`
+
+// magickMonitor is what magick -monitor prints to stderr: one line per
+// stage, rewritten with \r as rows are done.
+const magickMonitor = "load image[in.png]: 24 of 100, 25% complete\r" +
+	"load image[in.png]: 49 of 100, 50% complete\r" +
+	"load image[in.png]: 99 of 100, 100% complete\n" +
+	"resize image[in.png]: 99 of 400, 25% complete\r" +
+	"resize image[in.png]: 399 of 400, 100% complete\n"
+
+func TestProgressTracker(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   skills.ProgressSpec
+		total  float64
+		output string
+		want   []float64
+	}{
+		{
+			name:   "ffmpeg stderr with total pattern",
+			spec:   skills.ProgressSpec{Type: "regex", Pattern: `time=(\S+)`, Unit: "duration", TotalPattern: `Duration: ([0-9:.]+)`},
+			output: ffmpegStderr,
+			want:   []float64{0.25, 0.5, 0.75, 0.99},
+		},
+		{
+			name:   "ffmpeg stderr without a total",
+			spec:   skills.ProgressSpec{Type: "regex", Pattern: `time=(\S+)`, Unit: "duration"},
+			output: ffmpegStderr,
+		},
+		{
+			name:   "ffmpeg stderr with a probed total",
+			spec:   skills.ProgressSpec{Type: "regex", Pattern: `time=(\S+)`, Unit: "duration", TotalPattern: `Duration: ([0-9:.]+)`},
+			total:  20,
+			output: ffmpegStderr,
+			want:   []float64{0.13, 0.25, 0.38, 0.5},
+		},
+		{
+			name:   "ffmpeg progress microseconds",
+			spec:   skills.ProgressSpec{Type: "keyvalue", Key: "out_time_us", Unit: "microseconds"},
+			total:  10,
+			output: ffmpegProgress,
+			want:   []float64{0.5, 0.99},
+		},
+		{
+			name:   "ffmpeg progress duration",
+			spec:   skills.ProgressSpec{Type: "keyvalue", Key: "out_time", Unit: "duration"},
+			total:  40,
+			output: ffmpegProgress,
+			want:   []float64{0.13, 0.25},
+		},
+		{
+			name:   "milliseconds unit",
+			spec:   skills.ProgressSpec{Type: "keyvalue", Key: "out_time_ms", Unit: "milliseconds"},
+			total:  20000,
+			output: ffmpegProgress,
+			want:   []float64{0.25, 0.5},
+		},
+		{
+			name:   "ffmpeg progress with crlf",
+			spec:   skills.ProgressSpec{Type: "keyvalue", Key: "frame"},
+			total:  240,
+			output: strings.ReplaceAll(ffmpegProgress, "\n", "\r\n"),
+			want:   []float64{0.5, 0.99},
+		},
+		{
+			name:   "magick monitor current and total groups",
+			spec:   skills.ProgressSpec{Type: "regex", Pattern: `(\d+) of (\d+)`},
+			output: magickMonitor,
+			want:   []float64{0.24, 0.49, 0.99},
+		},
+		{
+			name:   "magick monitor percent",
+			spec:   skills.ProgressSpec{Type: "regex", Pattern: `(\d+)% complete`, Unit: "percent"},
+			output: magickMonitor,
+			want:   []float64{0.25, 0.5, 0.99},
+		},
+		{
+			name:   "small steps are not reported",
+			spec:   skills.ProgressSpec{Type: "regex", Pattern: `^(\d+)$`},
+			total:  1000,
+			output: "1\n5\n9\n10\n12\n30\n",
+			want:   []float64{0.01, 0.03},
+		},
+		{
+			name:   "line without newline is not read",
+			spec:   skills.ProgressSpec{Type: "regex", Pattern: `(\d+)%`, Unit: "percent"},
+			output: "10%\n20%",
+			want:   []float64{0.1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []float64
+			tracker := newProgressTracker(tt.spec, tt.total, func(v float64) {
+				got = append(got, math.Round(v*100)/100)
+			})
+			var passed bytes.Buffer
+			tracker.inner = &passed
+			// Odd-sized writes split lines the way pipe reads do.
+			for chunk := range slices.Chunk([]byte(tt.output), 7) {
+				if n, err := tracker.Write(chunk); n != len(chunk) || err != nil {
+					t.Fatalf("Write = %d, %v", n, err)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("reported %v, want %v", got, tt.want)
+			}
+			if passed.String() != tt.output {
+				t.Error("output was not passed on unchanged")
+			}
+		})
+	}
+}
+
+func TestProgressTrackerLongLine(t *testing.T) {
+	var got []float64
+	tracker := newProgressTracker(skills.ProgressSpec{Type: "regex", Pattern: `(\d+)%$`, Unit: "percent"}, 0, func(v float64) {
+		got = append(got, v)
+	})
+	// The value at the end of an overlong line is cut off.
+	tracker.Write([]byte(strings.Repeat("x", maxProgressLine) + " 50%\n"))
+	tracker.Write([]byte("60%\n"))
+	if !slices.Equal(got, []float64{0.6}) {
+		t.Errorf("reported %v, want [0.6]", got)
+	}
+}
+
+func TestParseProgressValue(t *testing.T) {
+	tests := []struct {
+		in     string
+		unit   string
+		want   float64
+		wantOK bool
+	}{
+		{"42", "", 42, true},
+		{" 12.5 ", "number", 12.5, true},
+		{"45%", "percent", 45, true},
+		{"00:01:02.50", "duration", 62.5, true},
+		{"1:30", "duration", 90, true},
+		{"90.5", "duration", 90.5, true},
+		{"-00:00:01.50", "duration", -1.5, true},
+		{"00:xx:01", "duration", 0, false},
+		{"1500", "milliseconds", 1.5, true},
+		{"2500000", "microseconds", 2.5, true},
+		{"N/A", "microseconds", 0, false},
+		{"", "", 0, false},
+		{"00:00:01", "number", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseProgressValue(tt.in, tt.unit)
+		if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("parseProgressValue(%q, %q) = %v, %v, want %v, %v", tt.in, tt.unit, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
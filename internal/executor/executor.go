@@ -9,10 +9,13 @@ import (
 	"sync"
 
 	"asteria/internal/drivers"
+	"asteria/internal/metadata"
 	"asteria/internal/preview"
 	"asteria/internal/session"
 	"asteria/internal/skills"
 	"asteria/internal/storage"
+
+	"github.com/google/uuid"
 )
 
 type Executor struct {
@@ -20,49 +23,129 @@ type Executor struct {
 	session  *session.State
 	drivers  map[string]drivers.Driver
 	usage    *storage.UsageStore
+	metadata *metadata.Registry
+
+	// onProgress receives per-file progress between 0 and 1.
+	onProgress func(fileID string, skillID string, value float64)
+	// authorize decides whether applied skills may run again when a file
+	// is rebuilt; see SetAuthorizer.
+	authorize func(skills ...skills.Skill) (func(ran bool), error)
 }
 
 const maxPipelineDepth = 6
 
 func NewExecutor(registry *skills.Registry, sessionState *session.State, usage *storage.UsageStore) *Executor {
+	cli := &drivers.CLIDriver{}
 	return &Executor{
 		registry: registry,
 		session:  sessionState,
 		drivers: map[string]drivers.Driver{
 			"image": &drivers.ImageDriver{},
-			"cli":   &drivers.CLIDriver{},
+			"cli":   cli,
 		},
-		usage: usage,
+		usage:    usage,
+		metadata: metadata.Default(policyInspector{registry: registry, cli: cli}),
+	}
+}
+
+// policyInspector runs metadata tools through the CLI driver unless the
+// policy denies their command; extractors that fail are skipped.
+type policyInspector struct {
+	registry *skills.Registry
+	cli      *drivers.CLIDriver
+}
+
+func (p policyInspector) Inspect(ctx context.Context, command string, args []string, path string) ([]byte, error) {
+	if p.registry != nil {
+		if reason := p.registry.PolicyBlocksCommand(command); reason != "" {
+			return nil, drivers.Errorf(drivers.ErrorPermissionDenied, "%s", reason)
+		}
+	}
+	return p.cli.Inspect(ctx, command, args, path)
+}
+
+// Metadata returns the extractors files are described with, so more can be
+// registered.
+func (e *Executor) Metadata() *metadata.Registry {
+	return e.metadata
+}
+
+// RefreshMetadata reads the metadata of a file's current content. It reports
+// false, and records nothing, when the content changed while it was read.
+func (e *Executor) RefreshMetadata(ctx context.Context, fileState *session.FileState) bool {
+	revision := fileState.Revision()
+	data := fileState.Data()
+	return fileState.SetMetadataAt(revision, e.metadata.Extract(ctx, data.WorkingPath, data.MimeType, data.CurrentExtension))
+}
+
+// SetProgressHandler sets the function driver progress is reported to.
+// Call it before running skills.
+func (e *Executor) SetProgressHandler(handler func(fileID string, skillID string, value float64)) {
+	e.onProgress = handler
+}
+
+// SetAuthorizer sets the check skills pass before a rebuild runs them again:
+// their definitions may have changed since they were approved. It returns a
+// release function to call with whether the run happened. Call it before
+// running skills.
+func (e *Executor) SetAuthorizer(authorize func(skills ...skills.Skill) (func(ran bool), error)) {
+	e.authorize = authorize
+}
+
+func (e *Executor) fileProgress(fileID string, skillID string) drivers.ProgressFunc {
+	if e.onProgress == nil {
+		return nil
+	}
+	return func(value float64) {
+		e.onProgress(fileID, skillID, value)
 	}
 }
 
-func (e *Executor) executeSkillToOutput(ctx context.Context, inputPath string, inputExt string, fileDir string, skill skills.Skill, params map[string]any, depth int) (string, string, error) {
+// CLI returns the driver that runs declarative CLI skills so the app can
+// configure it from settings.
+func (e *Executor) CLI() *drivers.CLIDriver {
+	cli, _ := e.drivers["cli"].(*drivers.CLIDriver)
+	return cli
+}
+
+// executeSkillToOutput runs skill on inputPath and returns the output path,
+// its extension and any additional outputs the (last) step produced.
+func (e *Executor) executeSkillToOutput(ctx context.Context, inputPath string, inputExt string, fileDir string, skill skills.Skill, params map[string]any, depth int, progress drivers.ProgressFunc) (string, string, []string, error) {
 	if skill.IsMeta {
-		return "", "", fmt.Errorf("meta skills cannot be executed on files")
+		return "", "", nil, fmt.Errorf("meta skills cannot be executed on files")
 	}
 	if strings.EqualFold(skill.Executor.Type, "pipeline") {
 		if depth > maxPipelineDepth {
-			return "", "", fmt.Errorf("pipeline depth exceeded")
+			return "", "", nil, fmt.Errorf("pipeline depth exceeded")
 		}
 		currentPath := inputPath
 		currentExt := inputExt
-		for _, step := range skill.Executor.Steps {
+		var outputs []string
+		steps := skill.Executor.Steps
+		for i, step := range steps {
 			stepSkill, ok := e.registry.GetByID(step.SkillID)
 			if !ok {
-				return "", "", fmt.Errorf("unknown pipeline step skill: %s", step.SkillID)
+				if reason := e.registry.PolicyBlocks(step.SkillID); reason != "" {
+					err := drivers.Errorf(drivers.ErrorPermissionDenied, "pipeline step blocked: %s", reason)
+					return "", "", nil, drivers.WithSkill(err, skill.ID)
+				}
+				err := drivers.Errorf(drivers.ErrorFailed, "unknown pipeline step skill: %s", step.SkillID)
+				return "", "", nil, drivers.WithSkill(err, skill.ID)
 			}
 			if stepSkill.IsMeta {
-				return "", "", fmt.Errorf("pipeline step cannot be meta: %s", step.SkillID)
+				err := drivers.Errorf(drivers.ErrorFailed, "pipeline step cannot be meta: %s", step.SkillID)
+				return "", "", nil, drivers.WithSkill(err, skill.ID)
 			}
 			mergedParams := mergeParams(params, step.Params)
-			outPath, outExt, err := e.executeSkillToOutput(ctx, currentPath, currentExt, fileDir, stepSkill, mergedParams, depth+1)
+			outPath, outExt, stepOutputs, err := e.executeSkillToOutput(ctx, currentPath, currentExt, fileDir, stepSkill, mergedParams, depth+1, stepProgress(progress, i, len(steps)))
 			if err != nil {
-				return "", "", err
+				return "", "", nil, drivers.WithSkill(err, skill.ID)
 			}
 			currentPath = outPath
 			currentExt = outExt
+			outputs = stepOutputs
 		}
-		return currentPath, currentExt, nil
+		return currentPath, currentExt, outputs, nil
 	}
 
 	driverID := skill.Driver
@@ -73,15 +156,60 @@ func (e *Executor) executeSkillToOutput(ctx context.Context, inputPath string, i
 	}
 	driver, ok := e.drivers[driverID]
 	if !ok {
-		return "", "", fmt.Errorf("missing driver: %s", driverID)
+		return "", "", nil, fmt.Errorf("missing driver: %s", driverID)
 	}
 
-	outputExt := effectiveOutputExt(skill, inputExt)
+	outputExt, err := effectiveOutputExt(skill, inputExt, params)
+	if err != nil {
+		return "", "", nil, drivers.WithSkill(drivers.Errorf(drivers.ErrorInvalidParams, "%v", err), skill.ID)
+	}
+	// The step writes to a staging path so the working file, which may be its
+	// input, is only replaced by an output that passed validation.
 	outputPath := filepath.Join(fileDir, "current"+outputExt)
-	if err := driver.Execute(ctx, inputPath, outputPath, skill, params, nil); err != nil {
-		return "", "", err
+	stagePath := filepath.Join(fileDir, ".step-"+uuid.NewString()[:8]+outputExt)
+	defer discardOutput(stagePath)
+	if err := driver.Execute(ctx, inputPath, stagePath, skill, params, progress); err != nil {
+		return "", "", nil, drivers.WithSkill(err, skill.ID)
+	}
+	if err := validateOutput(stagePath, outputExt); err != nil {
+		return "", "", nil, drivers.WithSkill(err, skill.ID)
+	}
+	if err := commitOutput(stagePath, outputPath); err != nil {
+		return "", "", nil, drivers.WithSkill(err, skill.ID)
+	}
+	return outputPath, outputExt, drivers.Outputs(outputPath), nil
+}
+
+// commitOutput moves a validated step output and its additional outputs into
+// place, replacing those of the previous run.
+func commitOutput(stagePath string, outputPath string) error {
+	// Additional outputs belong to the run that made them.
+	if err := os.RemoveAll(drivers.OutputsDir(outputPath)); err != nil {
+		return err
+	}
+	if _, err := os.Stat(drivers.OutputsDir(stagePath)); err == nil {
+		if err := os.Rename(drivers.OutputsDir(stagePath), drivers.OutputsDir(outputPath)); err != nil {
+			return err
+		}
+	}
+	return os.Rename(stagePath, outputPath)
+}
+
+// discardOutput removes what is left of a step that didn't commit.
+func discardOutput(stagePath string) {
+	_ = os.Remove(stagePath)
+	_ = os.RemoveAll(drivers.OutputsDir(stagePath))
+}
+
+// stepProgress scales a pipeline step's progress into its share of the
+// whole pipeline.
+func stepProgress(progress drivers.ProgressFunc, step int, steps int) drivers.ProgressFunc {
+	if progress == nil || steps == 0 {
+		return progress
+	}
+	return func(value float64) {
+		progress((float64(step) + value) / float64(steps))
 	}
-	return outputPath, outputExt, nil
 }
 
 func mergeParams(base map[string]any, override map[string]any) map[string]any {
@@ -98,55 +226,126 @@ func mergeParams(base map[string]any, override map[string]any) map[string]any {
 	return out
 }
 
-func effectiveOutputExt(skill skills.Skill, currentExt string) string {
+// effectiveOutputExt picks the output extension: one derived from params
+// wins, then the declared output type, then a fixed executor extension.
+func effectiveOutputExt(skill skills.Skill, currentExt string, params map[string]any) (string, error) {
+	isCLI := strings.EqualFold(skill.Executor.Type, "cli")
+	if isCLI && strings.Contains(skill.Executor.OutputExtension, "{{") {
+		return skill.OutputExtensionFor(params)
+	}
 	if skill.OutputType != "" && skill.OutputType != "none" {
-		return skill.OutputType
+		return skill.OutputType, nil
 	}
-	if strings.EqualFold(skill.Executor.Type, "cli") && strings.TrimSpace(skill.Executor.OutputExtension) != "" {
-		ext := strings.TrimSpace(skill.Executor.OutputExtension)
-		if !strings.HasPrefix(ext, ".") {
-			ext = "." + ext
-		}
-		return strings.ToLower(ext)
+	if isCLI && strings.TrimSpace(skill.Executor.OutputExtension) != "" {
+		return skill.OutputExtensionFor(params)
 	}
-	return currentExt
+	return currentExt, nil
 }
 
-func (e *Executor) ApplySkill(ctx context.Context, fileIDs []string, skillID string, params map[string]any) ([]session.WorkingFile, error) {
+// ApplySkill runs a skill on each file. Files it fails on keep their
+// previous state and are reported as FileErrors; err is only set when the
+// skill can't run at all.
+func (e *Executor) ApplySkill(ctx context.Context, fileIDs []string, skillID string, params map[string]any) ([]session.WorkingFile, []FileError, error) {
 	skill, ok := e.registry.GetByID(skillID)
 	if !ok {
-		return nil, fmt.Errorf("unknown skill: %s", skillID)
+		return nil, nil, fmt.Errorf("unknown skill: %s", skillID)
 	}
 	var driver drivers.Driver
 	if !strings.EqualFold(skill.Executor.Type, "pipeline") {
 		var ok bool
 		driver, ok = e.drivers[skill.Driver]
 		if !ok {
-			return nil, fmt.Errorf("missing driver: %s", skill.Driver)
+			return nil, nil, fmt.Errorf("missing driver: %s", skill.Driver)
 		}
 	}
+	transitions := e.pendingTransitions(fileIDs)
 	var wg sync.WaitGroup
 	results := make([]session.WorkingFile, len(fileIDs))
-	errs := make(chan error, len(fileIDs))
+	failures := make([]*FileError, len(fileIDs))
 	for i, fileID := range fileIDs {
 		wg.Add(1)
 		go func(idx int, id string) {
 			defer wg.Done()
 			updated, err := e.applyToFile(ctx, id, skill, driver, params)
 			if err != nil {
-				errs <- err
+				failures[idx] = e.fileError(id, err)
 				return
 			}
 			results[idx] = updated
 		}(i, fileID)
 	}
 	wg.Wait()
-	close(errs)
-	if err := firstError(errs); err != nil {
-		return nil, err
+
+	updated := make([]session.WorkingFile, 0, len(fileIDs))
+	var errs []FileError
+	for i := range fileIDs {
+		if failures[i] != nil {
+			errs = append(errs, *failures[i])
+			continue
+		}
+		updated = append(updated, results[i])
+	}
+	if len(updated) == 0 {
+		return nil, errs, nil
 	}
 	_ = e.usage.Increment(skillID)
-	return results, nil
+	for _, t := range transitions {
+		_ = e.usage.RecordTransition(t.previousSkillID, t.inputExt, skillID)
+	}
+	_ = e.usage.RecordParams(skillID, declaredParams(skill, params))
+	return updated, errs, nil
+}
+
+func (e *Executor) fileError(fileID string, err error) *FileError {
+	out := &FileError{FileID: fileID, Error: *drivers.AsError(err)}
+	if fileState, ok := e.session.GetFile(fileID); ok {
+		out.Name = fileState.Data().Name
+	}
+	return out
+}
+
+// declaredParams keeps only the params the skill declares, so stray keys
+// never turn into learned presets.
+func declaredParams(skill skills.Skill, params map[string]any) map[string]any {
+	if len(params) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(params))
+	for _, def := range skill.Params {
+		if v, ok := params[def.Name]; ok {
+			out[def.Name] = v
+		}
+	}
+	return out
+}
+
+type transition struct {
+	previousSkillID string
+	inputExt        string
+}
+
+// pendingTransitions captures, before a skill runs, the distinct
+// (previous skill, current extension) pairs of the target files.
+func (e *Executor) pendingTransitions(fileIDs []string) []transition {
+	seen := make(map[transition]struct{}, len(fileIDs))
+	out := make([]transition, 0, len(fileIDs))
+	for _, id := range fileIDs {
+		fileState, ok := e.session.GetFile(id)
+		if !ok {
+			continue
+		}
+		data := fileState.Data()
+		t := transition{inputExt: data.CurrentExtension}
+		if n := len(data.AppliedSkills); n > 0 {
+			t.previousSkillID = data.AppliedSkills[n-1].SkillID
+		}
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+	}
+	return out
 }
 
 func (e *Executor) RemoveSkill(ctx context.Context, fileID string, index int) (session.WorkingFile, error) {
@@ -160,14 +359,35 @@ func (e *Executor) RemoveSkill(ctx context.Context, fileID string, index int) (s
 	}
 	updated := append([]session.AppliedSkill{}, applied...)
 	updated = append(updated[:index], updated[index+1:]...)
+	release, err := e.authorizeApplied(updated[index:])
+	if err != nil {
+		return session.WorkingFile{}, err
+	}
 	fileState.ReplaceApplied(updated)
 
-	if err := e.rebuildFrom(ctx, fileState, index); err != nil {
+	err = e.rebuildFrom(ctx, fileState, index)
+	release(true)
+	if err != nil {
 		return session.WorkingFile{}, err
 	}
 	return fileState.Data(), nil
 }
 
+// authorizeApplied checks the skills a rebuild runs again. Skills that no
+// longer resolve are left to the rebuild to report.
+func (e *Executor) authorizeApplied(applied []session.AppliedSkill) (func(ran bool), error) {
+	if e.authorize == nil {
+		return func(bool) {}, nil
+	}
+	var list []skills.Skill
+	for _, step := range applied {
+		if skill, ok := e.registry.GetByID(step.SkillID); ok {
+			list = append(list, skill)
+		}
+	}
+	return e.authorize(list...)
+}
+
 func (e *Executor) applyToFile(ctx context.Context, fileID string, skill skills.Skill, driver drivers.Driver, params map[string]any) (session.WorkingFile, error) {
 	fileState, ok := e.session.GetFile(fileID)
 	if !ok {
@@ -177,7 +397,7 @@ func (e *Executor) applyToFile(ctx context.Context, fileID string, skill skills.
 	currentPath := data.WorkingPath
 	currentExt := data.CurrentExtension
 	fileDir := filepath.Dir(fileState.BasePath())
-	outputPath, outputExt, err := e.executeSkillToOutput(ctx, currentPath, currentExt, fileDir, skill, params, 0)
+	outputPath, outputExt, outputs, err := e.executeSkillToOutput(ctx, currentPath, currentExt, fileDir, skill, params, 0, e.fileProgress(fileID, skill.ID))
 	if err != nil {
 		return session.WorkingFile{}, err
 	}
@@ -186,6 +406,7 @@ func (e *Executor) applyToFile(ctx context.Context, fileID string, skill skills.
 		size = info.Size()
 	}
 	fileState.SetCurrentPath(outputPath, outputExt, size)
+	fileState.SetOutputs(outputs)
 
 	snapshotIndex := len(data.AppliedSkills)
 	snapshotPath := e.sessionSnapshotPath(fileState, snapshotIndex, outputExt)
@@ -194,6 +415,7 @@ func (e *Executor) applyToFile(ctx context.Context, fileID string, skill skills.
 	}
 
 	fileState.AppendApplied(session.NewAppliedSkill(skill.ID, params))
+	e.RefreshMetadata(ctx, fileState)
 	if previewURL, err := preview.ImagePreview(outputPath, 520); err == nil {
 		fileState.SetPreview(previewURL)
 	}
@@ -223,19 +445,32 @@ func (e *Executor) rebuildFrom(ctx context.Context, fileState *session.FileState
 		size = info.Size()
 	}
 	fileState.SetCurrentPath(currentPath, ext, size)
+	fileState.SetOutputs(nil)
 	fileState.TrimSnapshots(startIndex)
 
 	for i := startIndex; i < len(applied); i++ {
 		step := applied[i]
 		skill, ok := e.registry.GetByID(step.SkillID)
 		if !ok {
-			return fmt.Errorf("unknown skill: %s", step.SkillID)
+			if reason := e.registry.PolicyBlocks(step.SkillID); reason != "" {
+				err := drivers.Errorf(drivers.ErrorPermissionDenied, "skill blocked: %s", reason)
+				return drivers.WithSkill(err, step.SkillID)
+			}
+			err := drivers.Errorf(drivers.ErrorFailed, "unknown skill: %s", step.SkillID)
+			return drivers.WithSkill(err, step.SkillID)
+		}
+		if !skill.Available() {
+			err := drivers.Errorf(drivers.ErrorMissingTool, "skill unavailable: %s", skill.Unavailable)
+			if skill.InstallHint != "" {
+				err.Fix = skill.InstallHint
+			}
+			return drivers.WithSkill(err, skill.ID)
 		}
 
 		data := fileState.Data()
 		currentPath = data.WorkingPath
 		currentExt := data.CurrentExtension
-		outputPath, outputExt, err := e.executeSkillToOutput(ctx, currentPath, currentExt, fileDir, skill, step.Params, 0)
+		outputPath, outputExt, outputs, err := e.executeSkillToOutput(ctx, currentPath, currentExt, fileDir, skill, step.Params, 0, e.fileProgress(fileState.Data().ID, skill.ID))
 		if err != nil {
 			return err
 		}
@@ -244,11 +479,13 @@ func (e *Executor) rebuildFrom(ctx context.Context, fileState *session.FileState
 			size = info.Size()
 		}
 		fileState.SetCurrentPath(outputPath, outputExt, size)
+		fileState.SetOutputs(outputs)
 		snapshotPath := e.sessionSnapshotPath(fileState, i, outputExt)
 		if err := session.CopyFile(outputPath, snapshotPath); err == nil {
 			fileState.SetSnapshot(i, snapshotPath)
 		}
 	}
+	e.RefreshMetadata(ctx, fileState)
 	finalPath := fileState.Data().WorkingPath
 	if previewURL, err := preview.ImagePreview(finalPath, 520); err == nil {
 		fileState.SetPreview(previewURL)
@@ -260,15 +497,6 @@ func (e *Executor) sessionSnapshotPath(fileState *session.FileState, index int,
 	return fileState.SnapshotPath(e.session.Workspace(), index, ext)
 }
 
-func firstError(errs <-chan error) error {
-	for err := range errs {
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 func minInt(a, b int) int {
 	if a < b {
 		return a
@@ -1,9 +1,21 @@
 package executor
 
-import "asteria/internal/session"
+import (
+	"asteria/internal/drivers"
+	"asteria/internal/session"
+)
 
 type SkillResult struct {
 	UpdatedFiles []session.WorkingFile   `json:"updatedFiles"`
 	Session      session.SessionSnapshot `json:"session"`
 	Message      string                  `json:"message,omitempty"`
+	// Errors lists the files the skill failed on; they keep their state.
+	Errors []FileError `json:"errors,omitempty"`
+}
+
+// FileError is why a skill failed on one file.
+type FileError struct {
+	FileID string        `json:"fileId"`
+	Name   string        `json:"name"`
+	Error  drivers.Error `json:"error"`
 }
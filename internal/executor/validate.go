@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"errors"
+	"image"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"asteria/internal/drivers"
+	"asteria/internal/filetype"
+
+	"github.com/disintegration/imaging"
+)
+
+// decodable are the image types the app can decode itself.
+var decodable = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".tif": true, ".tiff": true,
+}
+
+// maxValidatePixels bounds the images that are fully decoded to check their
+// pixel data; larger ones only have their header checked, so a tool can't
+// make the app allocate without limit.
+const maxValidatePixels = 16 << 20
+
+// validateOutput checks that a step wrote a usable file at path: it exists,
+// isn't empty, its content matches ext and, for images the app can read, it
+// decodes. A broken output fails the step rather than reaching the next one.
+func validateOutput(path string, ext string) error {
+	ext = strings.ToLower(ext)
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return outputError(drivers.ErrorFailed, "produced no output file")
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return outputError(drivers.ErrorFailed, "output is not a file")
+	}
+	if info.Size() == 0 {
+		return outputError(drivers.ErrorFailed, "produced an empty output file")
+	}
+
+	head := make([]byte, filetype.HeadSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	head = head[:n]
+	if want, ok := filetype.ByExtension(ext); ok {
+		got, detected := filetype.Detect(head)
+		switch {
+		case detected && !filetype.Compatible(got, want):
+			return outputError(drivers.ErrorFailed, "wrote %s data, expected %s (%s)", got.Name, want.Name, ext)
+		case !detected && want.Detectable():
+			return outputError(drivers.ErrorFailed, "output is not a valid %s file", want.Name)
+		}
+	}
+
+	if decodable[ext] {
+		return validateImage(f)
+	}
+	return nil
+}
+
+// validateImage checks an image's header and, when it is small enough, that
+// its pixel data decodes.
+func validateImage(f *os.File) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return outputError(drivers.ErrorDecodeFailed, "output image does not decode: %v", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return outputError(drivers.ErrorDecodeFailed, "output image has no pixels")
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxValidatePixels {
+		return nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := imaging.Decode(f); err != nil {
+		return outputError(drivers.ErrorDecodeFailed, "output image does not decode: %v", err)
+	}
+	return nil
+}
+
+func outputError(category drivers.ErrorCategory, format string, args ...any) error {
+	err := drivers.Errorf(category, format, args...)
+	err.Fix = "The skill did not write a usable file; check its arguments and output type."
+	return err
+}
@@ -0,0 +1,105 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"asteria/internal/drivers"
+)
+
+// pngHeader returns a PNG signature and IHDR for an RGB image of the given
+// size followed by image data that does not decode.
+func pngHeader(width, height uint32) []byte {
+	chunk := func(kind string, data []byte) []byte {
+		out := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+		out = append(out, kind...)
+		out = append(out, data...)
+		return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(append([]byte(kind), data...)))
+	}
+	ihdr := binary.BigEndian.AppendUint32(nil, width)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, height)
+	ihdr = append(ihdr, 8, 2, 0, 0, 0) // 8-bit RGB
+	out := []byte("\x89PNG\r\n\x1a\n")
+	out = append(out, chunk("IHDR", ihdr)...)
+	return append(out, chunk("IDAT", []byte("not deflate data"))...)
+}
+
+func smallPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestValidateOutput(t *testing.T) {
+	valid := smallPNG(t)
+	tests := []struct {
+		name     string
+		data     []byte // nil writes no file
+		dir      bool
+		ext      string
+		category drivers.ErrorCategory // empty when the output is valid
+		message  string
+	}{
+		{name: "valid png", data: valid, ext: ".png"},
+		{name: "extension case", data: valid, ext: ".PNG"},
+		{name: "missing", ext: ".png", category: drivers.ErrorFailed, message: "no output file"},
+		{name: "directory", dir: true, ext: ".png", category: drivers.ErrorFailed, message: "not a file"},
+		{name: "empty", data: []byte{}, ext: ".png", category: drivers.ErrorFailed, message: "empty output"},
+		{name: "png data named jpg", data: valid, ext: ".jpg", category: drivers.ErrorFailed, message: "wrote PNG data, expected JPEG"},
+		{name: "pdf data named png", data: []byte("%PDF-1.7\n"), ext: ".png", category: drivers.ErrorFailed, message: "wrote PDF data"},
+		{name: "text named png", data: []byte("error: no delegate"), ext: ".png", category: drivers.ErrorFailed, message: "not a valid PNG"},
+		{name: "zip named docx", data: []byte("PK\x03\x04rest of the archive"), ext: ".docx"},
+		{name: "text named csv", data: []byte("a,b\n1,2\n"), ext: ".csv"},
+		{name: "unknown extension", data: []byte("anything"), ext: ".xyz"},
+		{name: "header only", data: []byte("\x89PNG\r\n\x1a\n"), ext: ".png", category: drivers.ErrorDecodeFailed, message: "does not decode"},
+		{name: "small image with broken pixels", data: pngHeader(100, 100), ext: ".png", category: drivers.ErrorDecodeFailed, message: "does not decode"},
+		{name: "no pixels", data: pngHeader(0, 10), ext: ".png", category: drivers.ErrorDecodeFailed},
+		// Over maxValidatePixels only the header is read, so the broken
+		// pixel data is not noticed and nothing the size of the image is
+		// allocated.
+		{name: "large image skips the full decode", data: pngHeader(8192, 8192), ext: ".png"},
+		{name: "just over the limit", data: pngHeader(4097, 4096), ext: ".png"},
+		{name: "at the limit is decoded", data: pngHeader(4096, 4096), ext: ".png", category: drivers.ErrorDecodeFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out"+tt.ext)
+			switch {
+			case tt.dir:
+				if err := os.Mkdir(path, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			case tt.data != nil:
+				if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := validateOutput(path, tt.ext)
+			if tt.category == "" {
+				if err != nil {
+					t.Fatalf("validateOutput = %v, want nil", err)
+				}
+				return
+			}
+			var de *drivers.Error
+			if !errors.As(err, &de) {
+				t.Fatalf("validateOutput = %v, want a %s error", err, tt.category)
+			}
+			if de.Category != tt.category || !strings.Contains(de.Message, tt.message) {
+				t.Errorf("validateOutput = %s %q, want %s containing %q", de.Category, de.Message, tt.category, tt.message)
+			}
+		})
+	}
+}
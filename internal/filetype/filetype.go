@@ -0,0 +1,189 @@
+// Package filetype identifies files by their content rather than their
+// name, so a PNG saved as .jpg or an extensionless download is still
+// recognized.
+package filetype
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// Kind groups types for display and matching.
+type Kind string
+
+const (
+	KindImage    Kind = "image"
+	KindAudio    Kind = "audio"
+	KindVideo    Kind = "video"
+	KindDocument Kind = "document"
+	KindArchive  Kind = "archive"
+)
+
+// HeadSize is how many leading bytes Detect needs to see.
+const HeadSize = 512
+
+// Type is a known file type.
+type Type struct {
+	Name string
+	MIME string
+	Kind Kind
+	// Exts are the extensions used for the type; the first is canonical.
+	Exts []string
+	// Parent is the MIME type of the container the type is stored in when
+	// its content can't be told apart from it (a .docx is a zip).
+	Parent string
+
+	match func(head []byte) bool
+}
+
+// Extension is the canonical extension for t, with the dot.
+func (t Type) Extension() string {
+	if len(t.Exts) == 0 {
+		return ""
+	}
+	return t.Exts[0]
+}
+
+// HasExtension reports whether ext is one of t's extensions.
+func (t Type) HasExtension(ext string) bool {
+	ext = normalizeExt(ext)
+	for _, e := range t.Exts {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// Detectable reports whether t can be recognized from content alone; some
+// types are only told apart from their container by extension.
+func (t Type) Detectable() bool {
+	return t.match != nil
+}
+
+// Compatible reports whether content detected as a can stand in for b: the
+// same type, or one is the container of the other.
+func Compatible(a Type, b Type) bool {
+	if a.MIME == "" || b.MIME == "" {
+		return false
+	}
+	return a.MIME == b.MIME || a.Parent == b.MIME || b.Parent == a.MIME
+}
+
+// Detect identifies head, the first HeadSize bytes of a file (or all of a
+// shorter one).
+func Detect(head []byte) (Type, bool) {
+	for _, t := range types {
+		if t.match != nil && t.match(head) {
+			return t, true
+		}
+	}
+	return Type{}, false
+}
+
+// Identify picks the type of a file from its content and extension: the
+// extension's type when the content agrees with it, which keeps .docx from
+// reading as zip, otherwise the detected type.
+func Identify(head []byte, ext string) (Type, bool) {
+	detected, ok := Detect(head)
+	if !ok {
+		return Type{}, false
+	}
+	if named, ok := ByExtension(ext); ok && Compatible(detected, named) {
+		return named, true
+	}
+	return detected, true
+}
+
+// ReadHead reads the leading bytes of path that Detect looks at.
+func ReadHead(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	head := make([]byte, HeadSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// IdentifyFile identifies the file at path; see Identify.
+func IdentifyFile(path string, ext string) (Type, bool) {
+	head, err := ReadHead(path)
+	if err != nil {
+		return Type{}, false
+	}
+	return Identify(head, ext)
+}
+
+// ByExtension looks up the type an extension (".jpg" or "jpg") stands for.
+func ByExtension(ext string) (Type, bool) {
+	ext = normalizeExt(ext)
+	if ext == "" {
+		return Type{}, false
+	}
+	for _, t := range types {
+		if t.Extension() == ext {
+			return t, true
+		}
+	}
+	for _, t := range types {
+		if t.HasExtension(ext) {
+			return t, true
+		}
+	}
+	return Type{}, false
+}
+
+// ByMIME looks up a type by its MIME type.
+func ByMIME(mime string) (Type, bool) {
+	mime = strings.ToLower(strings.TrimSpace(mime))
+	for _, t := range types {
+		if t.MIME == mime {
+			return t, true
+		}
+	}
+	return Type{}, false
+}
+
+// MatchMIME reports whether mime matches pattern, which may be exact
+// ("image/png"), a wildcard subtype ("image/*") or "*/*".
+func MatchMIME(pattern string, mime string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	mime = strings.ToLower(strings.TrimSpace(mime))
+	if pattern == "" || mime == "" {
+		return false
+	}
+	if pattern == "*/*" || pattern == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		major, _, _ := strings.Cut(mime, "/")
+		return major == prefix
+	}
+	return pattern == mime
+}
+
+// ValidMIMEPattern reports whether pattern is usable with MatchMIME.
+func ValidMIMEPattern(pattern string) bool {
+	if pattern == "*/*" || pattern == "*" {
+		return true
+	}
+	major, minor, ok := strings.Cut(pattern, "/")
+	if !ok || major == "" || minor == "" || major == "*" || strings.Contains(minor, "/") {
+		return false
+	}
+	return minor == "*" || !strings.Contains(minor, "*")
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
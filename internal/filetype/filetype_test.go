@@ -0,0 +1,214 @@
+package filetype
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ftyp returns an ISO base media header with the major brand and compatible
+// brands given.
+func ftyp(major string, compatible ...string) string {
+	size := 16 + 4*len(compatible)
+	return string([]byte{0, 0, 0, byte(size)}) + "ftyp" + major + "\x00\x00\x00\x00" + strings.Join(compatible, "")
+}
+
+// zipMime returns the start of a zip whose first stored entry is mimetype.
+func zipMime(mime string) string {
+	return "PK\x03\x04" + strings.Repeat("\x00", 26) + "mimetype" + mime
+}
+
+// signatures holds a minimal header per detectable type.
+var signatures = map[string]string{
+	"image/png":                 "\x89PNG\r\n\x1a\n",
+	"image/jpeg":                "\xff\xd8\xff\xe0",
+	"image/gif":                 "GIF89a",
+	"image/webp":                "RIFF\x00\x00\x00\x00WEBP",
+	"image/avif":                ftyp("avif"),
+	"image/heic":                ftyp("heic"),
+	"image/heif":                ftyp("mif1"),
+	"image/tiff":                "MM\x00*",
+	"image/bmp":                 "BM" + strings.Repeat("\x00", 12) + "\x28\x00\x00\x00",
+	"image/x-icon":              "\x00\x00\x01\x00",
+	"image/vnd.adobe.photoshop": "8BPS",
+	"image/jxl":                 "\xff\x0a",
+	"image/svg+xml":             "\xef\xbb\xbf\n<?xml version=\"1.0\"?>\n<svg>",
+	"audio/wav":                 "RIFF\x00\x00\x00\x00WAVE",
+	"audio/aiff":                "FORM\x00\x00\x00\x00AIFC",
+	"audio/flac":                "fLaC",
+	"audio/ogg":                 "OggS",
+	"audio/mp4":                 ftyp("M4A "),
+	"audio/mpeg":                "\xff\xfb",
+	"audio/aac":                 "\xff\xf1",
+	"video/quicktime":           ftyp("qt  "),
+	"video/mp4":                 ftyp("mp42", "isom"),
+	"video/3gpp":                ftyp("3gp5"),
+	"video/webm":                "\x1a\x45\xdf\xa3\x9f\x42\x82\x84webm",
+	"video/x-matroska":          "\x1a\x45\xdf\xa3\x9f\x42\x82\x88matroska",
+	"video/x-msvideo":           "RIFF\x00\x00\x00\x00AVI ",
+	"application/pdf":           "%PDF-1.7",
+	"application/rtf":           "{\\rtf1",
+	"application/epub+zip":      zipMime("application/epub+zip"),
+	"application/vnd.oasis.opendocument.text":         zipMime("application/vnd.oasis.opendocument.text"),
+	"application/vnd.oasis.opendocument.spreadsheet":  zipMime("application/vnd.oasis.opendocument.spreadsheet"),
+	"application/vnd.oasis.opendocument.presentation": zipMime("application/vnd.oasis.opendocument.presentation"),
+	"application/x-ole-storage":                       "\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1",
+	"application/zip":                                 "PK\x03\x04",
+	"application/gzip":                                "\x1f\x8b",
+	"application/x-bzip2":                             "BZh9",
+	"application/x-xz":                                "\xfd7zXZ\x00",
+	"application/zstd":                                "\x28\xb5\x2f\xfd",
+	"application/x-7z-compressed":                     "7z\xbc\xaf\x27\x1c",
+	"application/vnd.rar":                             "Rar!\x1a\x07\x00",
+	"application/x-tar":                               strings.Repeat("\x00", 257) + "ustar",
+}
+
+func TestDetect(t *testing.T) {
+	for _, typ := range types {
+		if !typ.Detectable() {
+			continue
+		}
+		head, ok := signatures[typ.MIME]
+		if !ok {
+			t.Errorf("%s has no test signature", typ.MIME)
+			continue
+		}
+		got, ok := Detect([]byte(head))
+		if !ok || got.MIME != typ.MIME {
+			t.Errorf("Detect(%s signature) = %q, %v", typ.MIME, got.MIME, ok)
+		}
+	}
+}
+
+func TestDetectOverlaps(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"avif before heif", ftyp("avif", "mif1", "miaf"), "image/avif"},
+		{"heic with mif1 major", ftyp("mif1", "heic"), "image/heic"},
+		{"m4a before mp4", ftyp("M4A ", "isom", "mp42"), "audio/mp4"},
+		{"brand in minor version ignored", "\x00\x00\x00\x10ftypisomheic", "video/mp4"},
+		{"brand past box size ignored", "\x00\x00\x00\x10ftypmp42\x00\x00\x00\x00heic", "video/mp4"},
+		{"webm before matroska", signatures["video/webm"], "video/webm"},
+		{"epub before zip", zipMime("application/epub+zip"), "application/epub+zip"},
+		{"zip with other first entry", "PK\x03\x04" + strings.Repeat("\x00", 26) + "word/doc", "application/zip"},
+		{"mp3 id3 tag", "ID3\x04", "audio/mpeg"},
+		{"svg element", "  <svg xmlns=\"http://www.w3.org/2000/svg\">", "image/svg+xml"},
+		{"tiff little endian", "II*\x00", "image/tiff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Detect([]byte(tt.head))
+			if !ok || got.MIME != tt.want {
+				t.Errorf("Detect = %q, %v, want %q", got.MIME, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectTruncated(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+	}{
+		{"empty", ""},
+		{"png", "\x89PNG\r\n"},
+		{"jpeg", "\xff\xd8"},
+		{"riff without form type", "RIFF\x00\x00\x00\x00WEB"},
+		{"aiff without form type", "FORM\x00\x00\x00\x00"},
+		{"ftyp without brand", "\x00\x00\x00\x18ftyp"},
+		{"bmp without dib header", "BM\x00\x00"},
+		{"tar before magic", strings.Repeat("\x00", 260)},
+		{"xml without svg", "<?xml version=\"1.0\"?><html>"},
+		{"mpeg sync only", "\xff"},
+		{"text", "hello, world"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, ok := Detect([]byte(tt.head)); ok {
+				t.Errorf("Detect(%q) = %q, want no match", tt.head, got.MIME)
+			}
+		})
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		ext  string
+		want string
+	}{
+		{"content matches extension", signatures["image/png"], ".png", "image/png"},
+		{"content wins over extension", signatures["image/png"], ".jpg", "image/png"},
+		{"no extension", signatures["image/jpeg"], "", "image/jpeg"},
+		{"docx from zip", signatures["application/zip"], ".docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{"xlsx upper case", signatures["application/zip"], "XLSX", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{"zip named pdf", signatures["application/zip"], ".pdf", "application/zip"},
+		{"doc from ole", signatures["application/x-ole-storage"], ".doc", "application/msword"},
+		{"heic named heif", signatures["image/heic"], ".heif", "image/heif"},
+		{"heif named heic", signatures["image/heif"], ".heic", "image/heic"},
+		{"webm named mkv", signatures["video/webm"], ".mkv", "video/x-matroska"},
+		{"epub named zip", signatures["application/epub+zip"], ".zip", "application/zip"},
+		{"jpeg alias extension", signatures["image/jpeg"], ".jpe", "image/jpeg"},
+		{"unknown content", "hello", ".png", ""},
+		{"truncated header", "\x89PN", ".png", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Identify([]byte(tt.head), tt.ext)
+			if ok != (tt.want != "") || got.MIME != tt.want {
+				t.Errorf("Identify(%q) = %q, %v, want %q", tt.ext, got.MIME, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentifyFile(t *testing.T) {
+	dir := t.TempDir()
+	short := filepath.Join(dir, "short.docx")
+	if err := os.WriteFile(short, []byte(signatures["application/zip"]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	long := filepath.Join(dir, "long")
+	if err := os.WriteFile(long, []byte(signatures["application/x-tar"]+strings.Repeat("\x00", 2*HeadSize)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok := IdentifyFile(short, ".docx"); !ok || got.Extension() != ".docx" {
+		t.Errorf("IdentifyFile(short) = %q, %v", got.MIME, ok)
+	}
+	if got, ok := IdentifyFile(long, ""); !ok || got.MIME != "application/x-tar" {
+		t.Errorf("IdentifyFile(long) = %q, %v", got.MIME, ok)
+	}
+	if _, ok := IdentifyFile(filepath.Join(dir, "missing"), ".png"); ok {
+		t.Error("IdentifyFile(missing) matched")
+	}
+	head, err := ReadHead(long)
+	if err != nil || len(head) != HeadSize {
+		t.Errorf("ReadHead(long) = %d bytes, %v", len(head), err)
+	}
+}
+
+func TestMatchMIME(t *testing.T) {
+	tests := []struct {
+		pattern string
+		mime    string
+		want    bool
+	}{
+		{"image/png", "image/png", true},
+		{"image/*", "IMAGE/PNG", true},
+		{"image/*", "video/mp4", false},
+		{"*/*", "application/pdf", true},
+		{"image/png", "image/jpeg", false},
+		{"", "image/png", false},
+	}
+	for _, tt := range tests {
+		if got := MatchMIME(tt.pattern, tt.mime); got != tt.want {
+			t.Errorf("MatchMIME(%q, %q) = %v, want %v", tt.pattern, tt.mime, got, tt.want)
+		}
+	}
+}
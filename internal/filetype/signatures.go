@@ -0,0 +1,174 @@
+package filetype
+
+import "bytes"
+
+// types are checked in order, so more specific signatures come before the
+// ones they overlap with (AVIF before HEIF, M4A before MP4, containers'
+// contents before the container).
+var types = []Type{
+	// Images.
+	{Name: "PNG", MIME: "image/png", Kind: KindImage, Exts: []string{".png"}, match: prefix("\x89PNG\r\n\x1a\n")},
+	{Name: "JPEG", MIME: "image/jpeg", Kind: KindImage, Exts: []string{".jpg", ".jpeg", ".jpe"}, match: prefix("\xff\xd8\xff")},
+	{Name: "GIF", MIME: "image/gif", Kind: KindImage, Exts: []string{".gif"}, match: anyPrefix("GIF87a", "GIF89a")},
+	{Name: "WebP", MIME: "image/webp", Kind: KindImage, Exts: []string{".webp"}, match: riff("WEBP")},
+	{Name: "AVIF", MIME: "image/avif", Kind: KindImage, Exts: []string{".avif"}, match: isobmff("avif", "avis")},
+	{Name: "HEIC", MIME: "image/heic", Kind: KindImage, Exts: []string{".heic"}, Parent: "image/heif", match: isobmff("heic", "heix", "heim", "heis", "hevc", "hevx")},
+	{Name: "HEIF", MIME: "image/heif", Kind: KindImage, Exts: []string{".heif", ".hif"}, match: isobmff("mif1", "msf1")},
+	{Name: "TIFF", MIME: "image/tiff", Kind: KindImage, Exts: []string{".tif", ".tiff"}, match: anyPrefix("II*\x00", "MM\x00*")},
+	{Name: "BMP", MIME: "image/bmp", Kind: KindImage, Exts: []string{".bmp"}, match: bmp},
+	{Name: "ICO", MIME: "image/x-icon", Kind: KindImage, Exts: []string{".ico"}, match: prefix("\x00\x00\x01\x00")},
+	{Name: "Photoshop", MIME: "image/vnd.adobe.photoshop", Kind: KindImage, Exts: []string{".psd"}, match: prefix("8BPS")},
+	{Name: "JPEG XL", MIME: "image/jxl", Kind: KindImage, Exts: []string{".jxl"}, match: anyPrefix("\xff\x0a", "\x00\x00\x00\x0cJXL \r\n\x87\n")},
+	{Name: "SVG", MIME: "image/svg+xml", Kind: KindImage, Exts: []string{".svg"}, match: svg},
+
+	// Audio.
+	{Name: "WAV", MIME: "audio/wav", Kind: KindAudio, Exts: []string{".wav"}, match: riff("WAVE")},
+	{Name: "AIFF", MIME: "audio/aiff", Kind: KindAudio, Exts: []string{".aiff", ".aif"}, match: form("AIFF", "AIFC")},
+	{Name: "FLAC", MIME: "audio/flac", Kind: KindAudio, Exts: []string{".flac"}, match: prefix("fLaC")},
+	{Name: "Ogg", MIME: "audio/ogg", Kind: KindAudio, Exts: []string{".ogg", ".oga", ".opus"}, match: prefix("OggS")},
+	{Name: "M4A", MIME: "audio/mp4", Kind: KindAudio, Exts: []string{".m4a"}, match: isobmff("M4A ", "M4B ")},
+	{Name: "MP3", MIME: "audio/mpeg", Kind: KindAudio, Exts: []string{".mp3"}, match: mp3},
+	{Name: "AAC", MIME: "audio/aac", Kind: KindAudio, Exts: []string{".aac"}, match: adts},
+
+	// Video.
+	{Name: "QuickTime", MIME: "video/quicktime", Kind: KindVideo, Exts: []string{".mov"}, match: isobmff("qt  ")},
+	{Name: "MP4", MIME: "video/mp4", Kind: KindVideo, Exts: []string{".mp4", ".m4v"}, match: isobmff("isom", "iso2", "iso4", "iso5", "iso6", "mp41", "mp42", "avc1", "dash", "M4V ")},
+	{Name: "3GP", MIME: "video/3gpp", Kind: KindVideo, Exts: []string{".3gp"}, match: isobmff("3gp4", "3gp5", "3gp6", "3ge6", "3gg6")},
+	{Name: "WebM", MIME: "video/webm", Kind: KindVideo, Exts: []string{".webm"}, Parent: "video/x-matroska", match: ebml("webm")},
+	{Name: "Matroska", MIME: "video/x-matroska", Kind: KindVideo, Exts: []string{".mkv", ".mka"}, match: ebml("")},
+	{Name: "AVI", MIME: "video/x-msvideo", Kind: KindVideo, Exts: []string{".avi"}, match: riff("AVI ")},
+
+	// Documents.
+	{Name: "PDF", MIME: "application/pdf", Kind: KindDocument, Exts: []string{".pdf"}, match: prefix("%PDF-")},
+	{Name: "RTF", MIME: "application/rtf", Kind: KindDocument, Exts: []string{".rtf"}, match: prefix("{\\rtf")},
+	{Name: "EPUB", MIME: "application/epub+zip", Kind: KindDocument, Exts: []string{".epub"}, Parent: "application/zip", match: zipMimetype("application/epub+zip")},
+	{Name: "OpenDocument Text", MIME: "application/vnd.oasis.opendocument.text", Kind: KindDocument, Exts: []string{".odt"}, Parent: "application/zip", match: zipMimetype("application/vnd.oasis.opendocument.text")},
+	{Name: "OpenDocument Spreadsheet", MIME: "application/vnd.oasis.opendocument.spreadsheet", Kind: KindDocument, Exts: []string{".ods"}, Parent: "application/zip", match: zipMimetype("application/vnd.oasis.opendocument.spreadsheet")},
+	{Name: "OpenDocument Presentation", MIME: "application/vnd.oasis.opendocument.presentation", Kind: KindDocument, Exts: []string{".odp"}, Parent: "application/zip", match: zipMimetype("application/vnd.oasis.opendocument.presentation")},
+	// Office Open XML files are plain zips at the start; they are only told
+	// apart by extension (see Identify).
+	{Name: "Word", MIME: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", Kind: KindDocument, Exts: []string{".docx"}, Parent: "application/zip"},
+	{Name: "Excel", MIME: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Kind: KindDocument, Exts: []string{".xlsx"}, Parent: "application/zip"},
+	{Name: "PowerPoint", MIME: "application/vnd.openxmlformats-officedocument.presentationml.presentation", Kind: KindDocument, Exts: []string{".pptx"}, Parent: "application/zip"},
+	{Name: "Word 97", MIME: "application/msword", Kind: KindDocument, Exts: []string{".doc"}, Parent: "application/x-ole-storage"},
+	{Name: "Excel 97", MIME: "application/vnd.ms-excel", Kind: KindDocument, Exts: []string{".xls"}, Parent: "application/x-ole-storage"},
+	{Name: "PowerPoint 97", MIME: "application/vnd.ms-powerpoint", Kind: KindDocument, Exts: []string{".ppt"}, Parent: "application/x-ole-storage"},
+	{Name: "OLE document", MIME: "application/x-ole-storage", Kind: KindDocument, Exts: []string{".ole"}, match: prefix("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1")},
+
+	// Archives.
+	{Name: "ZIP", MIME: "application/zip", Kind: KindArchive, Exts: []string{".zip"}, match: anyPrefix("PK\x03\x04", "PK\x05\x06", "PK\x07\x08")},
+	{Name: "gzip", MIME: "application/gzip", Kind: KindArchive, Exts: []string{".gz", ".tgz"}, match: prefix("\x1f\x8b")},
+	{Name: "bzip2", MIME: "application/x-bzip2", Kind: KindArchive, Exts: []string{".bz2"}, match: prefix("BZh")},
+	{Name: "xz", MIME: "application/x-xz", Kind: KindArchive, Exts: []string{".xz"}, match: prefix("\xfd7zXZ\x00")},
+	{Name: "Zstandard", MIME: "application/zstd", Kind: KindArchive, Exts: []string{".zst"}, match: prefix("\x28\xb5\x2f\xfd")},
+	{Name: "7-Zip", MIME: "application/x-7z-compressed", Kind: KindArchive, Exts: []string{".7z"}, match: prefix("7z\xbc\xaf\x27\x1c")},
+	{Name: "RAR", MIME: "application/vnd.rar", Kind: KindArchive, Exts: []string{".rar"}, match: prefix("Rar!\x1a\x07")},
+	{Name: "tar", MIME: "application/x-tar", Kind: KindArchive, Exts: []string{".tar"}, match: tar},
+}
+
+func prefix(magic string) func([]byte) bool {
+	return func(head []byte) bool { return bytes.HasPrefix(head, []byte(magic)) }
+}
+
+func anyPrefix(magics ...string) func([]byte) bool {
+	return func(head []byte) bool {
+		for _, magic := range magics {
+			if bytes.HasPrefix(head, []byte(magic)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func riff(kind string) func([]byte) bool {
+	return func(head []byte) bool {
+		return len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == kind
+	}
+}
+
+func form(kinds ...string) func([]byte) bool {
+	return func(head []byte) bool {
+		if len(head) < 12 || string(head[:4]) != "FORM" {
+			return false
+		}
+		for _, kind := range kinds {
+			if string(head[8:12]) == kind {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// isobmff matches an ISO base media file (MP4, HEIF, ...) whose major or
+// compatible brands include one of brands.
+func isobmff(brands ...string) func([]byte) bool {
+	return func(head []byte) bool {
+		if len(head) < 12 || string(head[4:8]) != "ftyp" {
+			return false
+		}
+		size := int(head[0])<<24 | int(head[1])<<16 | int(head[2])<<8 | int(head[3])
+		size = min(max(size, 12), len(head))
+		for i := 8; i+4 <= size; i += 4 {
+			if i == 12 {
+				continue // minor version
+			}
+			for _, brand := range brands {
+				if string(head[i:i+4]) == brand {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// ebml matches a Matroska file, or one whose DocType is docType.
+func ebml(docType string) func([]byte) bool {
+	return func(head []byte) bool {
+		if !bytes.HasPrefix(head, []byte("\x1a\x45\xdf\xa3")) {
+			return false
+		}
+		return docType == "" || bytes.Contains(head[:min(len(head), 64)], []byte(docType))
+	}
+}
+
+// zipMimetype matches the uncompressed "mimetype" first entry that
+// OpenDocument and EPUB files start with.
+func zipMimetype(mime string) func([]byte) bool {
+	return func(head []byte) bool {
+		return bytes.HasPrefix(head, []byte("PK\x03\x04")) && len(head) >= 38 &&
+			string(head[30:38]) == "mimetype" && bytes.HasPrefix(head[38:], []byte(mime))
+	}
+}
+
+func bmp(head []byte) bool {
+	// The DIB header size follows the 14-byte file header.
+	return len(head) >= 18 && string(head[:2]) == "BM" && head[14] >= 12 && head[15] == 0
+}
+
+// mp3 matches an ID3 tag or an MPEG audio frame header (layer bits set).
+func mp3(head []byte) bool {
+	if bytes.HasPrefix(head, []byte("ID3")) {
+		return true
+	}
+	return len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0 && head[1]&0x06 != 0
+}
+
+// adts matches an AAC ADTS frame header (layer bits zero).
+func adts(head []byte) bool {
+	return len(head) >= 2 && head[0] == 0xFF && head[1]&0xF6 == 0xF0
+}
+
+func tar(head []byte) bool {
+	return len(head) >= 262 && string(head[257:262]) == "ustar"
+}
+
+func svg(head []byte) bool {
+	text := bytes.TrimLeft(head, "\xef\xbb\xbf \t\r\n")
+	if bytes.HasPrefix(text, []byte("<svg")) {
+		return true
+	}
+	return bytes.HasPrefix(text, []byte("<?xml")) && bytes.Contains(head, []byte("<svg"))
+}
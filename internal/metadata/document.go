@@ -0,0 +1,121 @@
+package metadata
+
+import (
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"asteria/internal/filetype"
+)
+
+// maxPDFScan bounds how much of a PDF is read to count pages.
+const maxPDFScan = 64 << 20
+
+var (
+	pdfPage  = regexp.MustCompile(`/Type\s*/Page(?:[^s]|$)`)
+	pdfCount = regexp.MustCompile(`/Type\s*/Pages\b[^>]*?/Count\s+(\d+)|/Count\s+(\d+)[^>]*?/Type\s*/Pages\b`)
+)
+
+// PDFExtractor counts the pages of a PDF.
+type PDFExtractor struct{}
+
+func (e *PDFExtractor) ID() string {
+	return "pdf"
+}
+
+func (e *PDFExtractor) Supports(t filetype.Type) bool {
+	return t.MIME == "application/pdf"
+}
+
+// Extract counts page objects, falling back to the largest page tree count
+// when pages are in compressed object streams.
+func (e *PDFExtractor) Extract(ctx context.Context, path string, t filetype.Type) (Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxPDFScan))
+	if err != nil {
+		return Metadata{}, err
+	}
+	pages := len(pdfPage.FindAllIndex(data, -1))
+	if pages == 0 {
+		for _, m := range pdfCount.FindAllSubmatch(data, -1) {
+			count := m[1]
+			if count == nil {
+				count = m[2]
+			}
+			n, _ := strconv.Atoi(string(count))
+			pages = max(pages, n)
+		}
+	}
+	return Metadata{Pages: pages}, nil
+}
+
+// MagickExtractor reads images Go can't decode (HEIC, AVIF, PSD, ...) with
+// ImageMagick's identify.
+type MagickExtractor struct {
+	Inspector Inspector
+}
+
+func (e *MagickExtractor) ID() string {
+	return "magick"
+}
+
+func (e *MagickExtractor) Supports(t filetype.Type) bool {
+	return t.Kind == filetype.KindImage && !(&ImageExtractor{}).Supports(t)
+}
+
+// magickFormat prints the fields of the first frame, separated by "|".
+const magickFormat = "%w|%h|%[colorspace]|%x|%[units]|%[EXIF:Make]|%[EXIF:Model]|%[EXIF:DateTimeOriginal]|%[EXIF:Orientation]\n"
+
+func (e *MagickExtractor) Extract(ctx context.Context, path string, t filetype.Type) (Metadata, error) {
+	out, err := e.Inspector.Inspect(ctx, "magick", []string{"identify", "-ping", "-format", magickFormat, path + "[0]"}, path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	fields := strings.Split(line, "|")
+	if len(fields) < 9 {
+		return Metadata{}, nil
+	}
+	var md Metadata
+	md.Width, _ = strconv.Atoi(fields[0])
+	md.Height, _ = strconv.Atoi(fields[1])
+	md.ColorModel = magickColorModel(fields[2])
+	// ImageMagick 6 prints "72 PixelsPerInch" for %x.
+	resolution, _, _ := strings.Cut(strings.TrimSpace(fields[3]), " ")
+	if res, err := strconv.ParseFloat(resolution, 64); err == nil && res > 0 {
+		switch strings.TrimSpace(fields[4]) {
+		case "PixelsPerInch":
+			md.DPI = int(res + 0.5)
+		case "PixelsPerCentimeter":
+			md.DPI = int(res*2.54 + 0.5)
+		}
+	}
+	md.CameraMake = strings.TrimSpace(fields[5])
+	md.CameraModel = strings.TrimSpace(fields[6])
+	md.TakenAt = exifTime(strings.TrimSpace(fields[7]))
+	if o, err := strconv.Atoi(strings.TrimSpace(fields[8])); err == nil && o >= 1 && o <= 8 {
+		md.Orientation = o
+	}
+	return md, nil
+}
+
+func magickColorModel(colorspace string) string {
+	switch strings.ToLower(strings.TrimSpace(colorspace)) {
+	case "srgb", "rgb", "scrgb":
+		return "rgb"
+	case "gray", "lineargray":
+		return "gray"
+	case "cmyk":
+		return "cmyk"
+	case "ycbcr", "rec601ycbcr", "rec709ycbcr":
+		return "ycbcr"
+	}
+	return strings.ToLower(strings.TrimSpace(colorspace))
+}
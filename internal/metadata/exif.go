@@ -0,0 +1,171 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// EXIF (TIFF) tags that are read.
+const (
+	tagImageWidth       = 0x0100
+	tagImageLength      = 0x0101
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagXResolution      = 0x011A
+	tagResolutionUnit   = 0x0128
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagDateTimeOriginal = 0x9003
+)
+
+// maxIFDEntries bounds how many entries are read from one directory, so a
+// damaged file can't make parsing slow.
+const maxIFDEntries = 512
+
+// tiffField is one IFD entry.
+type tiffField struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// parseTIFF reads the camera fields of a TIFF structure: a TIFF file, or the
+// EXIF block of a JPEG or PNG.
+func parseTIFF(data []byte) (Metadata, bool) {
+	if len(data) < 8 {
+		return Metadata{}, false
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return Metadata{}, false
+	}
+	if order.Uint16(data[2:4]) != 42 {
+		return Metadata{}, false
+	}
+	ifd0 := readIFD(data, order, order.Uint32(data[4:8]))
+	if ifd0 == nil {
+		return Metadata{}, false
+	}
+
+	var md Metadata
+	md.CameraMake = asciiField(ifd0[tagMake])
+	md.CameraModel = asciiField(ifd0[tagModel])
+	md.Orientation = intField(ifd0[tagOrientation], order)
+	if md.Orientation < 1 || md.Orientation > 8 {
+		md.Orientation = 0
+	}
+	md.Width = intField(ifd0[tagImageWidth], order)
+	md.Height = intField(ifd0[tagImageLength], order)
+	if res := rationalField(ifd0[tagXResolution], order); res > 0 {
+		switch intField(ifd0[tagResolutionUnit], order) {
+		case 0, 2: // inches, the default
+			md.DPI = int(res + 0.5)
+		case 3: // centimeters
+			md.DPI = int(res*2.54 + 0.5)
+		}
+	}
+	taken := asciiField(ifd0[tagDateTime])
+	if f, ok := ifd0[tagExifIFD]; ok {
+		if exif := readIFD(data, order, uint32(intField(f, order))); exif != nil {
+			if original := asciiField(exif[tagDateTimeOriginal]); original != "" {
+				taken = original
+			}
+		}
+	}
+	md.TakenAt = exifTime(taken)
+	return md, true
+}
+
+func readIFD(data []byte, order binary.ByteOrder, offset uint32) map[uint16]tiffField {
+	if offset < 8 || uint64(offset)+2 > uint64(len(data)) {
+		return nil
+	}
+	n := int(order.Uint16(data[offset:]))
+	if n > maxIFDEntries {
+		return nil
+	}
+	fields := make(map[uint16]tiffField, n)
+	for i := 0; i < n; i++ {
+		at := int(offset) + 2 + i*12
+		if at+12 > len(data) {
+			break
+		}
+		entry := data[at : at+12]
+		typ := order.Uint16(entry[2:4])
+		count := order.Uint32(entry[4:8])
+		size := uint64(typeSize(typ)) * uint64(count)
+		if size == 0 {
+			continue
+		}
+		value := entry[8:12]
+		if size > 4 {
+			start := uint64(order.Uint32(entry[8:12]))
+			if start+size > uint64(len(data)) {
+				continue
+			}
+			value = data[start : start+size]
+		}
+		fields[order.Uint16(entry[0:2])] = tiffField{typ: typ, count: count, value: value}
+	}
+	return fields
+}
+
+func typeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7: // byte, ascii, sbyte, undefined
+		return 1
+	case 3, 8: // short, sshort
+		return 2
+	case 4, 9, 11: // long, slong, float
+		return 4
+	case 5, 10, 12: // rational, srational, double
+		return 8
+	}
+	return 0
+}
+
+func asciiField(f tiffField) string {
+	if f.typ != 2 {
+		return ""
+	}
+	s, _, _ := strings.Cut(string(f.value), "\x00")
+	return strings.TrimSpace(s)
+}
+
+func intField(f tiffField, order binary.ByteOrder) int {
+	switch {
+	case f.typ == 3 && len(f.value) >= 2:
+		return int(order.Uint16(f.value))
+	case f.typ == 4 && len(f.value) >= 4:
+		return int(order.Uint32(f.value))
+	}
+	return 0
+}
+
+func rationalField(f tiffField, order binary.ByteOrder) float64 {
+	if f.typ != 5 || len(f.value) < 8 {
+		return 0
+	}
+	num, den := order.Uint32(f.value[:4]), order.Uint32(f.value[4:8])
+	if den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
+
+// exifTime turns "2006:01:02 15:04:05" into "2006-01-02T15:04:05".
+func exifTime(s string) string {
+	if len(s) < 19 || s[4] != ':' || s[7] != ':' || s[10] != ' ' {
+		return ""
+	}
+	if strings.HasPrefix(s, "0000") {
+		return ""
+	}
+	return s[:4] + "-" + s[5:7] + "-" + s[8:10] + "T" + s[11:19]
+}
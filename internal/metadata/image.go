@@ -0,0 +1,161 @@
+package metadata
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+
+	"asteria/internal/filetype"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// maxHeaderScan bounds how much of an image is read looking for DPI and
+// EXIF; both sit before the pixel data.
+const maxHeaderScan = 1 << 20
+
+// ImageExtractor reads dimensions, color model, DPI and EXIF from the image
+// formats Go can decode.
+type ImageExtractor struct{}
+
+func (e *ImageExtractor) ID() string {
+	return "image"
+}
+
+func (e *ImageExtractor) Supports(t filetype.Type) bool {
+	switch t.MIME {
+	case "image/png", "image/jpeg", "image/gif", "image/bmp", "image/tiff", "image/webp":
+		return true
+	}
+	return false
+}
+
+func (e *ImageExtractor) Extract(ctx context.Context, path string, t filetype.Type) (Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return Metadata{}, err
+	}
+	// Dimensions come from the decoder; EXIF copies of them can be stale.
+	md := Metadata{Width: cfg.Width, Height: cfg.Height, ColorModel: colorModelName(cfg.ColorModel)}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return md, nil
+	}
+	head, err := io.ReadAll(io.LimitReader(f, maxHeaderScan))
+	if err != nil {
+		return md, nil
+	}
+	var found Metadata
+	switch t.MIME {
+	case "image/jpeg":
+		found = jpegHeaders(head)
+	case "image/png":
+		found = pngChunks(head)
+	case "image/tiff":
+		found, _ = parseTIFF(head)
+	}
+	md.merge(found)
+	return md, nil
+}
+
+func colorModelName(m color.Model) string {
+	switch m {
+	case color.RGBAModel, color.RGBA64Model, color.NRGBAModel, color.NRGBA64Model:
+		return "rgba"
+	case color.GrayModel, color.Gray16Model:
+		return "gray"
+	case color.CMYKModel:
+		return "cmyk"
+	case color.YCbCrModel, color.NYCbCrAModel:
+		return "ycbcr"
+	case color.AlphaModel, color.Alpha16Model:
+		return "alpha"
+	}
+	if _, ok := m.(color.Palette); ok {
+		return "paletted"
+	}
+	return ""
+}
+
+// jpegHeaders reads the JFIF density and the EXIF block from the segments
+// before the image data.
+func jpegHeaders(data []byte) Metadata {
+	var md Metadata
+	i := 2 // SOI
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			break
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 { // start of scan, end of image
+			break
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+size]
+		switch {
+		case marker == 0xE0 && bytes.HasPrefix(segment, []byte("JFIF\x00")) && len(segment) >= 12:
+			density := int(binary.BigEndian.Uint16(segment[8:10]))
+			switch segment[7] {
+			case 1:
+				md.DPI = density
+			case 2:
+				md.DPI = int(float64(density)*2.54 + 0.5)
+			}
+		case marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")):
+			if exif, ok := parseTIFF(segment[6:]); ok {
+				// A JFIF density, read first, wins over EXIF's.
+				md.merge(exif)
+			}
+		}
+		i += 2 + size
+	}
+	return md
+}
+
+// pngChunks reads the pHYs density and an eXIf block from the chunks before
+// the image data.
+func pngChunks(data []byte) Metadata {
+	var md Metadata
+	i := 8 // signature
+	for i+8 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[i:]))
+		kind := string(data[i+4 : i+8])
+		if kind == "IDAT" || size < 0 || i+12+size > len(data) {
+			break
+		}
+		chunk := data[i+8 : i+8+size]
+		switch kind {
+		case "pHYs":
+			if len(chunk) >= 9 && chunk[8] == 1 { // pixels per meter
+				md.DPI = int(float64(binary.BigEndian.Uint32(chunk[:4]))*0.0254 + 0.5)
+			}
+		case "eXIf":
+			if exif, ok := parseTIFF(chunk); ok {
+				md.merge(exif)
+			}
+		}
+		i += 12 + size
+	}
+	return md
+}
@@ -0,0 +1,163 @@
+package metadata
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"asteria/internal/filetype"
+)
+
+// WAVExtractor reads the format and length of WAV files.
+type WAVExtractor struct{}
+
+func (e *WAVExtractor) ID() string {
+	return "wav"
+}
+
+func (e *WAVExtractor) Supports(t filetype.Type) bool {
+	return t.MIME == "audio/wav"
+}
+
+func (e *WAVExtractor) Extract(ctx context.Context, path string, t filetype.Type) (Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer f.Close()
+	head := make([]byte, 12)
+	if _, err := io.ReadFull(f, head); err != nil {
+		return Metadata{}, err
+	}
+
+	var md Metadata
+	byteRate := 0
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(f, chunk); err != nil {
+			break
+		}
+		size := int64(binary.LittleEndian.Uint32(chunk[4:]))
+		switch string(chunk[:4]) {
+		case "fmt ":
+			format := make([]byte, min(size, 16))
+			if _, err := io.ReadFull(f, format); err != nil || len(format) < 16 {
+				return md, nil
+			}
+			md.AudioCodec = wavCodec(binary.LittleEndian.Uint16(format[0:2]), binary.LittleEndian.Uint16(format[14:16]))
+			md.Channels = int(binary.LittleEndian.Uint16(format[2:4]))
+			md.SampleRate = int(binary.LittleEndian.Uint32(format[4:8]))
+			byteRate = int(binary.LittleEndian.Uint32(format[8:12]))
+			size -= int64(len(format))
+		case "data":
+			if byteRate > 0 {
+				md.Duration = float64(size) / float64(byteRate)
+			}
+			return md, nil
+		}
+		// Chunks are padded to an even size.
+		if _, err := f.Seek(size+size%2, io.SeekCurrent); err != nil {
+			break
+		}
+	}
+	return md, nil
+}
+
+// wavCodec names a WAVE format code the way ffprobe does.
+func wavCodec(format uint16, bits uint16) string {
+	switch format {
+	case 1:
+		switch bits {
+		case 8:
+			return "pcm_u8"
+		case 16, 24, 32:
+			return "pcm_s" + strconv.Itoa(int(bits)) + "le"
+		}
+		return "pcm"
+	case 3:
+		return "pcm_f" + strconv.Itoa(int(bits)) + "le"
+	case 6:
+		return "pcm_alaw"
+	case 7:
+		return "pcm_mulaw"
+	}
+	return ""
+}
+
+// ProbeExtractor reads duration, codecs and video dimensions with ffprobe.
+type ProbeExtractor struct {
+	Inspector Inspector
+}
+
+func (e *ProbeExtractor) ID() string {
+	return "ffprobe"
+}
+
+func (e *ProbeExtractor) Supports(t filetype.Type) bool {
+	return t.Kind == filetype.KindAudio || t.Kind == filetype.KindVideo
+}
+
+func (e *ProbeExtractor) Extract(ctx context.Context, path string, t filetype.Type) (Metadata, error) {
+	out, err := e.Inspector.Inspect(ctx, "ffprobe", []string{
+		"-v", "error", "-print_format", "json", "-show_format", "-show_streams", path,
+	}, path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	var probe struct {
+		Format struct {
+			Duration string            `json:"duration"`
+			Tags     map[string]string `json:"tags"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType  string `json:"codec_type"`
+			CodecName  string `json:"codec_name"`
+			Width      int    `json:"width"`
+			Height     int    `json:"height"`
+			SampleRate string `json:"sample_rate"`
+			Channels   int    `json:"channels"`
+			// Cover art shows up as a video stream.
+			Disposition struct {
+				AttachedPic int `json:"attached_pic"`
+			} `json:"disposition"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return Metadata{}, err
+	}
+
+	var md Metadata
+	if d, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil && d > 0 {
+		md.Duration = d
+	}
+	for _, s := range probe.Streams {
+		switch {
+		case s.CodecType == "video" && s.Disposition.AttachedPic == 0 && md.VideoCodec == "":
+			md.VideoCodec = s.CodecName
+			md.Width, md.Height = s.Width, s.Height
+		case s.CodecType == "audio" && md.AudioCodec == "":
+			md.AudioCodec = s.CodecName
+			md.SampleRate, _ = strconv.Atoi(s.SampleRate)
+			md.Channels = s.Channels
+		}
+	}
+	if created := probe.Format.Tags["creation_time"]; created != "" {
+		if at, err := time.Parse(time.RFC3339Nano, created); err == nil {
+			md.TakenAt = at.Local().Format("2006-01-02T15:04:05")
+		}
+	}
+	for _, key := range []string{"title", "artist", "album"} {
+		if v := strings.TrimSpace(probe.Format.Tags[key]); v != "" {
+			if md.Extra == nil {
+				md.Extra = map[string]string{}
+			}
+			md.Extra[key] = v
+		}
+	}
+	return md, nil
+}
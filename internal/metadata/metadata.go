@@ -0,0 +1,224 @@
+// Package metadata reads what a file is about — dimensions, camera, length,
+// codecs, pages — so the UI can show it and naming can use it.
+package metadata
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"asteria/internal/filetype"
+)
+
+// Metadata describes a file. Fields an extractor can't fill stay zero.
+type Metadata struct {
+	Kind string `json:"kind,omitempty"`
+
+	// Images and video.
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	ColorModel string `json:"colorModel,omitempty"`
+	DPI        int    `json:"dpi,omitempty"`
+
+	// EXIF.
+	CameraMake  string `json:"cameraMake,omitempty"`
+	CameraModel string `json:"cameraModel,omitempty"`
+	// TakenAt is the capture time as "2006-01-02T15:04:05", in the camera's
+	// local time when the file doesn't say otherwise.
+	TakenAt string `json:"takenAt,omitempty"`
+	// Orientation is the EXIF orientation, 1 (upright) to 8.
+	Orientation int `json:"orientation,omitempty"`
+
+	// Audio and video. Duration is in seconds.
+	Duration   float64 `json:"duration,omitempty"`
+	VideoCodec string  `json:"videoCodec,omitempty"`
+	AudioCodec string  `json:"audioCodec,omitempty"`
+	SampleRate int     `json:"sampleRate,omitempty"`
+	Channels   int     `json:"channels,omitempty"`
+
+	// Documents.
+	Pages int `json:"pages,omitempty"`
+
+	// Extra holds anything else an extractor found, keyed by name.
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// IsZero reports whether nothing is known about the file.
+func (m Metadata) IsZero() bool {
+	return len(m.Values()) == 0
+}
+
+// Values flattens m into named strings for naming patterns and similar
+// lookups; unknown fields are left out.
+func (m Metadata) Values() map[string]string {
+	out := map[string]string{}
+	for k, v := range m.Extra {
+		if v != "" {
+			out[k] = v
+		}
+	}
+	set := func(key string, value string) {
+		if value != "" {
+			out[key] = value
+		}
+	}
+	setInt := func(key string, value int) {
+		if value != 0 {
+			out[key] = strconv.Itoa(value)
+		}
+	}
+	setInt("width", m.Width)
+	setInt("height", m.Height)
+	set("colorModel", m.ColorModel)
+	setInt("dpi", m.DPI)
+	set("cameraMake", m.CameraMake)
+	set("cameraModel", m.CameraModel)
+	set("camera", m.Camera())
+	set("takenAt", m.TakenAt)
+	if date, _, ok := strings.Cut(m.TakenAt, "T"); ok {
+		set("date", date)
+	}
+	setInt("orientation", m.Orientation)
+	if m.Duration > 0 {
+		set("duration", strconv.FormatFloat(m.Duration, 'f', -1, 64))
+	}
+	set("videoCodec", m.VideoCodec)
+	set("audioCodec", m.AudioCodec)
+	setInt("sampleRate", m.SampleRate)
+	setInt("channels", m.Channels)
+	setInt("pages", m.Pages)
+	return out
+}
+
+// Camera is the make and model, without the make repeated when the model
+// already starts with it ("Canon Canon EOS R5").
+func (m Metadata) Camera() string {
+	brand, model := strings.TrimSpace(m.CameraMake), strings.TrimSpace(m.CameraModel)
+	switch {
+	case model == "":
+		return brand
+	case brand == "" || strings.HasPrefix(strings.ToLower(model), strings.ToLower(brand)):
+		return model
+	}
+	return brand + " " + model
+}
+
+// merge fills the fields of m that are still unknown from other.
+func (m *Metadata) merge(other Metadata) {
+	fillString := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fillInt := func(dst *int, src int) {
+		if *dst == 0 {
+			*dst = src
+		}
+	}
+	fillString(&m.Kind, other.Kind)
+	fillInt(&m.Width, other.Width)
+	fillInt(&m.Height, other.Height)
+	fillString(&m.ColorModel, other.ColorModel)
+	fillInt(&m.DPI, other.DPI)
+	fillString(&m.CameraMake, other.CameraMake)
+	fillString(&m.CameraModel, other.CameraModel)
+	fillString(&m.TakenAt, other.TakenAt)
+	fillInt(&m.Orientation, other.Orientation)
+	if m.Duration == 0 {
+		m.Duration = other.Duration
+	}
+	fillString(&m.VideoCodec, other.VideoCodec)
+	fillString(&m.AudioCodec, other.AudioCodec)
+	fillInt(&m.SampleRate, other.SampleRate)
+	fillInt(&m.Channels, other.Channels)
+	fillInt(&m.Pages, other.Pages)
+	for k, v := range other.Extra {
+		if _, ok := m.Extra[k]; ok || v == "" {
+			continue
+		}
+		if m.Extra == nil {
+			m.Extra = map[string]string{}
+		}
+		m.Extra[k] = v
+	}
+}
+
+// Extractor reads metadata from files of the types it supports.
+type Extractor interface {
+	ID() string
+	Supports(t filetype.Type) bool
+	Extract(ctx context.Context, path string, t filetype.Type) (Metadata, error)
+}
+
+// Inspector runs a read-only tool on a file and returns its stdout; the CLI
+// driver provides it so tools are resolved and isolated like skills.
+type Inspector interface {
+	Inspect(ctx context.Context, command string, args []string, path string) ([]byte, error)
+}
+
+// extractTimeout bounds all extractors together for one file.
+const extractTimeout = 20 * time.Second
+
+// Registry runs the extractors registered for a type, in order; earlier
+// extractors win where two report the same field.
+type Registry struct {
+	mu         sync.RWMutex
+	extractors []Extractor
+}
+
+func NewRegistry(extractors ...Extractor) *Registry {
+	return &Registry{extractors: extractors}
+}
+
+// Default returns the built-in extractors. The ones reading files in Go come
+// first; inspector, if set, lets ffprobe and ImageMagick fill in the rest.
+func Default(inspector Inspector) *Registry {
+	r := NewRegistry(&ImageExtractor{}, &WAVExtractor{}, &PDFExtractor{})
+	if inspector != nil {
+		r.Register(&ProbeExtractor{Inspector: inspector})
+		r.Register(&MagickExtractor{Inspector: inspector})
+	}
+	return r
+}
+
+// Register adds an extractor after the existing ones.
+func (r *Registry) Register(e Extractor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.extractors = append(r.extractors, e)
+}
+
+// Extract reads what the extractors can find about path, whose type is
+// mimeType or, when that is empty, the type ext stands for. Extractor
+// failures are skipped: metadata is best-effort.
+func (r *Registry) Extract(ctx context.Context, path string, mimeType string, ext string) Metadata {
+	t, ok := filetype.ByMIME(mimeType)
+	if !ok {
+		if t, ok = filetype.ByExtension(ext); !ok {
+			return Metadata{}
+		}
+	}
+	r.mu.RLock()
+	extractors := append([]Extractor(nil), r.extractors...)
+	r.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, extractTimeout)
+	defer cancel()
+	out := Metadata{Kind: string(t.Kind)}
+	for _, e := range extractors {
+		if ctx.Err() != nil {
+			break
+		}
+		if !e.Supports(t) {
+			continue
+		}
+		md, err := e.Extract(ctx, path, t)
+		if err != nil {
+			continue
+		}
+		out.merge(md)
+	}
+	return out
+}
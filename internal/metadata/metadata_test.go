@@ -0,0 +1,381 @@
+package metadata
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"asteria/internal/filetype"
+)
+
+// byteOrder is binary.LittleEndian or binary.BigEndian.
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	value []byte
+}
+
+func asciiEntry(tag uint16, s string) tiffEntry {
+	return tiffEntry{tag: tag, typ: 2, value: []byte(s + "\x00")}
+}
+
+func shortEntry(order byteOrder, tag uint16, v uint16) tiffEntry {
+	return tiffEntry{tag: tag, typ: 3, value: order.AppendUint16(nil, v)}
+}
+
+func rationalEntry(order byteOrder, tag uint16, num, den uint32) tiffEntry {
+	return tiffEntry{tag: tag, typ: 5, value: order.AppendUint32(order.AppendUint32(nil, num), den)}
+}
+
+// buildTIFF lays out a TIFF header, IFD0, an EXIF IFD when exif is not nil,
+// and the values that don't fit in their entries.
+func buildTIFF(order byteOrder, ifd0 []tiffEntry, exif []tiffEntry) []byte {
+	ifdSize := func(entries []tiffEntry) int { return 2 + 12*len(entries) + 4 }
+	if exif != nil {
+		ifd0 = append(ifd0, tiffEntry{tag: tagExifIFD, typ: 4})
+	}
+	exifAt := 8 + ifdSize(ifd0)
+	dataAt := exifAt
+	if exif != nil {
+		dataAt += ifdSize(exif)
+		ifd0[len(ifd0)-1].value = order.AppendUint32(nil, uint32(exifAt))
+	}
+
+	var extra []byte
+	writeIFD := func(out []byte, entries []tiffEntry) []byte {
+		out = order.AppendUint16(out, uint16(len(entries)))
+		for _, e := range entries {
+			out = order.AppendUint16(out, e.tag)
+			out = order.AppendUint16(out, e.typ)
+			out = order.AppendUint32(out, uint32(len(e.value)/typeSize(e.typ)))
+			if len(e.value) > 4 {
+				out = order.AppendUint32(out, uint32(dataAt+len(extra)))
+				extra = append(extra, e.value...)
+			} else {
+				out = append(out, e.value...)
+				out = append(out, make([]byte, 4-len(e.value))...)
+			}
+		}
+		return order.AppendUint32(out, 0)
+	}
+
+	out := []byte("II")
+	if order == binary.BigEndian {
+		out = []byte("MM")
+	}
+	out = order.AppendUint16(out, 42)
+	out = order.AppendUint32(out, 8)
+	out = writeIFD(out, ifd0)
+	if exif != nil {
+		out = writeIFD(out, exif)
+	}
+	return append(out, extra...)
+}
+
+func cameraTIFF(order byteOrder) []byte {
+	return buildTIFF(order, []tiffEntry{
+		asciiEntry(tagMake, "Canon"),
+		asciiEntry(tagModel, "Canon EOS R5"),
+		shortEntry(order, tagOrientation, 6),
+		shortEntry(order, tagImageWidth, 8192),
+		shortEntry(order, tagImageLength, 5464),
+		rationalEntry(order, tagXResolution, 300, 1),
+		asciiEntry(tagDateTime, "2024:05:01 09:00:00"),
+	}, []tiffEntry{
+		asciiEntry(tagDateTimeOriginal, "2024:04:30 18:12:45"),
+	})
+}
+
+func TestParseTIFF(t *testing.T) {
+	le, be := binary.LittleEndian, binary.BigEndian
+	camera := Metadata{
+		Width: 8192, Height: 5464, DPI: 300, Orientation: 6,
+		CameraMake: "Canon", CameraModel: "Canon EOS R5", TakenAt: "2024-04-30T18:12:45",
+	}
+	badValue := buildTIFF(le, []tiffEntry{asciiEntry(tagMake, "Nikon Corporation"), shortEntry(le, tagOrientation, 1)}, nil)
+	badValue[8+2+8] = 0xF0 // point the make past the end
+
+	tests := []struct {
+		name   string
+		data   []byte
+		want   Metadata
+		wantOK bool
+	}{
+		{"little endian", cameraTIFF(le), camera, true},
+		{"big endian", cameraTIFF(be), camera, true},
+		{
+			name: "centimeters and no exif ifd",
+			data: buildTIFF(be, []tiffEntry{
+				rationalEntry(be, tagXResolution, 11811, 100),
+				shortEntry(be, tagResolutionUnit, 3),
+				asciiEntry(tagDateTime, "2020:01:02 03:04:05"),
+			}, nil),
+			want:   Metadata{DPI: 300, TakenAt: "2020-01-02T03:04:05"},
+			wantOK: true,
+		},
+		{
+			name: "invalid orientation and zero date",
+			data: buildTIFF(le, []tiffEntry{
+				shortEntry(le, tagOrientation, 9),
+				asciiEntry(tagDateTime, "0000:00:00 00:00:00"),
+				rationalEntry(le, tagXResolution, 72, 0),
+			}, nil),
+			wantOK: true,
+		},
+		{"value outside data", badValue, Metadata{Orientation: 1}, true},
+		{"empty", nil, Metadata{}, false},
+		{"truncated header", []byte("II*\x00\x08\x00"), Metadata{}, false},
+		{"bad magic", []byte("II\x2b\x00\x08\x00\x00\x00\x00\x00"), Metadata{}, false},
+		{"ifd past end", []byte("MM\x00\x2a\x00\x00\x10\x00\x00\x00"), Metadata{}, false},
+		{"ifd in header", []byte("II*\x00\x04\x00\x00\x00\x00\x00"), Metadata{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTIFF(tt.data)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTIFF = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	out := []byte{0xFF, marker}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(payload)+2))
+	return append(out, payload...)
+}
+
+func jfif(unit byte, density uint16) []byte {
+	payload := []byte("JFIF\x00\x01\x02")
+	payload = append(payload, unit)
+	payload = binary.BigEndian.AppendUint16(payload, density)
+	payload = binary.BigEndian.AppendUint16(payload, density)
+	return jpegSegment(0xE0, append(payload, 0, 0))
+}
+
+func jpegFile(segments ...[]byte) []byte {
+	out := []byte{0xFF, 0xD8}
+	for _, s := range segments {
+		out = append(out, s...)
+	}
+	return out
+}
+
+func TestJPEGHeaders(t *testing.T) {
+	exif := jpegSegment(0xE1, append([]byte("Exif\x00\x00"), cameraTIFF(binary.BigEndian)...))
+	tests := []struct {
+		name string
+		data []byte
+		want Metadata
+	}{
+		{
+			name: "jfif density wins over exif",
+			data: jpegFile(jfif(1, 72), exif),
+			want: Metadata{Width: 8192, Height: 5464, DPI: 72, Orientation: 6, CameraMake: "Canon", CameraModel: "Canon EOS R5", TakenAt: "2024-04-30T18:12:45"},
+		},
+		{"dots per centimeter", jpegFile(jfif(2, 118)), Metadata{DPI: 300}},
+		{"aspect ratio only", jpegFile(jfif(0, 1)), Metadata{}},
+		{"fill bytes", jpegFile([]byte{0xFF}, jfif(1, 96)), Metadata{DPI: 96}},
+		{"stops at start of scan", jpegFile(jpegSegment(0xDA, []byte{0, 0}), jfif(1, 96)), Metadata{}},
+		{"truncated segment", jpegFile(jfif(1, 96)[:10]), Metadata{}},
+		{"segment size below two", jpegFile([]byte{0xFF, 0xE0, 0x00, 0x01}, jfif(1, 96)), Metadata{}},
+		{"exif without tiff", jpegFile(jpegSegment(0xE1, []byte("Exif\x00\x00MM"))), Metadata{}},
+		{"soi only", jpegFile(), Metadata{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegHeaders(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jpegHeaders = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func pngChunk(kind string, data []byte) []byte {
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	out = append(out, kind...)
+	out = append(out, data...)
+	return append(out, 0, 0, 0, 0) // CRC, not checked
+}
+
+func pngFile(chunks ...[]byte) []byte {
+	out := []byte("\x89PNG\r\n\x1a\n")
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return out
+}
+
+func phys(perMeter uint32, unit byte) []byte {
+	data := binary.BigEndian.AppendUint32(nil, perMeter)
+	data = binary.BigEndian.AppendUint32(data, perMeter)
+	return pngChunk("pHYs", append(data, unit))
+}
+
+func TestPNGChunks(t *testing.T) {
+	ihdr := pngChunk("IHDR", make([]byte, 13))
+	tests := []struct {
+		name string
+		data []byte
+		want Metadata
+	}{
+		{
+			name: "phys and exif",
+			data: pngFile(ihdr, phys(11811, 1), pngChunk("eXIf", cameraTIFF(binary.LittleEndian))),
+			want: Metadata{Width: 8192, Height: 5464, DPI: 300, Orientation: 6, CameraMake: "Canon", CameraModel: "Canon EOS R5", TakenAt: "2024-04-30T18:12:45"},
+		},
+		{"unknown unit", pngFile(ihdr, phys(11811, 0)), Metadata{}},
+		{"stops at image data", pngFile(ihdr, pngChunk("IDAT", []byte{1}), phys(11811, 1)), Metadata{}},
+		{"truncated chunk", pngFile(ihdr, phys(11811, 1)[:12]), Metadata{}},
+		{"short phys", pngFile(pngChunk("pHYs", []byte{1, 2, 3})), Metadata{}},
+		{"huge size", pngFile([]byte("\xff\xff\xff\xffpHYs")), Metadata{}},
+		{"signature only", pngFile(), Metadata{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pngChunks(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pngChunks = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func wavChunk(kind string, data []byte) []byte {
+	out := append([]byte(kind), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+	out = append(out, data...)
+	if len(data)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+func wavFmt(format, channels uint16, rate uint32, bits uint16) []byte {
+	blockAlign := channels * bits / 8
+	data := binary.LittleEndian.AppendUint16(nil, format)
+	data = binary.LittleEndian.AppendUint16(data, channels)
+	data = binary.LittleEndian.AppendUint32(data, rate)
+	data = binary.LittleEndian.AppendUint32(data, rate*uint32(blockAlign))
+	data = binary.LittleEndian.AppendUint16(data, blockAlign)
+	return wavChunk("fmt ", binary.LittleEndian.AppendUint16(data, bits))
+}
+
+func wavFile(chunks ...[]byte) []byte {
+	out := []byte("RIFF\x00\x00\x00\x00WAVE")
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return out
+}
+
+func extractWAV(t testing.TB, data []byte) (Metadata, error) {
+	path := filepath.Join(t.TempDir(), "in.wav")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wav, _ := filetype.ByMIME("audio/wav")
+	return (&WAVExtractor{}).Extract(context.Background(), path, wav)
+}
+
+func TestWAVExtractor(t *testing.T) {
+	second := make([]byte, 44100*2*2)
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Metadata
+		wantErr bool
+	}{
+		{
+			name: "pcm after an odd sized chunk",
+			data: wavFile(wavChunk("LIST", []byte("INFOx")), wavFmt(1, 2, 44100, 16), wavChunk("data", second)),
+			want: Metadata{AudioCodec: "pcm_s16le", Channels: 2, SampleRate: 44100, Duration: 1},
+		},
+		{
+			name: "float with extended fmt",
+			data: wavFile(wavChunk("fmt ", append(wavFmt(3, 1, 48000, 32)[8:], 0, 0)), wavChunk("data", make([]byte, 96000))),
+			want: Metadata{AudioCodec: "pcm_f32le", Channels: 1, SampleRate: 48000, Duration: 0.5},
+		},
+		{
+			name: "data before fmt",
+			data: wavFile(wavChunk("data", make([]byte, 8)), wavFmt(1, 1, 8000, 8)),
+			want: Metadata{},
+		},
+		{
+			name: "truncated fmt",
+			data: wavFile(wavFmt(1, 2, 44100, 16)[:20]),
+			want: Metadata{},
+		},
+		{
+			name: "no data chunk",
+			data: wavFile(wavFmt(6, 1, 8000, 8)),
+			want: Metadata{AudioCodec: "pcm_alaw", Channels: 1, SampleRate: 8000},
+		},
+		{"short header", []byte("RIFF"), Metadata{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractWAV(t, tt.data)
+			if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// checkParsed fails on values no parser may produce, whatever the input.
+func checkParsed(t *testing.T, md Metadata) {
+	t.Helper()
+	if md.Orientation < 0 || md.Orientation > 8 {
+		t.Errorf("orientation %d out of range", md.Orientation)
+	}
+	if md.TakenAt != "" && len(md.TakenAt) != len("2006-01-02T15:04:05") {
+		t.Errorf("malformed time %q", md.TakenAt)
+	}
+}
+
+func FuzzParseTIFF(f *testing.F) {
+	f.Add(cameraTIFF(binary.LittleEndian))
+	f.Add(cameraTIFF(binary.BigEndian))
+	f.Add([]byte("MM\x00\x2a\x00\x00\x00\x08\xff\xff"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		md, ok := parseTIFF(data)
+		if !ok && !reflect.DeepEqual(md, Metadata{}) {
+			t.Errorf("rejected input returned %+v", md)
+		}
+		checkParsed(t, md)
+	})
+}
+
+func FuzzParseJPEG(f *testing.F) {
+	f.Add(jpegFile(jfif(1, 72), jpegSegment(0xE1, append([]byte("Exif\x00\x00"), cameraTIFF(binary.BigEndian)...))))
+	f.Add(jpegFile([]byte{0xFF, 0xFF, 0xFF}))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkParsed(t, jpegHeaders(data))
+	})
+}
+
+func FuzzParsePNG(f *testing.F) {
+	f.Add(pngFile(phys(11811, 1), pngChunk("eXIf", cameraTIFF(binary.LittleEndian))))
+	f.Add(pngFile([]byte("\x7f\xff\xff\xf0pHYs")))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkParsed(t, pngChunks(data))
+	})
+}
+
+func FuzzParseWAV(f *testing.F) {
+	f.Add(wavFile(wavFmt(1, 2, 44100, 16), wavChunk("data", make([]byte, 16))))
+	f.Add(wavFile(wavChunk("fmt ", []byte{1, 0}), []byte("data\xff\xff\xff\xff")))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		md, err := extractWAV(t, data)
+		if err == nil && (md.Duration < 0 || md.Channels < 0) {
+			t.Errorf("negative values in %+v", md)
+		}
+	})
+}
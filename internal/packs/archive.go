@@ -0,0 +1,153 @@
+package packs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// maxArchiveBytes bounds the total unpacked size of a pack.
+	maxArchiveBytes = 64 << 20
+	// maxArchiveFiles bounds the number of entries in a pack.
+	maxArchiveFiles = 2000
+)
+
+// IsArchive reports whether path has a supported pack archive extension.
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ".zip") || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+// extract unpacks a .zip or .tar.gz archive into dst. Only regular files and
+// directories are written; links and entries escaping dst are rejected.
+func extract(archivePath string, dst string) error {
+	lower := strings.ToLower(archivePath)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return extractZip(archivePath, dst)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return extractTarGz(archivePath, dst)
+	default:
+		return fmt.Errorf("unsupported pack archive: %s", filepath.Base(archivePath))
+	}
+}
+
+func extractZip(archivePath string, dst string) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if len(r.File) > maxArchiveFiles {
+		return fmt.Errorf("pack archive has too many files")
+	}
+	budget := int64(maxArchiveBytes)
+	for _, f := range r.File {
+		target, err := entryPath(dst, f.Name)
+		if err != nil {
+			return err
+		}
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			budget, err = writeEntry(target, rc, budget)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("pack archive entry %q is not a regular file", f.Name)
+		}
+	}
+	return nil
+}
+
+func extractTarGz(archivePath string, dst string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	budget := int64(maxArchiveBytes)
+	for files := 0; ; files++ {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if files >= maxArchiveFiles {
+			return fmt.Errorf("pack archive has too many files")
+		}
+		target, err := entryPath(dst, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			budget, err = writeEntry(target, tr, budget)
+			if err != nil {
+				return err
+			}
+		case tar.TypeXGlobalHeader:
+		default:
+			return fmt.Errorf("pack archive entry %q is not a regular file", hdr.Name)
+		}
+	}
+}
+
+// entryPath resolves an archive entry name inside dst, rejecting absolute
+// paths and ".." segments. The root entry ("./", common in tarballs)
+// resolves to dst itself.
+func entryPath(dst string, name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(strings.ReplaceAll(name, "\\", "/")))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("pack archive entry %q escapes the pack folder", name)
+	}
+	return filepath.Join(dst, clean), nil
+}
+
+func writeEntry(target string, r io.Reader, budget int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return budget, err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return budget, err
+	}
+	defer out.Close()
+	n, err := io.Copy(out, io.LimitReader(r, budget+1))
+	if err != nil {
+		return budget, err
+	}
+	if n > budget {
+		return budget, fmt.Errorf("pack archive is larger than %d MB", maxArchiveBytes>>20)
+	}
+	return budget - n, nil
+}
@@ -0,0 +1,44 @@
+package packs
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEntryPath(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "pack")
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "skill.json", want: filepath.Join(dst, "skill.json")},
+		{name: "skills/resize.json", want: filepath.Join(dst, "skills", "resize.json")},
+		{name: "skills/", want: filepath.Join(dst, "skills")},
+		{name: "./", want: dst},
+		{name: ".", want: dst},
+		{name: "a/../b.json", want: filepath.Join(dst, "b.json")},
+		{name: `skills\resize.json`, want: filepath.Join(dst, "skills", "resize.json")},
+		{name: "../escape.json", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: "skills/../../escape.json", wantErr: true},
+		{name: `..\escape.json`, wantErr: true},
+		{name: "/etc/passwd", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := entryPath(dst, tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("entryPath(%q) = %q, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("entryPath(%q): %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("entryPath(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package packs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"asteria/internal/skills"
+)
+
+// catalogFilename is looked up when a catalog source is a folder.
+const catalogFilename = "catalog.json"
+
+// Catalog is a JSON index of packs and their published versions.
+type Catalog struct {
+	Packs []CatalogPack `json:"packs"`
+}
+
+type CatalogPack struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name,omitempty"`
+	Author      string           `json:"author,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Versions    []CatalogVersion `json:"versions"`
+}
+
+type CatalogVersion struct {
+	Version string `json:"version"`
+	// URL of the archive, absolute or relative to the catalog.
+	URL           string   `json:"url"`
+	SHA256        string   `json:"sha256,omitempty"`
+	MinAppVersion string   `json:"minAppVersion,omitempty"`
+	Permissions   []string `json:"permissions,omitempty"`
+}
+
+// CatalogEntry is a catalog pack merged with its installed state.
+type CatalogEntry struct {
+	CatalogPack
+	Source           string `json:"source"`
+	LatestVersion    string `json:"latestVersion"`
+	InstalledVersion string `json:"installedVersion,omitempty"`
+	UpdateAvailable  bool   `json:"updateAvailable"`
+}
+
+// CatalogClient reads catalogs from local folders, local files or HTTPS
+// mirrors.
+type CatalogClient struct {
+	HTTP *http.Client
+}
+
+func NewCatalogClient() *CatalogClient {
+	return &CatalogClient{HTTP: &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			if isPlainHTTP(req.URL.String()) && !isPlainHTTP(via[0].URL.String()) {
+				return fmt.Errorf("refusing redirect from https to %s", req.URL.Redacted())
+			}
+			return nil
+		},
+	}}
+}
+
+// CheckSource rejects catalog sources that can't be trusted. A catalog
+// pins its archives with their sha256, so it must not come over plain http,
+// where whoever controls the network controls both.
+func CheckSource(source string) error {
+	if isPlainHTTP(source) {
+		return fmt.Errorf("catalog %s: catalogs must be served over https", source)
+	}
+	return nil
+}
+
+// Load reads the catalog at source and sorts each pack's versions newest
+// first.
+func (c *CatalogClient) Load(source string) (Catalog, error) {
+	if err := CheckSource(source); err != nil {
+		return Catalog{}, err
+	}
+	data, err := c.read(catalogLocation(source))
+	if err != nil {
+		return Catalog{}, fmt.Errorf("catalog %s: %w", source, err)
+	}
+	var catalog Catalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return Catalog{}, fmt.Errorf("catalog %s: %w", source, err)
+	}
+	for i := range catalog.Packs {
+		versions := catalog.Packs[i].Versions
+		sort.SliceStable(versions, func(a, b int) bool {
+			return skills.CompareVersions(versions[a].Version, versions[b].Version) > 0
+		})
+	}
+	return catalog, nil
+}
+
+// Entries merges all catalogs with the installed packs. The first catalog
+// listing a pack wins.
+func (c *CatalogClient) Entries(sources []string, installed []skills.Pack) ([]CatalogEntry, error) {
+	installedVersions := make(map[string]string, len(installed))
+	for _, p := range installed {
+		installedVersions[p.Manifest.ID] = p.Manifest.Version
+	}
+	seen := make(map[string]bool)
+	var out []CatalogEntry
+	var errOut error
+	for _, source := range sources {
+		catalog, err := c.Load(source)
+		if err != nil {
+			errOut = joinErr(errOut, err)
+			continue
+		}
+		for _, p := range catalog.Packs {
+			if seen[p.ID] || len(p.Versions) == 0 {
+				continue
+			}
+			seen[p.ID] = true
+			entry := CatalogEntry{
+				CatalogPack:      p,
+				Source:           source,
+				LatestVersion:    p.Versions[0].Version,
+				InstalledVersion: installedVersions[p.ID],
+			}
+			entry.UpdateAvailable = entry.InstalledVersion != "" && skills.CompareVersions(entry.LatestVersion, entry.InstalledVersion) > 0
+			out = append(out, entry)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, errOut
+}
+
+// Download fetches the archive of packID at version (latest when empty) into
+// dir, verifying its checksum when the catalog publishes one. Catalogs are
+// tried in order until one lists the version.
+func (c *CatalogClient) Download(sources []string, packID string, version string, dir string) (string, error) {
+	listed := false
+	var loadErr error
+	for _, source := range sources {
+		catalog, err := c.Load(source)
+		if err != nil {
+			loadErr = joinErr(loadErr, err)
+			continue
+		}
+		for _, p := range catalog.Packs {
+			if p.ID != packID {
+				continue
+			}
+			listed = true
+			for _, v := range p.Versions {
+				if version != "" && v.Version != version {
+					continue
+				}
+				return c.fetchArchive(source, p.ID, v, dir)
+			}
+		}
+	}
+	switch {
+	case !listed && loadErr != nil:
+		return "", fmt.Errorf("pack %s not found in any catalog: %w", packID, loadErr)
+	case !listed:
+		return "", fmt.Errorf("pack %s not found in any catalog", packID)
+	case version == "":
+		return "", fmt.Errorf("pack %s has no published version", packID)
+	default:
+		return "", fmt.Errorf("pack %s has no version %s in any catalog", packID, version)
+	}
+}
+
+func (c *CatalogClient) fetchArchive(source string, packID string, v CatalogVersion, dir string) (string, error) {
+	location, err := resolveURL(catalogLocation(source), v.URL)
+	if err != nil {
+		return "", err
+	}
+	// Over plain http the archive could be swapped in transit; only a
+	// checksum from the catalog, itself never read over http, pins it.
+	if v.SHA256 == "" && isPlainHTTP(location) {
+		return "", fmt.Errorf("download %s %s: the catalog must list a sha256 for archives served over http", packID, v.Version)
+	}
+	data, err := c.read(location)
+	if err != nil {
+		return "", fmt.Errorf("download %s %s: %w", packID, v.Version, err)
+	}
+	if v.SHA256 != "" {
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), v.SHA256) {
+			return "", fmt.Errorf("download %s %s: checksum mismatch", packID, v.Version)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	ext := ".zip"
+	if lower := strings.ToLower(location); strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") {
+		ext = ".tar.gz"
+	}
+	path := filepath.Join(dir, packID+"-"+v.Version+ext)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// read fetches a catalog or archive, refusing anything over maxArchiveBytes
+// rather than returning it cut short.
+func (c *CatalogClient) read(location string) ([]byte, error) {
+	var r io.Reader
+	if isHTTP(location) {
+		resp, err := c.HTTP.Get(location)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", location, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(location)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxArchiveBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxArchiveBytes {
+		return nil, fmt.Errorf("%s is larger than %d MB", location, maxArchiveBytes>>20)
+	}
+	return data, nil
+}
+
+// catalogLocation turns a folder source into the path of its catalog.json.
+func catalogLocation(source string) string {
+	if isHTTP(source) {
+		return source
+	}
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		return filepath.Join(source, catalogFilename)
+	}
+	return source
+}
+
+func resolveURL(catalog string, ref string) (string, error) {
+	if isHTTP(ref) || filepath.IsAbs(ref) {
+		return ref, nil
+	}
+	if isHTTP(catalog) {
+		base, err := url.Parse(catalog)
+		if err != nil {
+			return "", err
+		}
+		rel, err := url.Parse(ref)
+		if err != nil {
+			return "", err
+		}
+		return base.ResolveReference(rel).String(), nil
+	}
+	return filepath.Join(filepath.Dir(catalog), filepath.FromSlash(ref)), nil
+}
+
+func isHTTP(s string) bool {
+	lower := strings.ToLower(s)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+func isPlainHTTP(s string) bool {
+	return strings.HasPrefix(strings.ToLower(s), "http://")
+}
+
+func joinErr(existing error, next error) error {
+	if next == nil {
+		return existing
+	}
+	if existing == nil {
+		return next
+	}
+	return fmt.Errorf("%v; %w", existing, next)
+}
@@ -0,0 +1,138 @@
+package packs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCatalog writes a catalog folder whose archives are local files.
+func writeCatalog(t *testing.T, packs ...CatalogPack) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, p := range packs {
+		for _, v := range p.Versions {
+			if err := os.WriteFile(filepath.Join(dir, v.URL), []byte(p.ID+" "+v.Version), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	data, err := json.Marshal(Catalog{Packs: packs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, catalogFilename), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestDownload(t *testing.T) {
+	first := writeCatalog(t, CatalogPack{ID: "tools", Versions: []CatalogVersion{{Version: "1.0.0", URL: "tools-1.zip"}}})
+	second := writeCatalog(t,
+		CatalogPack{ID: "tools", Versions: []CatalogVersion{{Version: "2.0.0", URL: "tools-2.zip"}}},
+		CatalogPack{ID: "empty"},
+	)
+	sources := []string{first, second}
+	c := NewCatalogClient()
+
+	tests := []struct {
+		name    string
+		packID  string
+		version string
+		want    string
+		wantErr string
+	}{
+		{name: "latest from the first catalog", packID: "tools", want: "tools 1.0.0"},
+		{name: "version from a later catalog", packID: "tools", version: "2.0.0", want: "tools 2.0.0"},
+		{name: "unknown version", packID: "tools", version: "3.0.0", wantErr: "no version 3.0.0"},
+		{name: "no versions", packID: "empty", wantErr: "no published version"},
+		{name: "unknown pack", packID: "missing", wantErr: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := c.Download(sources, tt.packID, tt.version, t.TempDir())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Download() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("downloaded %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalogTransport(t *testing.T) {
+	archive := []byte("archive")
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/catalog.json" {
+			w.Write([]byte(`{"packs":[]}`))
+			return
+		}
+		w.Write(archive)
+	}))
+	defer plain.Close()
+	sum := sha256.Sum256(archive)
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/catalog.json":
+			json.NewEncoder(w).Encode(Catalog{Packs: []CatalogPack{
+				{ID: "pinned", Versions: []CatalogVersion{{Version: "1.0.0", URL: plain.URL + "/pinned.zip", SHA256: hex.EncodeToString(sum[:])}}},
+				{ID: "unpinned", Versions: []CatalogVersion{{Version: "1.0.0", URL: plain.URL + "/unpinned.zip"}}},
+				{ID: "https", Versions: []CatalogVersion{{Version: "1.0.0", URL: "archive.zip"}}},
+			}})
+		case "/downgrade.json":
+			http.Redirect(w, r, plain.URL+"/catalog.json", http.StatusFound)
+		default:
+			w.Write(archive)
+		}
+	}))
+	defer secure.Close()
+
+	c := NewCatalogClient()
+	c.HTTP.Transport = secure.Client().Transport
+	source := secure.URL + "/catalog.json"
+
+	tests := []struct {
+		name    string
+		source  string
+		packID  string
+		wantErr string
+	}{
+		{name: "http catalog", source: plain.URL + "/catalog.json", packID: "pinned", wantErr: "https"},
+		{name: "redirect to http", source: secure.URL + "/downgrade.json", packID: "pinned", wantErr: "redirect"},
+		{name: "http archive pinned by an https catalog", source: source, packID: "pinned"},
+		{name: "http archive without a checksum", source: source, packID: "unpinned", wantErr: "sha256"},
+		{name: "https archive", source: source, packID: "https"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.Download([]string{tt.source}, tt.packID, "", t.TempDir())
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Download() = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Download() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+	if err := CheckSource(plain.URL); err == nil {
+		t.Error("CheckSource accepted an http catalog")
+	}
+}
@@ -0,0 +1,205 @@
+package packs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"asteria/internal/skills"
+)
+
+// Inspection is what a pack archive would install, shown to the user before
+// installing.
+type Inspection struct {
+	Pack skills.Pack `json:"pack"`
+	// ElevatedPermissions are the permissions that need a trust decision.
+	ElevatedPermissions []string `json:"elevatedPermissions"`
+	// InstalledVersion is the version currently installed, if any.
+	InstalledVersion string `json:"installedVersion,omitempty"`
+	Warnings         string `json:"warnings,omitempty"`
+}
+
+// Installer unpacks pack archives into the community skills directory.
+type Installer struct {
+	SkillsDir  string
+	StagingDir string
+	AppVersion string
+	// TrustedPublishers verify the archive's signature.
+	TrustedPublishers []skills.Publisher
+	// Policy rejects packs an administrator disallows before installing.
+	Policy skills.Policy
+}
+
+// Inspect unpacks archivePath into a staging folder and validates it without
+// installing.
+func (i *Installer) Inspect(archivePath string) (Inspection, error) {
+	staged, cleanup, err := i.stage(archivePath)
+	if err != nil {
+		return Inspection{}, err
+	}
+	defer cleanup()
+	return i.inspection(staged)
+}
+
+// Install validates the archive and moves the pack into SkillsDir/<pack id>,
+// replacing any installed version. accepted are the elevated permissions the
+// user agreed to after Inspect; a pack asking for more is not installed.
+func (i *Installer) Install(archivePath string, accepted []string) (Inspection, error) {
+	staged, cleanup, err := i.stage(archivePath)
+	if err != nil {
+		return Inspection{}, err
+	}
+	defer cleanup()
+	insp, err := i.inspection(staged)
+	if err != nil {
+		return Inspection{}, err
+	}
+	var unaccepted []string
+	for _, p := range insp.ElevatedPermissions {
+		if !slices.Contains(accepted, p) {
+			unaccepted = append(unaccepted, p)
+		}
+	}
+	if len(unaccepted) > 0 {
+		return Inspection{}, fmt.Errorf("pack %s asks for permissions that were not accepted: %s", insp.Pack.Manifest.ID, strings.Join(unaccepted, ", "))
+	}
+
+	if err := os.MkdirAll(i.SkillsDir, 0o755); err != nil {
+		return Inspection{}, err
+	}
+	dest := filepath.Join(i.SkillsDir, insp.Pack.Manifest.ID)
+	backup := ""
+	if _, err := os.Stat(dest); err == nil {
+		backup = filepath.Join(i.StagingDir, insp.Pack.Manifest.ID+".previous")
+		_ = os.RemoveAll(backup)
+		if err := os.Rename(dest, backup); err != nil {
+			return Inspection{}, err
+		}
+	}
+	if err := os.Rename(staged.dir, dest); err != nil {
+		if backup != "" {
+			_ = os.Rename(backup, dest)
+		}
+		return Inspection{}, err
+	}
+	if backup != "" {
+		_ = os.RemoveAll(backup)
+	}
+	insp.Pack.Dir = dest
+	return insp, nil
+}
+
+// manifestNames are the manifest filenames the skills loader recognises.
+var manifestNames = []string{"manifest.json", "pack.json"}
+
+type stagedPack struct {
+	// dir is the pack folder (the one holding the manifest).
+	dir string
+}
+
+// stage extracts the archive into a fresh folder under StagingDir. The
+// manifest may sit at the archive root or inside a single top-level folder.
+func (i *Installer) stage(archivePath string) (stagedPack, func(), error) {
+	if !IsArchive(archivePath) {
+		return stagedPack{}, nil, fmt.Errorf("unsupported pack archive: %s", filepath.Base(archivePath))
+	}
+	if err := os.MkdirAll(i.StagingDir, 0o755); err != nil {
+		return stagedPack{}, nil, err
+	}
+	root, err := os.MkdirTemp(i.StagingDir, "pack-")
+	if err != nil {
+		return stagedPack{}, nil, err
+	}
+	cleanup := func() { _ = os.RemoveAll(root) }
+	if err := extract(archivePath, root); err != nil {
+		cleanup()
+		return stagedPack{}, nil, err
+	}
+	dir, err := findPackDir(root)
+	if err != nil {
+		cleanup()
+		return stagedPack{}, nil, err
+	}
+	return stagedPack{dir: dir}, cleanup, nil
+}
+
+func findPackDir(root string) (string, error) {
+	if hasManifest(root) {
+		return root, nil
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		dir := filepath.Join(root, entries[0].Name())
+		if hasManifest(dir) {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("pack archive has no manifest.json")
+}
+
+func hasManifest(dir string) bool {
+	for _, name := range manifestNames {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// inspection loads the staged pack with a throwaway loader so it is validated
+// exactly like an installed one.
+func (i *Installer) inspection(staged stagedPack) (Inspection, error) {
+	loader := skills.NewLoader(skills.LoaderOptions{
+		CommunityRoot:     staged.dir,
+		AppVersion:        i.AppVersion,
+		TrustedPublishers: i.TrustedPublishers,
+	})
+	loadErr := loader.LoadAll()
+	packs := loader.Packs()
+	if len(packs) != 1 {
+		if loadErr != nil {
+			return Inspection{}, loadErr
+		}
+		return Inspection{}, fmt.Errorf("pack archive has no valid manifest")
+	}
+	pack := packs[0]
+	if pack.Verification == skills.VerificationTampered {
+		return Inspection{}, fmt.Errorf("pack %s does not match its signature", pack.Manifest.ID)
+	}
+	if pack.Error != "" {
+		return Inspection{}, fmt.Errorf("pack %s: %s", pack.Manifest.ID, pack.Error)
+	}
+	if reason := i.Policy.BlocksPack(pack); reason != "" {
+		return Inspection{}, fmt.Errorf("%s", reason)
+	}
+	if len(pack.SkillIDs) == 0 {
+		return Inspection{}, fmt.Errorf("pack %s contains no valid skills", pack.Manifest.ID)
+	}
+	insp := Inspection{
+		Pack:                pack,
+		ElevatedPermissions: skills.ElevatedPermissions(pack.Manifest.Permissions),
+	}
+	if loadErr != nil {
+		insp.Warnings = loadErr.Error()
+	}
+	insp.InstalledVersion = installedVersion(filepath.Join(i.SkillsDir, pack.Manifest.ID))
+	return insp, nil
+}
+
+func installedVersion(dir string) string {
+	for _, name := range manifestNames {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if m, err := skills.ParsePackManifest(b); err == nil {
+			return m.Version
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package packs
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePackZip writes a pack archive whose manifest declares permissions.
+func writePackZip(t *testing.T, permissions string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pack.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	files := map[string]string{
+		"manifest.json": `{"id":"acme","version":"1.0.0","permissions":` + permissions + `}`,
+		"blur.json": `{"id":"acme.blur","name":"Blur","version":"1.0.0","category":"filter",
+			"inputTypes":[".png"],"driver":"image","executor":{"type":"native","handler":"image.blur"},
+			"permissions":["files.read","files.write"]}`,
+	}
+	for name, content := range files {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInstallRequiresAcceptedPermissions(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions string
+		accepted    []string
+		wantErr     string
+	}{
+		{name: "no elevated permissions", permissions: `[]`},
+		{name: "all accepted", permissions: `["network","system"]`, accepted: []string{"network", "system"}},
+		{name: "none accepted", permissions: `["network"]`, wantErr: "not accepted: network"},
+		{name: "some accepted", permissions: `["network","system"]`, accepted: []string{"network"}, wantErr: "not accepted: system"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			installer := &Installer{
+				SkillsDir:  filepath.Join(root, "skills"),
+				StagingDir: filepath.Join(root, "staging"),
+			}
+			archive := writePackZip(t, tt.permissions)
+			insp, err := installer.Install(archive, tt.accepted)
+			installed := false
+			if _, statErr := os.Stat(filepath.Join(installer.SkillsDir, "acme", "manifest.json")); statErr == nil {
+				installed = true
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Install() error = %v, want %q", err, tt.wantErr)
+				}
+				if installed {
+					t.Error("pack was installed although its permissions were not accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !installed || insp.Pack.Manifest.ID != "acme" {
+				t.Errorf("pack not installed: %+v", insp)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const rlimitNproc = 6
+
+// rlimits lists the resources and values to set for limits. nproc is the
+// absolute RLIMIT_NPROC value (see absoluteProcesses).
+func rlimits(limits Limits, nproc uint64) map[int]uint64 {
+	out := make(map[int]uint64)
+	if limits.MemoryBytes > 0 {
+		out[syscall.RLIMIT_AS] = limits.MemoryBytes
+	}
+	if limits.CPUSeconds > 0 {
+		out[syscall.RLIMIT_CPU] = limits.CPUSeconds
+	}
+	if limits.FileSizeBytes > 0 {
+		out[syscall.RLIMIT_FSIZE] = limits.FileSizeBytes
+	}
+	if nproc > 0 {
+		out[rlimitNproc] = nproc
+	}
+	return out
+}
+
+// absoluteProcesses turns the per-command process allowance into an
+// RLIMIT_NPROC value. It must run on the host side: inside the sandbox's PID
+// namespace the user's other processes are not visible.
+func absoluteProcesses(limits Limits) uint64 {
+	if limits.Processes == 0 {
+		return 0
+	}
+	return userProcesses() + limits.Processes
+}
+
+// setLimits applies limits to the calling process; the sandbox helper calls
+// it right before exec so the command never runs unlimited.
+func setLimits(limits Limits, nproc uint64) error {
+	for resource, v := range rlimits(limits, nproc) {
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: v, Max: v}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allocationFailures are what tools print when an allocation fails, as it
+// does at RLIMIT_AS: malloc returns NULL rather than the kernel signaling.
+var allocationFailures = []string{
+	"cannot allocate memory",
+	"out of memory",
+	"memory allocation failed",
+	"memoryallocationfailed",
+	"failed to allocate",
+	"bad_alloc",
+}
+
+// Exceeded explains a command failure caused by one of limits, or returns
+// "" when the failure looks unrelated. CPU and file size overruns are told by
+// the signals the kernel sends for them. A memory overrun has no signal of
+// its own: with a memory limit set, a command that dies of a signal or exits
+// on a failed allocation is taken to have hit it. Callers rule out timeouts
+// and cancellation first, which kill the command too.
+func Exceeded(err error, limits Limits, stderr string) string {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return ""
+	}
+	memory := fmt.Sprintf("exceeded the memory limit of %d MB", limits.MemoryBytes>>20)
+	status, _ := exitErr.Sys().(syscall.WaitStatus)
+	if !status.Signaled() {
+		lower := strings.ToLower(stderr)
+		// A sandboxed command is PID 1 of its namespace, which ignores
+		// SIGXFSZ; its writes fail with EFBIG instead.
+		if limits.FileSizeBytes > 0 && strings.Contains(lower, "file too large") {
+			return fmt.Sprintf("exceeded the output size limit of %d MB", limits.FileSizeBytes>>20)
+		}
+		if limits.MemoryBytes > 0 {
+			for _, failure := range allocationFailures {
+				if strings.Contains(lower, failure) {
+					return memory
+				}
+			}
+		}
+		return ""
+	}
+	// PID 1 also ignores SIGXCPU and is killed at the hard CPU limit, which
+	// equals the soft one; CPU time near the limit tells that SIGKILL apart.
+	cpu := exitErr.UserTime() + exitErr.SystemTime()
+	switch signal := status.Signal(); {
+	case limits.CPUSeconds > 0 && (signal == syscall.SIGXCPU || (signal == syscall.SIGKILL && cpu >= time.Duration(limits.CPUSeconds)*time.Second*9/10)):
+		return fmt.Sprintf("exceeded the CPU time limit of %ds", limits.CPUSeconds)
+	case limits.FileSizeBytes > 0 && signal == syscall.SIGXFSZ:
+		return fmt.Sprintf("exceeded the output size limit of %d MB", limits.FileSizeBytes>>20)
+	case limits.MemoryBytes > 0:
+		// Unchecked allocations crash (SIGSEGV), checked ones often abort
+		// (SIGABRT), and the OOM killer sends SIGKILL.
+		switch signal {
+		case syscall.SIGKILL, syscall.SIGABRT, syscall.SIGSEGV, syscall.SIGBUS:
+			return memory
+		}
+	}
+	return ""
+}
+
+// userProcesses counts the processes of the current real user, which
+// RLIMIT_NPROC is checked against.
+func userProcesses() uint64 {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0
+	}
+	uid := uint32(os.Getuid())
+	var n uint64
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue
+		}
+		info, err := os.Stat("/proc/" + e.Name())
+		if err != nil {
+			continue
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok && st.Uid == uid {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,42 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestExceeded(t *testing.T) {
+	memory := Limits{MemoryBytes: 512 << 20}
+	tests := []struct {
+		name   string
+		script string
+		limits Limits
+		stderr string
+		want   string
+	}{
+		{name: "crash under a memory limit", script: "kill -SEGV $$", limits: memory, want: "memory limit of 512 MB"},
+		{name: "abort under a memory limit", script: "kill -ABRT $$", limits: memory, want: "memory limit"},
+		{name: "crash without a memory limit", script: "kill -SEGV $$", limits: Limits{CPUSeconds: 10}},
+		{name: "allocation failure", script: "exit 1", limits: memory, stderr: "convert: Memory allocation failed `in.tif'", want: "memory limit"},
+		{name: "allocation failure without a limit", script: "exit 1", stderr: "Cannot allocate memory"},
+		{name: "unrelated failure", script: "exit 1", limits: memory, stderr: "no such file"},
+		{name: "cpu signal", script: "kill -XCPU $$", limits: Limits{CPUSeconds: 5}, want: "CPU time limit of 5s"},
+		{name: "file size signal", script: "kill -XFSZ $$", limits: Limits{FileSizeBytes: 1 << 20}, want: "output size limit of 1 MB"},
+		{name: "file size error", script: "exit 1", limits: Limits{FileSizeBytes: 1 << 20}, stderr: "write: File too large", want: "output size limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := exec.Command("/bin/sh", "-c", tt.script).Run()
+			if err == nil {
+				t.Fatal("command succeeded")
+			}
+			got := Exceeded(err, tt.limits, tt.stderr)
+			if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+				t.Errorf("Exceeded() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Package sandbox runs CLI skill commands with only the access their
+// permissions grant.
+//
+// On Linux a command runs in fresh user, mount, PID and (unless network is
+// granted) network namespaces. Its root is a tmpfs holding read-only system
+// directories, the input files and the writable output directories; the
+// rest of the host filesystem is not reachable. Other platforms run commands
+// directly.
+package sandbox
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// helperArg marks the re-executed app binary that sets up the sandbox and
+// then execs the command.
+const helperArg = "__asteria-sandbox"
+
+// Mode selects how strictly commands are sandboxed.
+type Mode string
+
+const (
+	// ModeAuto sandboxes when the platform supports it and runs commands
+	// directly otherwise.
+	ModeAuto Mode = "auto"
+	// ModeRequired refuses to run commands that cannot be sandboxed.
+	ModeRequired Mode = "required"
+	// ModeOff runs every command directly.
+	ModeOff Mode = "off"
+)
+
+func ParseMode(s string) Mode {
+	switch Mode(strings.ToLower(strings.TrimSpace(s))) {
+	case ModeRequired:
+		return ModeRequired
+	case ModeOff:
+		return ModeOff
+	default:
+		return ModeAuto
+	}
+}
+
+// Spec describes one sandboxed command.
+type Spec struct {
+	// Command is the absolute path of the executable.
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+	// Dir is the working directory inside the sandbox.
+	Dir string   `json:"dir,omitempty"`
+	Env []string `json:"env,omitempty"`
+	// ReadOnly and Writable are host paths exposed at the same location.
+	ReadOnly []string `json:"readOnly,omitempty"`
+	Writable []string `json:"writable,omitempty"`
+	// Network keeps the host network (the network permission).
+	Network bool `json:"network,omitempty"`
+	// HostFilesystem keeps the whole host filesystem visible (the
+	// files.anywhere permission).
+	HostFilesystem bool `json:"hostFilesystem,omitempty"`
+
+	Limits Limits `json:"limits,omitempty"`
+
+	// Root is the empty directory the sandbox root is mounted on and
+	// ProcessLimit the absolute RLIMIT_NPROC; both are set by Command.
+	Root         string `json:"root,omitempty"`
+	ProcessLimit uint64 `json:"processLimit,omitempty"`
+	// Direct is set by Direct: the helper only applies limits.
+	Direct bool `json:"direct,omitempty"`
+}
+
+// Limits are rlimits applied to a command; zero means unlimited.
+type Limits struct {
+	MemoryBytes   uint64 `json:"memoryBytes,omitempty"`
+	CPUSeconds    uint64 `json:"cpuSeconds,omitempty"`
+	FileSizeBytes uint64 `json:"fileSizeBytes,omitempty"`
+	// Processes is how many processes the command may add to those its user
+	// already runs (RLIMIT_NPROC counts per user, not per command).
+	Processes uint64 `json:"processes,omitempty"`
+}
+
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// Direct returns a command running spec.Command without isolation but with
+// spec.Limits in force from its first instruction: on Linux the helper sets
+// them and execs the command. Only Command, Args, Dir, Env and Limits are
+// used; limits are not enforced on other platforms.
+func Direct(ctx context.Context, spec Spec) (*exec.Cmd, error) {
+	if spec.Limits.IsZero() {
+		cmd := exec.CommandContext(ctx, spec.Command, spec.Args...)
+		cmd.Dir = spec.Dir
+		cmd.Env = spec.Env
+		return cmd, nil
+	}
+	return direct(ctx, spec)
+}
+
+// Command returns a command running spec in the sandbox. cleanup must be
+// called once the command has exited.
+func Command(ctx context.Context, spec Spec) (cmd *exec.Cmd, cleanup func(), err error) {
+	return command(ctx, spec)
+}
+
+var (
+	probeOnce sync.Once
+	probeErr  error
+)
+
+// Available reports whether commands can be sandboxed here. The result of
+// the first probe is cached.
+func Available() error {
+	probeOnce.Do(func() {
+		probeErr = probe()
+	})
+	return probeErr
+}
+
+// MaybeRunHelper runs the sandbox setup helper when the process was started
+// as one and never returns in that case. Call it first thing in main.
+func MaybeRunHelper() {
+	if len(os.Args) == 3 && os.Args[1] == helperArg {
+		os.Exit(runHelper(os.Args[2]))
+	}
+}
+
+// Env returns the scrubbed environment commands run with: a fixed PATH, a
+// throwaway HOME and the host's locale settings.
+func Env() []string {
+	env := []string{
+		"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		"HOME=/tmp",
+		"TMPDIR=/tmp",
+	}
+	for _, key := range []string{"LANG", "LC_ALL", "LC_CTYPE", "TZ"} {
+		if v, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+v)
+		}
+	}
+	return env
+}
+
+// hostEnvKeys are the host variables kept for commands that run directly:
+// what tools need to find system libraries and temp space, not credentials.
+var hostEnvKeys = []string{
+	"PATH", "LANG", "LC_ALL", "LC_CTYPE", "TZ", "TMPDIR", "TEMP", "TMP",
+	"SYSTEMROOT", "SYSTEMDRIVE", "WINDIR", "PATHEXT", "COMSPEC",
+}
+
+// DirectEnv returns the scrubbed environment for commands that run without
+// the sandbox: the host's PATH, locale and system variables, with HOME
+// pointed at the temp directory. Tokens and other secrets are dropped.
+func DirectEnv() []string {
+	env := []string{"HOME=" + os.TempDir()}
+	for _, key := range hostEnvKeys {
+		if v, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+v)
+		}
+	}
+	return env
+}
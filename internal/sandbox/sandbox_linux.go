@@ -0,0 +1,401 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// setupFailed is the helper's exit code when the sandbox could not be built;
+// the command itself never ran.
+const setupFailed = 125
+
+// systemPaths are exposed read-only so tools and their libraries load.
+var systemPaths = []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32", "/etc", "/opt", "/nix"}
+
+var devices = []string{"null", "zero", "full", "random", "urandom"}
+
+func command(ctx context.Context, spec Spec) (*exec.Cmd, func(), error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := os.MkdirTemp("", "asteria-sandbox-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { _ = os.Remove(root) }
+	spec.Root = root
+	spec.ProcessLimit = absoluteProcesses(spec.Limits)
+	if spec.Command != "" && !underSystemPath(spec.Command) {
+		spec.ReadOnly = append(spec.ReadOnly, filepath.Dir(spec.Command))
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, exe, helperArg, string(data))
+	cmd.Env = spec.Env
+	flags := syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if !spec.Network {
+		flags |= syscall.CLONE_NEWNET
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  uintptr(flags),
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		Pdeathsig:   syscall.SIGKILL,
+	}
+	return cmd, cleanup, nil
+}
+
+// direct runs spec through the helper without namespaces, so the limits are
+// set before the command execs rather than after it started.
+func direct(ctx context.Context, spec Spec) (*exec.Cmd, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if spec.Dir == "" {
+		if spec.Dir, err = os.Getwd(); err != nil {
+			return nil, err
+		}
+	}
+	spec.Direct = true
+	spec.HostFilesystem = true
+	spec.ProcessLimit = absoluteProcesses(spec.Limits)
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, exe, helperArg, string(data))
+	cmd.Env = spec.Env
+	return cmd, nil
+}
+
+// probe builds an empty sandbox to check that namespaces and mounts are
+// permitted (unprivileged user namespaces can be disabled).
+func probe() error {
+	cmd, cleanup, err := command(context.Background(), Spec{Env: Env()})
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return err
+	}
+	return nil
+}
+
+func runHelper(arg string) int {
+	var spec Spec
+	if err := json.Unmarshal([]byte(arg), &spec); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		return setupFailed
+	}
+	if !spec.HostFilesystem {
+		if err := buildRoot(spec); err != nil {
+			fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+			return setupFailed
+		}
+	}
+	if spec.Command == "" {
+		return 0
+	}
+	dir := spec.Dir
+	if dir == "" {
+		dir = "/tmp"
+	}
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		return setupFailed
+	}
+	if err := setLimits(spec.Limits, spec.ProcessLimit); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: set limits: %v\n", err)
+		return setupFailed
+	}
+	// Capabilities and no_new_privs are per thread: drop them on the thread
+	// that execs. Direct commands run as the user and keep what they have.
+	runtime.LockOSThread()
+	if !spec.Direct {
+		if err := dropPrivileges(); err != nil {
+			fmt.Fprintf(os.Stderr, "sandbox: drop privileges: %v\n", err)
+			return setupFailed
+		}
+	}
+	argv := append([]string{spec.Command}, spec.Args...)
+	err := syscall.Exec(spec.Command, argv, spec.Env)
+	fmt.Fprintf(os.Stderr, "sandbox: exec %s: %v\n", spec.Command, err)
+	return 126
+}
+
+// prctl options and securebits used by dropPrivileges.
+const (
+	prCapbsetDrop       = 24
+	prSetSecurebits     = 28
+	prSetNoNewPrivs     = 38
+	prCapAmbient        = 47
+	prCapAmbientClear   = 4
+	linuxCapabilityV3   = 0x20080522
+	maxCapability       = 63
+	securebitsLockedAll = 0x3f // NOROOT, NO_SETUID_FIXUP, KEEP_CAPS and their locks
+	securebitsAmbient   = 0xc0 // NO_CAP_AMBIENT_RAISE and its lock
+)
+
+// dropPrivileges leaves the helper, root in its user namespace, without
+// capabilities before it execs the command: securebits stop exec from
+// granting root's capabilities back, the bounding and ambient sets are
+// emptied, the current sets are cleared, and no_new_privs blocks setuid and
+// file capabilities.
+func dropPrivileges() error {
+	if err := prctl(prSetSecurebits, securebitsLockedAll|securebitsAmbient); err != nil {
+		// Kernels before 4.3 have no ambient capabilities.
+		if err := prctl(prSetSecurebits, securebitsLockedAll); err != nil {
+			return fmt.Errorf("set securebits: %w", err)
+		}
+	}
+	for c := uintptr(0); c <= maxCapability; c++ {
+		if err := prctl(prCapbsetDrop, c); err != nil {
+			if err == syscall.EINVAL { // past the last capability
+				break
+			}
+			return fmt.Errorf("drop capability %d: %w", c, err)
+		}
+	}
+	if err := prctl(prCapAmbient, prCapAmbientClear); err != nil && err != syscall.EINVAL {
+		return fmt.Errorf("clear ambient capabilities: %w", err)
+	}
+	header := struct {
+		version uint32
+		pid     int32
+	}{version: linuxCapabilityV3}
+	var data [2]struct{ effective, permitted, inheritable uint32 }
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return fmt.Errorf("clear capabilities: %w", errno)
+	}
+	if err := prctl(prSetNoNewPrivs, 1); err != nil {
+		return fmt.Errorf("set no_new_privs: %w", err)
+	}
+	return nil
+}
+
+func prctl(option uintptr, arg uintptr) error {
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, option, arg, 0, 0, 0, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// buildRoot mounts a tmpfs root with the exposed paths and pivots into it.
+func buildRoot(spec Spec) error {
+	root := spec.Root
+	if root == "" {
+		return fmt.Errorf("missing root")
+	}
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+	if err := syscall.Mount("tmpfs", root, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+
+	for _, p := range systemPaths {
+		if err := exposeSystemPath(root, p); err != nil {
+			return err
+		}
+	}
+	if err := mountDev(root); err != nil {
+		return err
+	}
+	tmp := filepath.Join(root, "tmp")
+	if err := os.MkdirAll(tmp, 0o1777); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", tmp, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+	proc := filepath.Join(root, "proc")
+	if err := os.MkdirAll(proc, 0o555); err != nil {
+		return err
+	}
+	if err := syscall.Mount("proc", proc, "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount /proc: %w", err)
+	}
+
+	// Exposed paths go last so they are not hidden by /tmp or /dev.
+	for _, p := range spec.ReadOnly {
+		if err := bindPath(root, p, true); err != nil {
+			return err
+		}
+	}
+	for _, p := range spec.Writable {
+		if err := bindPath(root, p, false); err != nil {
+			return err
+		}
+	}
+
+	oldRoot := filepath.Join(root, ".oldroot")
+	if err := os.MkdirAll(oldRoot, 0o700); err != nil {
+		return err
+	}
+	if err := syscall.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("pivot root: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	if err := syscall.Unmount("/.oldroot", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach host root: %w", err)
+	}
+	_ = os.Remove("/.oldroot")
+	// Make the root itself read-only; /tmp and the writable binds stay
+	// writable.
+	if err := syscall.Mount("", "/", "", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("make root read-only: %w", err)
+	}
+	return nil
+}
+
+// exposeSystemPath binds a system directory read-only, recreating it as a
+// symlink when the host has one (merged /usr layouts link /bin to usr/bin).
+func exposeSystemPath(root string, p string) error {
+	info, err := os.Lstat(p)
+	if err != nil {
+		return nil
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(p)
+		if err != nil {
+			return nil
+		}
+		if !filepath.IsAbs(target) {
+			return os.Symlink(target, filepath.Join(root, p))
+		}
+	}
+	return bindPath(root, p, true)
+}
+
+// bindPath bind-mounts host path p at the same location under root.
+func bindPath(root string, p string, readOnly bool) error {
+	if !filepath.IsAbs(p) {
+		return fmt.Errorf("path %q is not absolute", p)
+	}
+	src, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return fmt.Errorf("expose %s: %w", p, err)
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("expose %s: %w", p, err)
+	}
+	target := filepath.Join(root, filepath.Clean(p))
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0o755)
+	} else {
+		err = touch(target)
+	}
+	if err != nil {
+		return fmt.Errorf("expose %s: %w", p, err)
+	}
+	if err := syscall.Mount(src, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("expose %s: %w", p, err)
+	}
+	if !readOnly {
+		return nil
+	}
+	// A read-only remount must keep the flags the kernel locked on the
+	// original mount, or it fails inside a user namespace.
+	flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY)
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(src, &st); err == nil {
+		flags |= lockedFlags(st.Flags)
+	}
+	if err := syscall.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("expose %s read-only: %w", p, err)
+	}
+	return nil
+}
+
+// lockedFlags maps statfs ST_* flags to the MS_* flags a remount must keep.
+func lockedFlags(st int64) uintptr {
+	const (
+		stNoSuid     = 0x2
+		stNoDev      = 0x4
+		stNoExec     = 0x8
+		stNoAtime    = 0x400
+		stNoDirAtime = 0x800
+		stRelAtime   = 0x1000
+	)
+	var flags uintptr
+	for _, m := range []struct {
+		st int64
+		ms uintptr
+	}{
+		{stNoSuid, syscall.MS_NOSUID},
+		{stNoDev, syscall.MS_NODEV},
+		{stNoExec, syscall.MS_NOEXEC},
+		{stNoAtime, syscall.MS_NOATIME},
+		{stNoDirAtime, syscall.MS_NODIRATIME},
+		{stRelAtime, syscall.MS_RELATIME},
+	} {
+		if st&m.st != 0 {
+			flags |= m.ms
+		}
+	}
+	return flags
+}
+
+func mountDev(root string) error {
+	dev := filepath.Join(root, "dev")
+	if err := os.MkdirAll(dev, 0o755); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", dev, "tmpfs", syscall.MS_NOSUID|syscall.MS_NOEXEC, "mode=0755"); err != nil {
+		return fmt.Errorf("mount /dev: %w", err)
+	}
+	for _, name := range devices {
+		target := filepath.Join(dev, name)
+		if err := touch(target); err != nil {
+			return err
+		}
+		if err := syscall.Mount("/dev/"+name, target, "", syscall.MS_BIND, ""); err != nil {
+			return fmt.Errorf("expose /dev/%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func touch(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func underSystemPath(p string) bool {
+	for _, sys := range systemPaths {
+		if p == sys || strings.HasPrefix(p, sys+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary stand in for the sandbox helper and for the
+// commands isolation is tested with.
+func TestMain(m *testing.M) {
+	MaybeRunHelper()
+	if len(os.Args) == 3 {
+		switch os.Args[1] {
+		case "write":
+			if err := os.WriteFile(os.Args[2], []byte("x"), 0o644); err != nil {
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "dial":
+			conn, err := net.DialTimeout("tcp", os.Args[2], 2*time.Second)
+			if err != nil {
+				os.Exit(1)
+			}
+			conn.Close()
+			os.Exit(0)
+		}
+	}
+	os.Exit(m.Run())
+}
+
+func TestCommandIsolation(t *testing.T) {
+	if err := Available(); err != nil {
+		t.Skipf("sandbox unavailable: %v", err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	outside := t.TempDir()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		name    string
+		args    []string
+		network bool
+		wantOK  bool
+	}{
+		{"write output dir", []string{"write", filepath.Join(out, "ok")}, false, true},
+		{"write outside output dir", []string{"write", filepath.Join(outside, "escaped")}, false, false},
+		{"write root", []string{"write", "/escaped"}, false, false},
+		{"write next to the command", []string{"write", filepath.Join(filepath.Dir(exe), "escaped")}, false, false},
+		{"dial without network", []string{"dial", ln.Addr().String()}, false, false},
+		{"dial with network", []string{"dial", ln.Addr().String()}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, cleanup, err := Command(context.Background(), Spec{
+				Command:  exe,
+				Args:     tt.args,
+				Env:      Env(),
+				Writable: []string{out},
+				Network:  tt.network,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cleanup()
+			output, err := cmd.CombinedOutput()
+			if ok := err == nil; ok != tt.wantOK {
+				t.Errorf("%v: ok = %v, want %v (err %v, output %q)", tt.args, ok, tt.wantOK, err, output)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(outside, "escaped")); err == nil {
+		t.Error("write outside the output dir reached the host")
+	}
+}
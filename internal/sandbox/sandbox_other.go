@@ -0,0 +1,35 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+var errUnsupported = errors.New("sandboxing is only supported on Linux")
+
+func command(ctx context.Context, spec Spec) (*exec.Cmd, func(), error) {
+	return nil, nil, errUnsupported
+}
+
+func probe() error {
+	return errUnsupported
+}
+
+func runHelper(arg string) int {
+	return 1
+}
+
+func direct(ctx context.Context, spec Spec) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, spec.Command, spec.Args...)
+	cmd.Dir = spec.Dir
+	cmd.Env = spec.Env
+	return cmd, nil
+}
+
+// Exceeded reports nothing: limits are not enforced on this platform.
+func Exceeded(err error, limits Limits, stderr string) string {
+	return ""
+}
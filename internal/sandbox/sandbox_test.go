@@ -0,0 +1,54 @@
+package sandbox
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Mode
+	}{
+		{"", ModeAuto},
+		{"auto", ModeAuto},
+		{"required", ModeRequired},
+		{" Required ", ModeRequired},
+		{"off", ModeOff},
+		{"OFF", ModeOff},
+		{"disabled", ModeAuto},
+	}
+	for _, tt := range tests {
+		if got := ParseMode(tt.in); got != tt.want {
+			t.Errorf("ParseMode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDirectEnv(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin:/bin")
+	t.Setenv("TZ", "Europe/Berlin")
+	t.Setenv("GITHUB_TOKEN", "secret")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("HOME", "/home/someone")
+
+	env := map[string]string{}
+	for _, kv := range DirectEnv() {
+		k, v, _ := strings.Cut(kv, "=")
+		if _, dup := env[k]; dup {
+			t.Errorf("%s is set twice", k)
+		}
+		env[k] = v
+	}
+	for k, want := range map[string]string{"PATH": "/usr/bin:/bin", "TZ": "Europe/Berlin", "HOME": os.TempDir()} {
+		if env[k] != want {
+			t.Errorf("%s = %q, want %q", k, env[k], want)
+		}
+	}
+	for _, k := range []string{"GITHUB_TOKEN", "AWS_SECRET_ACCESS_KEY"} {
+		if _, ok := env[k]; ok {
+			t.Errorf("%s leaked into the environment", k)
+		}
+	}
+}
@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
+	"asteria/internal/filetype"
+	"asteria/internal/metadata"
+
 	"github.com/google/uuid"
 )
 
@@ -16,6 +20,8 @@ type FileState struct {
 	data      WorkingFile
 	basePath  string
 	snapshots []string
+	// revision counts changes of the current content.
+	revision int
 }
 
 type State struct {
@@ -79,13 +85,23 @@ func (s *State) AddFile(path string) (WorkingFile, error) {
 	}
 	id := uuid.NewString()
 	ext := strings.ToLower(filepath.Ext(path))
-	name := strings.TrimSuffix(filepath.Base(path), ext)
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	// Skills match on the current extension, so it follows the content when
+	// the name is wrong or missing.
+	currentExt := ext
+	mimeType := ""
+	if t, ok := filetype.IdentifyFile(path, ext); ok {
+		mimeType = t.MIME
+		if !t.HasExtension(ext) {
+			currentExt = t.Extension()
+		}
+	}
 	fileDir, err := s.workspace.EnsureFileDir(id)
 	if err != nil {
 		return WorkingFile{}, err
 	}
-	basePath := filepath.Join(fileDir, "base"+ext)
-	currentPath := filepath.Join(fileDir, "current"+ext)
+	basePath := filepath.Join(fileDir, "base"+currentExt)
+	currentPath := filepath.Join(fileDir, "current"+currentExt)
 	if err := CopyFile(path, basePath); err != nil {
 		return WorkingFile{}, err
 	}
@@ -96,7 +112,8 @@ func (s *State) AddFile(path string) (WorkingFile, error) {
 		ID:               id,
 		Name:             name,
 		Extension:        ext,
-		CurrentExtension: ext,
+		CurrentExtension: currentExt,
+		MimeType:         mimeType,
 		OriginalPath:     path,
 		WorkingPath:      currentPath,
 		Size:             info.Size(),
@@ -214,11 +231,59 @@ func (f *FileState) CurrentPath() string {
 }
 
 func (f *FileState) SetCurrentPath(path string, ext string, size int64) {
+	mimeType := ""
+	if t, ok := filetype.IdentifyFile(path, ext); ok {
+		mimeType = t.MIME
+	}
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	f.data.WorkingPath = path
 	f.data.CurrentExtension = ext
+	f.data.MimeType = mimeType
 	f.data.Size = size
+	f.revision++
+}
+
+// Revision identifies the current content; it changes with every
+// SetCurrentPath.
+func (f *FileState) Revision() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.revision
+}
+
+// SetMetadataAt records md like SetMetadata if the content is still at
+// revision, and reports whether it was.
+func (f *FileState) SetMetadataAt(revision int, md metadata.Metadata) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.revision != revision {
+		return false
+	}
+	f.setMetadata(md)
+	return true
+}
+
+// SetMetadata records what is known about the current content.
+func (f *FileState) SetMetadata(md metadata.Metadata) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setMetadata(md)
+}
+
+func (f *FileState) setMetadata(md metadata.Metadata) {
+	if md.IsZero() {
+		f.data.Metadata = nil
+		return
+	}
+	f.data.Metadata = &md
+}
+
+// SetOutputs records the additional outputs of the last skill.
+func (f *FileState) SetOutputs(paths []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data.Outputs = paths
 }
 
 func (f *FileState) SetPreview(preview string) {
@@ -312,17 +377,54 @@ func NewAppliedSkill(skillID string, params map[string]any) AppliedSkill {
 	}
 }
 
-func ExportName(pattern string, name string, ext string, skill string) string {
+// ExportName fills pattern's {name}, {ext} and {skill} and any metadata
+// value such as {width}, {date} or {camera}; placeholders without a value
+// are left empty.
+func ExportName(pattern string, name string, ext string, skill string, values map[string]string) string {
 	if strings.TrimSpace(pattern) == "" {
 		pattern = "{name}_{skill}.{ext}"
 	}
 	sanitizedSkill := strings.ReplaceAll(skill, " ", "_")
 	sanitizedSkill = strings.ReplaceAll(sanitizedSkill, "-", "_")
-	out := strings.ReplaceAll(pattern, "{name}", name)
-	out = strings.ReplaceAll(out, "{ext}", strings.TrimPrefix(ext, "."))
-	out = strings.ReplaceAll(out, "{skill}", sanitizedSkill)
+	sanitizedSkill = strings.ReplaceAll(sanitizedSkill, "/", "_")
+	// One pass over the pattern, so a file name or metadata value containing
+	// "{...}" is never expanded again.
+	out := nameToken.ReplaceAllStringFunc(pattern, func(token string) string {
+		switch key := token[1 : len(token)-1]; key {
+		case "name":
+			return name
+		case "ext":
+			return strings.TrimPrefix(ext, ".")
+		case "skill":
+			return sanitizedSkill
+		default:
+			return sanitizeNamePart(values[key])
+		}
+	})
 	if !strings.Contains(out, ".") {
 		out = fmt.Sprintf("%s.%s", out, strings.TrimPrefix(ext, "."))
 	}
 	return out
 }
+
+var nameToken = regexp.MustCompile(`\{[A-Za-z][A-Za-z0-9]*\}`)
+
+// sanitizeNamePart keeps a metadata value from adding folders or characters
+// file systems reject. Values made only of dots would name the current or
+// parent folder and are dropped.
+func sanitizeNamePart(value string) string {
+	out := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '-'
+		}
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(value))
+	if strings.Trim(out, ".") == "" {
+		return ""
+	}
+	return out
+}
@@ -0,0 +1,30 @@
+package session
+
+import "testing"
+
+func TestExportName(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		values  map[string]string
+		want    string
+	}{
+		{"default pattern", "", nil, "photo_convert_jpeg.jpg"},
+		{"metadata value", "{camera}_{name}.{ext}", map[string]string{"camera": "Canon EOS R5"}, "Canon EOS R5_photo.jpg"},
+		{"missing value", "{camera}{name}.{ext}", nil, "photo.jpg"},
+		{"separators replaced", "{camera}/{name}.{ext}", map[string]string{"camera": "a/../b\\c"}, "a-..-b-c/photo.jpg"},
+		{"parent folder dropped", "{camera}/{name}.{ext}", map[string]string{"camera": ".."}, "/photo.jpg"},
+		{"current folder dropped", "{camera}/{name}.{ext}", map[string]string{"camera": " . "}, "/photo.jpg"},
+		{"dots only dropped", "{a}/{b}/{name}.{ext}", map[string]string{"a": "...", "b": ".."}, "//photo.jpg"},
+		{"dots inside kept", "{camera}_{name}.{ext}", map[string]string{"camera": "v1..2"}, "v1..2_photo.jpg"},
+		{"value not expanded again", "{camera}_{name}.{ext}", map[string]string{"camera": "{name}"}, "{name}_photo.jpg"},
+		{"extension added", "{name}", nil, "photo.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExportName(tt.pattern, "photo", ".jpg", "convert-jpeg", tt.values); got != tt.want {
+				t.Errorf("ExportName(%q) = %q, want %q", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package session
 
+import "asteria/internal/metadata"
+
 type Mode string
 
 const (
@@ -23,6 +25,13 @@ type WorkingFile struct {
 	Size             int64          `json:"size"`
 	PreviewDataURL   string         `json:"previewDataUrl"`
 	AppliedSkills    []AppliedSkill `json:"appliedSkills"`
+	// Outputs are additional files the last skill produced (frames, pages,
+	// segments); WorkingPath holds the first.
+	Outputs []string `json:"outputs,omitempty"`
+	// MimeType is detected from the content; empty when it isn't recognized.
+	MimeType string `json:"mimeType,omitempty"`
+	// Metadata describes the current content; nil until it has been read.
+	Metadata *metadata.Metadata `json:"metadata,omitempty"`
 }
 
 type SessionSnapshot struct {
@@ -35,4 +44,6 @@ type SessionSnapshot struct {
 type ExportResult struct {
 	FileID     string `json:"fileId"`
 	OutputPath string `json:"outputPath"`
+	// ExtraOutputs are the exported additional outputs, if any.
+	ExtraOutputs []string `json:"extraOutputs,omitempty"`
 }
@@ -0,0 +1,449 @@
+package skills
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Argument templates
+//
+// Each element of executor.args is a template. Placeholders are written
+// {{name}}, {{name:type}} or {{name|default}} (both: {{name:type|default}}):
+//
+//   - name is a declared param or a builtin: {{input}}, {{output}} and
+//     {{outputDir}}, where a skill with executor.outputs writes its files.
+//   - type is int, float, string or bool, or a printf verb such as %.2f or
+//     %03d; it defaults to the param's declared type.
+//   - default is used when the param is unset or empty.
+//
+// An element wrapped in brackets, "[-quality {{quality}}]", is an optional
+// group: its text is split on spaces into several args (before substitution,
+// so values are never split), and the whole group is dropped when any param
+// in it is empty, unset or false.
+//
+// A bool placeholder is a condition rather than text: "[-strip {{strip}}]"
+// renders "-strip" when strip is true and nothing otherwise. An arg holding
+// a false bool outside a group is dropped.
+//
+// Values are validated against the ParamDef (type, min/max, options), and a
+// param value that starts an arg may not begin with "-", so it can't be read
+// as an option. Numbers are exempt.
+
+// ArgTemplate is a parsed executor.args list.
+type ArgTemplate struct {
+	groups []argGroup
+}
+
+type argGroup struct {
+	optional bool
+	args     [][]argPart
+}
+
+type argPart struct {
+	literal     string
+	placeholder *placeholder
+}
+
+type placeholder struct {
+	name   string
+	typ    string
+	format string
+	def    string
+	hasDef bool
+}
+
+// builtinArgs are the placeholders every CLI skill can use.
+var builtinArgs = map[string]bool{"input": true, "output": true, "outputDir": true}
+
+// ParseArgs parses args and checks every placeholder names a builtin or a
+// declared param with a compatible type.
+func ParseArgs(args []string, params []ParamDef) (ArgTemplate, error) {
+	defs := paramDefs(params)
+	var t ArgTemplate
+	for _, raw := range args {
+		group := argGroup{}
+		elems := []string{raw}
+		if strings.HasPrefix(raw, "[") && strings.HasSuffix(raw, "]") && len(raw) >= 2 {
+			group.optional = true
+			elems = splitGroup(raw[1 : len(raw)-1])
+			if len(elems) == 0 {
+				return ArgTemplate{}, fmt.Errorf("args: empty optional group %q", raw)
+			}
+		}
+		hasParam := false
+		for _, elem := range elems {
+			parts, err := parseArg(elem)
+			if err != nil {
+				return ArgTemplate{}, err
+			}
+			for _, p := range parts {
+				if p.placeholder == nil {
+					continue
+				}
+				if err := p.placeholder.check(defs); err != nil {
+					return ArgTemplate{}, err
+				}
+				if !builtinArgs[p.placeholder.name] {
+					hasParam = true
+				}
+			}
+			group.args = append(group.args, parts)
+		}
+		if group.optional && !hasParam {
+			return ArgTemplate{}, fmt.Errorf("args: optional group %q references no param", raw)
+		}
+		t.groups = append(t.groups, group)
+	}
+	return t, nil
+}
+
+// Render substitutes builtins and params. Params missing from params fall
+// back to the placeholder default, then the ParamDef default.
+func (t ArgTemplate) Render(builtins map[string]string, params map[string]any, defs []ParamDef) ([]string, error) {
+	byName := paramDefs(defs)
+	out := make([]string, 0, len(t.groups))
+	for _, group := range t.groups {
+		rendered := make([]string, 0, len(group.args))
+		skip := false
+		for _, parts := range group.args {
+			var b strings.Builder
+			conditionOnly, drop := true, false
+			for _, p := range parts {
+				if p.placeholder == nil {
+					b.WriteString(p.literal)
+					conditionOnly = false
+					continue
+				}
+				ph := p.placeholder
+				if builtinArgs[ph.name] {
+					b.WriteString(builtins[ph.name])
+					conditionOnly = false
+					continue
+				}
+				def := byName[ph.name]
+				condition := ph.valueType(def) == "bool" && ph.format == ""
+				value, numeric, empty, err := ph.render(params, def)
+				if err != nil {
+					return nil, err
+				}
+				if empty {
+					if group.optional {
+						skip = true
+						break
+					}
+					if condition {
+						drop = true
+						continue
+					}
+					return nil, fmt.Errorf("param %s has no value", ph.name)
+				}
+				if condition {
+					continue
+				}
+				conditionOnly = false
+				// Only what starts the argument can be read as an option;
+				// conditions before it write nothing.
+				if b.Len() == 0 && !numeric && strings.HasPrefix(value, "-") {
+					return nil, fmt.Errorf("param %s: value %q would be read as an option", ph.name, value)
+				}
+				b.WriteString(value)
+			}
+			if skip {
+				break
+			}
+			if drop || (conditionOnly && b.Len() == 0) {
+				continue
+			}
+			rendered = append(rendered, b.String())
+		}
+		if !skip {
+			out = append(out, rendered...)
+		}
+	}
+	return out, nil
+}
+
+// RenderValue renders a single template, such as an env value, without
+// optional groups. A false bool condition renders "".
+func RenderValue(tmpl string, builtins map[string]string, params map[string]any, defs []ParamDef) (string, error) {
+	if strings.HasPrefix(tmpl, "[") && strings.HasSuffix(tmpl, "]") {
+		return "", fmt.Errorf("args: optional groups are not allowed in %q", tmpl)
+	}
+	t, err := ParseArgs([]string{tmpl}, defs)
+	if err != nil {
+		return "", err
+	}
+	out, err := t.Render(builtins, params, defs)
+	if err != nil || len(out) == 0 {
+		return "", err
+	}
+	return out[0], nil
+}
+
+func splitGroup(s string) []string {
+	// Split on spaces outside placeholders, so "{{a | b}}" stays whole.
+	var out []string
+	var cur strings.Builder
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"):
+			depth++
+			cur.WriteString("{{")
+			i++
+		case strings.HasPrefix(s[i:], "}}") && depth > 0:
+			depth--
+			cur.WriteString("}}")
+			i++
+		case s[i] == ' ' && depth == 0:
+			if cur.Len() > 0 {
+				out = append(out, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteByte(s[i])
+		}
+	}
+	if cur.Len() > 0 {
+		out = append(out, cur.String())
+	}
+	return out
+}
+
+func parseArg(s string) ([]argPart, error) {
+	var parts []argPart
+	for s != "" {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			parts = append(parts, argPart{literal: s})
+			break
+		}
+		if start > 0 {
+			parts = append(parts, argPart{literal: s[:start]})
+		}
+		end := strings.Index(s[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("args: unclosed placeholder in %q", s)
+		}
+		ph, err := parsePlaceholder(s[start+2 : start+end])
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, argPart{placeholder: ph})
+		s = s[start+end+2:]
+	}
+	return parts, nil
+}
+
+func parsePlaceholder(expr string) (*placeholder, error) {
+	ph := &placeholder{}
+	if i := strings.Index(expr, "|"); i >= 0 {
+		ph.def, ph.hasDef = strings.TrimSpace(expr[i+1:]), true
+		expr = expr[:i]
+	}
+	if i := strings.Index(expr, ":"); i >= 0 {
+		spec := strings.TrimSpace(expr[i+1:])
+		expr = expr[:i]
+		if strings.HasPrefix(spec, "%") {
+			if strings.Count(strings.ReplaceAll(spec, "%%", ""), "%") != 1 {
+				return nil, fmt.Errorf("args: format %q must have exactly one verb", spec)
+			}
+			ph.format = spec
+			switch spec[len(spec)-1] {
+			case 'd', 'x', 'X', 'o':
+				ph.typ = "int"
+			case 'f', 'F', 'g', 'G', 'e', 'E':
+				ph.typ = "float"
+			case 's', 'q':
+				ph.typ = "string"
+			default:
+				return nil, fmt.Errorf("args: unsupported format %q", spec)
+			}
+		} else {
+			ph.typ = strings.ToLower(spec)
+		}
+	}
+	ph.name = strings.TrimSpace(expr)
+	if ph.name == "" {
+		return nil, fmt.Errorf("args: placeholder without a name")
+	}
+	return ph, nil
+}
+
+func (ph *placeholder) check(defs map[string]ParamDef) error {
+	if builtinArgs[ph.name] {
+		if ph.typ != "" || ph.hasDef {
+			return fmt.Errorf("args: {{%s}} takes no type or default", ph.name)
+		}
+		return nil
+	}
+	def, ok := defs[ph.name]
+	if !ok {
+		return fmt.Errorf("args: {{%s}} is not a declared param", ph.name)
+	}
+	typ := ph.valueType(def)
+	switch typ {
+	case "int", "float", "string", "bool":
+	default:
+		return fmt.Errorf("args: {{%s}} has unsupported type %q", ph.name, typ)
+	}
+	if ph.hasDef && ph.def != "" {
+		if _, err := convertParam(ph.def, typ, def); err != nil {
+			return fmt.Errorf("args: default of {{%s}}: %w", ph.name, err)
+		}
+	}
+	return nil
+}
+
+// valueType is the placeholder's explicit type or the param's declared one;
+// undeclared and unknown param types (select, color, ...) are strings.
+func (ph *placeholder) valueType(def ParamDef) string {
+	if ph.typ != "" {
+		return ph.typ
+	}
+	switch t := strings.ToLower(def.Type); t {
+	case "int", "float", "bool":
+		return t
+	case "integer":
+		return "int"
+	case "number":
+		return "float"
+	case "boolean":
+		return "bool"
+	}
+	return "string"
+}
+
+// render returns the formatted value, whether it is a number, and whether it
+// is empty (unset, "" or false).
+func (ph *placeholder) render(params map[string]any, def ParamDef) (string, bool, bool, error) {
+	typ := ph.valueType(def)
+	v, ok := params[ph.name]
+	if !ok || v == nil || v == "" {
+		switch {
+		case ph.hasDef:
+			v = ph.def
+		case def.Default != nil:
+			v = def.Default
+		default:
+			return "", false, true, nil
+		}
+		if v == "" {
+			return "", false, true, nil
+		}
+	}
+	value, err := convertParam(v, typ, def)
+	if err != nil {
+		return "", false, false, fmt.Errorf("param %s: %w", ph.name, err)
+	}
+	if b, isBool := value.(bool); isBool && !b {
+		return "", false, true, nil
+	}
+	if ph.format != "" {
+		return fmt.Sprintf(ph.format, value), typ != "string", false, nil
+	}
+	switch x := value.(type) {
+	case int64:
+		return strconv.FormatInt(x, 10), true, false, nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), true, false, nil
+	case bool:
+		return "", false, false, nil
+	default:
+		return fmt.Sprint(x), false, false, nil
+	}
+}
+
+// convertParam coerces v to typ (returning int64, float64, bool or string)
+// and checks it against def's min, max and options.
+func convertParam(v any, typ string, def ParamDef) (any, error) {
+	switch typ {
+	case "int", "float":
+		f, err := toFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%v is not a finite number", v)
+		}
+		if def.Min != nil && f < *def.Min {
+			return nil, fmt.Errorf("%v is below the minimum %v", f, *def.Min)
+		}
+		if def.Max != nil && f > *def.Max {
+			return nil, fmt.Errorf("%v is above the maximum %v", f, *def.Max)
+		}
+		if typ == "float" {
+			return f, nil
+		}
+		if f != math.Trunc(f) {
+			return nil, fmt.Errorf("%v is not a whole number", f)
+		}
+		return int64(f), nil
+	case "bool":
+		switch x := v.(type) {
+		case bool:
+			return x, nil
+		case string:
+			b, err := strconv.ParseBool(x)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not true or false", x)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("%v is not true or false", v)
+	default:
+		s, ok := v.(string)
+		if !ok {
+			switch v.(type) {
+			case map[string]any, []any:
+				return nil, fmt.Errorf("expected a text value")
+			}
+			s = fmt.Sprint(v)
+		}
+		if strings.ContainsRune(s, 0) {
+			return nil, fmt.Errorf("value contains a NUL byte")
+		}
+		if len(def.Options) > 0 {
+			for _, o := range def.Options {
+				if o == s {
+					return s, nil
+				}
+			}
+			return nil, fmt.Errorf("%q is not one of %s", s, strings.Join(def.Options, ", "))
+		}
+		return s, nil
+	}
+}
+
+func toFloat(v any) (float64, error) {
+	switch x := v.(type) {
+	case float64:
+		return x, nil
+	case float32:
+		return float64(x), nil
+	case int:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case json.Number:
+		return x.Float64()
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", x)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
+
+func paramDefs(params []ParamDef) map[string]ParamDef {
+	out := make(map[string]ParamDef, len(params))
+	for _, p := range params {
+		out[p.Name] = p
+	}
+	return out
+}
@@ -0,0 +1,170 @@
+package skills
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestArgTemplate(t *testing.T) {
+	hundred := 100.0
+	params := []ParamDef{
+		{Name: "quality", Type: "int", Default: 85.0, Max: &hundred},
+		{Name: "strip", Type: "bool"},
+		{Name: "sigma", Type: "float"},
+		{Name: "label", Type: "string"},
+		{Name: "offset", Type: "int"},
+		{Name: "mode", Type: "select", Options: []string{"fast", "slow"}},
+	}
+	builtins := map[string]string{"input": "in.png", "output": "out.png", "outputDir": "out"}
+
+	tests := []struct {
+		name    string
+		args    []string
+		values  map[string]any
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "builtins",
+			args: []string{"{{input}}", "-o", "{{output}}"},
+			want: []string{"in.png", "-o", "out.png"},
+		},
+		{
+			name: "param default",
+			args: []string{"-quality", "{{quality}}"},
+			want: []string{"-quality", "85"},
+		},
+		{
+			name:   "param value in text",
+			args:   []string{"--quality={{quality}}"},
+			values: map[string]any{"quality": 70.0},
+			want:   []string{"--quality=70"},
+		},
+		{
+			name:   "optional group kept",
+			args:   []string{"[-label {{label}}]", "{{input}}"},
+			values: map[string]any{"label": "Hello world"},
+			want:   []string{"-label", "Hello world", "in.png"},
+		},
+		{
+			name: "optional group dropped",
+			args: []string{"[-label {{label}}]", "{{input}}"},
+			want: []string{"in.png"},
+		},
+		{
+			name:   "bool condition true",
+			args:   []string{"[-strip {{strip}}]", "{{input}}"},
+			values: map[string]any{"strip": true},
+			want:   []string{"-strip", "in.png"},
+		},
+		{
+			name:   "bool condition false",
+			args:   []string{"[-strip {{strip}}]", "{{input}}"},
+			values: map[string]any{"strip": false},
+			want:   []string{"in.png"},
+		},
+		{
+			name:   "bool condition outside a group",
+			args:   []string{"{{strip}}-strip", "{{input}}"},
+			values: map[string]any{"strip": "false"},
+			want:   []string{"in.png"},
+		},
+		{
+			name: "format with placeholder default",
+			args: []string{"-blur", "0x{{sigma:%.1f|1}}"},
+			want: []string{"-blur", "0x1.0"},
+		},
+		{
+			name:   "format with value",
+			args:   []string{"{{sigma:%.2f}}"},
+			values: map[string]any{"sigma": "2.345"},
+			want:   []string{"2.35"},
+		},
+		{
+			name:   "negative number allowed",
+			args:   []string{"-offset", "{{offset}}"},
+			values: map[string]any{"offset": -5.0},
+			want:   []string{"-offset", "-5"},
+		},
+		{
+			name:    "option-like value rejected",
+			args:    []string{"-label", "{{label}}"},
+			values:  map[string]any{"label": "--delete"},
+			wantErr: true,
+		},
+		{
+			name:    "option-like value after a condition rejected",
+			args:    []string{"{{strip}}{{label}}"},
+			values:  map[string]any{"strip": true, "label": "-rf"},
+			wantErr: true,
+		},
+		{
+			name:   "option-like value inside text allowed",
+			args:   []string{"label:{{label}}"},
+			values: map[string]any{"label": "-rf"},
+			want:   []string{"label:-rf"},
+		},
+		{
+			name:    "missing param",
+			args:    []string{"-label", "{{label}}"},
+			wantErr: true,
+		},
+		{
+			name:    "above maximum",
+			args:    []string{"{{quality}}"},
+			values:  map[string]any{"quality": 120.0},
+			wantErr: true,
+		},
+		{
+			name:    "not an option",
+			args:    []string{"{{mode}}"},
+			values:  map[string]any{"mode": "medium"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseArgs(tt.args, params)
+			if err != nil {
+				t.Fatalf("ParseArgs(%q): %v", tt.args, err)
+			}
+			got, err := tmpl.Render(builtins, tt.values, params)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Render() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render(): %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	params := []ParamDef{{Name: "width", Type: "int"}}
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "undeclared param", args: []string{"{{height}}"}},
+		{name: "unclosed placeholder", args: []string{"{{width"}},
+		{name: "empty placeholder", args: []string{"{{}}"}},
+		{name: "builtin with a type", args: []string{"{{input:int}}"}},
+		{name: "unsupported type", args: []string{"{{width:date}}"}},
+		{name: "unsupported format", args: []string{"{{width:%v}}"}},
+		{name: "two verbs", args: []string{"{{width:%d%d}}"}},
+		{name: "invalid default", args: []string{"{{width|wide}}"}},
+		{name: "empty group", args: []string{"[]"}},
+		{name: "group without a param", args: []string{"[-o {{output}}]"}},
+	}
+	for _, tt := range tests {
+		if _, err := ParseArgs(tt.args, params); err == nil {
+			t.Errorf("%s: ParseArgs(%q) succeeded, want an error", tt.name, tt.args)
+		}
+	}
+}
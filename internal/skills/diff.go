@@ -0,0 +1,117 @@
+package skills
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// DefinitionChange is one field that differs between two versions of a skill
+// definition. Before and After are compact JSON; empty means absent.
+type DefinitionChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// HashDefinition returns the hex sha256 of a definition file's bytes.
+func HashDefinition(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// DiffDefinitions lists the fields that differ between two definition files,
+// descending into objects ("executor.args"). Unparseable input is reported
+// as a single whole-definition change.
+func DiffDefinitions(before []byte, after []byte) []DefinitionChange {
+	var a, b any
+	if json.Unmarshal(before, &a) != nil || json.Unmarshal(after, &b) != nil {
+		if bytes.Equal(before, after) {
+			return nil
+		}
+		return []DefinitionChange{{Field: "definition", Before: compactJSON(before), After: compactJSON(after)}}
+	}
+	var out []DefinitionChange
+	diffValues("", a, b, &out)
+	return out
+}
+
+func diffValues(field string, a any, b any, out *[]DefinitionChange) {
+	am, aok := a.(map[string]any)
+	bm, bok := b.(map[string]any)
+	if aok && bok {
+		keys := make(map[string]struct{}, len(am)+len(bm))
+		for k := range am {
+			keys[k] = struct{}{}
+		}
+		for k := range bm {
+			keys[k] = struct{}{}
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			sub := k
+			if field != "" {
+				sub = field + "." + k
+			}
+			diffValues(sub, am[k], bm[k], out)
+		}
+		return
+	}
+	before, after := marshalValue(a), marshalValue(b)
+	if before == after {
+		return
+	}
+	if field == "" {
+		field = "definition"
+	}
+	*out = append(*out, DefinitionChange{Field: field, Before: before, After: after})
+}
+
+func marshalValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func compactJSON(b []byte) string {
+	var buf bytes.Buffer
+	if json.Compact(&buf, b) != nil {
+		return string(b)
+	}
+	return buf.String()
+}
+
+// PermissionDiff returns the permissions in after but not before, and the
+// reverse.
+func PermissionDiff(before []string, after []string) (added []string, removed []string) {
+	in := func(list []string, p string) bool {
+		for _, x := range list {
+			if x == p {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range NormalizePermissions(after) {
+		if !in(before, p) {
+			added = append(added, p)
+		}
+	}
+	for _, p := range NormalizePermissions(before) {
+		if !in(after, p) {
+			removed = append(removed, p)
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,130 @@
+package skills
+
+import (
+	"sort"
+	"time"
+)
+
+// SearchEvent is one recorded pick from the command bar. It holds no file
+// names or paths: only the input extensions, the choice and, when it is the
+// start of a skill name or alias, what was typed. Time is the UTC day.
+type SearchEvent struct {
+	Time  time.Time `json:"time"`
+	Query string    `json:"query"`
+	// Redacted marks a pick whose query was not kept because it could hold
+	// anything the user typed; it is replayed as usage but not scored.
+	Redacted        bool     `json:"redacted,omitempty"`
+	InputTypes      []string `json:"inputTypes,omitempty"`
+	PreviousSkillID string   `json:"previousSkillId,omitempty"`
+	ChosenSkillID   string   `json:"chosenSkillId"`
+	Position        int      `json:"position"`
+}
+
+// EvalReport summarizes how well a ranker reproduces recorded choices.
+type EvalReport struct {
+	Events int `json:"events"`
+	// Redacted counts events without a query, which are not scored.
+	Redacted int `json:"redacted"`
+	// Missing counts events whose chosen skill was not in the candidates,
+	// e.g. because it was removed since.
+	Missing int     `json:"missing"`
+	MRR     float64 `json:"mrr"`
+	Top1    float64 `json:"top1"`
+	Top3    float64 `json:"top3"`
+	// RecordedMRR is the MRR of the positions the app actually showed.
+	RecordedMRR float64 `json:"recordedMrr"`
+}
+
+// Evaluate replays events in time order against ranker. Usage and chain
+// transitions are rebuilt from the events themselves, so frecency and
+// next-step learning are judged on the same history the user had.
+func (r *Registry) Evaluate(ranker *Ranker, events []SearchEvent) EvalReport {
+	ordered := append([]SearchEvent(nil), events...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Time.Before(ordered[j].Time)
+	})
+	if r.loader != nil {
+		ranker = ranker.WithCategories(r.loader.Categories())
+	}
+
+	usage := make(map[string]UsageStats)
+	chains := make(ChainStats)
+	var report EvalReport
+	var reciprocal, recorded float64
+	var top1, top3 int
+	for _, ev := range ordered {
+		chain := ChainContext{PreviousSkillID: ev.PreviousSkillID, InputExt: ChainInputExt(ev.InputTypes), Transitions: chains}
+		if ev.Redacted {
+			report.Redacted++
+			recordReplayUsage(usage, chains, chain, ev)
+			continue
+		}
+		report.Events++
+		recorded += 1 / float64(ev.Position+1)
+
+		replay := ranker.clone()
+		at := ev.Time
+		replay.Now = func() time.Time { return at }
+
+		candidates, trimmed := r.candidates(ev.Query, ev.InputTypes)
+		ranked := r.order(replay.RankExplained(candidates, trimmed, ev.InputTypes, usage, chain), trimmed)
+
+		rank := -1
+		for i, item := range ranked {
+			if item.Skill.ID == ev.ChosenSkillID {
+				rank = i
+				break
+			}
+		}
+		if rank < 0 {
+			report.Missing++
+		} else {
+			reciprocal += 1 / float64(rank+1)
+			if rank == 0 {
+				top1++
+			}
+			if rank < 3 {
+				top3++
+			}
+		}
+		recordReplayUsage(usage, chains, chain, ev)
+	}
+	if report.Events > 0 {
+		n := float64(report.Events)
+		report.MRR = reciprocal / n
+		report.Top1 = float64(top1) / n
+		report.Top3 = float64(top3) / n
+		report.RecordedMRR = recorded / n
+	}
+	return report
+}
+
+// recordReplayUsage learns from ev the way the app does after a pick.
+func recordReplayUsage(usage map[string]UsageStats, chains ChainStats, chain ChainContext, ev SearchEvent) {
+	stat := usage[ev.ChosenSkillID]
+	stat.Count++
+	stat.LastUsed = ev.Time
+	usage[ev.ChosenSkillID] = stat
+	recordReplayTransition(chains, chain, ev.ChosenSkillID, ev.Time)
+}
+
+func recordReplayTransition(chains ChainStats, chain ChainContext, skillID string, at time.Time) {
+	keys := make([]string, 0, 2)
+	if chain.PreviousSkillID != "" {
+		keys = append(keys, TransitionKey(chain.PreviousSkillID, ""))
+	}
+	if chain.InputExt != "" {
+		keys = append(keys, TransitionKey(chain.PreviousSkillID, chain.InputExt))
+	}
+	for _, key := range keys {
+		next := chains[key]
+		if next == nil {
+			next = make(map[string]UsageStats)
+			chains[key] = next
+		}
+		stat := next[skillID]
+		stat.Count++
+		stat.LastUsed = at
+		next[skillID] = stat
+	}
+}
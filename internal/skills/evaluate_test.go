@@ -0,0 +1,103 @@
+package skills
+
+import (
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// newTestRegistry loads skills as embedded core skills.
+func newTestRegistry(t *testing.T, list ...Skill) *Registry {
+	t.Helper()
+	files := fstest.MapFS{}
+	for _, s := range list {
+		if s.Version == "" {
+			s.Version = "1.0.0"
+		}
+		if s.Category == "" {
+			s.Category = "convert"
+		}
+		if s.Executor.Type == "" {
+			s.Executor = Executor{Type: "native", Handler: "image." + s.ID}
+		}
+		if len(s.InputTypes) == 0 {
+			s.InputTypes = []string{".png", ".jpg"}
+		}
+		data, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files["core/"+s.ID+".json"] = &fstest.MapFile{Data: data}
+	}
+	registry := NewRegistry(RegistryOptions{EmbeddedFS: files, EmbeddedRoot: "core"})
+	if got := len(registry.List()); got != len(list) {
+		t.Fatalf("loaded %d skills, want %d", got, len(list))
+	}
+	return registry
+}
+
+func TestRegistryIsNamePrefix(t *testing.T) {
+	registry := newTestRegistry(t,
+		Skill{ID: "blur", Name: "Blur", Aliases: []string{"soften", "gaussian"},
+			Locales: map[string]LocalizedText{"de": {Name: "Weichzeichnen"}}},
+		Skill{ID: "rotate", Name: "Rotate", Aliases: []string{"turn"}},
+		Skill{ID: "secret", Name: "Hidden Tool"},
+	)
+	registry.SetOverlay(Overlay{Aliases: map[string][]string{"rotate": {"spin it"}}, Hidden: []string{"secret"}})
+	registry.SetLocale("de")
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"  ", true},
+		{"blu", true},
+		{" SOFT", true},
+		{"gaussian", true},
+		{"weich", true},
+		{"spin i", true},
+		{"rotate", true},
+		{"lur", false},
+		{"blur my holiday photos", false},
+		{"hidden", false},
+		{"/home/someone/private.png", false},
+	}
+	for _, tt := range tests {
+		if got := registry.IsNamePrefix(tt.query); got != tt.want {
+			t.Errorf("IsNamePrefix(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	registry := newTestRegistry(t,
+		Skill{ID: "blur", Name: "Blur"},
+		Skill{ID: "rotate", Name: "Rotate"},
+		Skill{ID: "sharpen", Name: "Sharpen"},
+	)
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	events := []SearchEvent{
+		{Time: day, Query: "", ChosenSkillID: "sharpen", Position: 2},
+		{Time: day, Redacted: true, ChosenSkillID: "rotate", Position: 0},
+		{Time: day.AddDate(0, 0, 1), Query: "sha", ChosenSkillID: "sharpen", Position: 0},
+	}
+
+	report := registry.Evaluate(registry.Ranker(), events)
+	if report.Events != 2 || report.Redacted != 1 {
+		t.Fatalf("scored %d events and %d redacted, want 2 and 1", report.Events, report.Redacted)
+	}
+	if report.Top1 != 0.5 {
+		t.Errorf("top1 = %v, want 0.5: the first empty query has no history", report.Top1)
+	}
+	if want := (1.0/3 + 1) / 2; report.RecordedMRR != want {
+		t.Errorf("recorded MRR = %v, want %v", report.RecordedMRR, want)
+	}
+
+	// Pins lead empty-query results as in the command bar.
+	registry.SetOverlay(Overlay{Pinned: []string{"sharpen"}})
+	if report := registry.Evaluate(registry.Ranker(), events); report.Top1 != 1 {
+		t.Errorf("top1 with sharpen pinned = %v, want 1", report.Top1)
+	}
+}
@@ -0,0 +1,102 @@
+package skills
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	extensionPattern = regexp.MustCompile(`^\.[a-z0-9]{1,10}$`)
+	envNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// reservedEnv are variables no skill may set: they change which binaries
+// and libraries a command loads.
+var reservedEnv = []string{"PATH", "LD_*", "DYLD_*", "PATHEXT", "COMSPEC", "SYSTEMROOT"}
+
+// tunableEnv are the variables skills without the system permission may set:
+// thread counts, resource limits and locale, which tune a tool but can't
+// make it load or run anything else.
+var tunableEnv = []string{"OMP_NUM_THREADS", "OMP_THREAD_LIMIT", "MAGICK_*_LIMIT", "MAGICK_THROTTLE", "AV_LOG_FORCE_NOCOLOR", "TZ", "LANG", "LC_*"}
+
+// validateCLI checks the templates and options of a CLI executor.
+func validateCLI(s Skill) error {
+	e := s.Executor
+	if _, err := ParseArgs(e.Args, s.Params); err != nil {
+		return err
+	}
+	if strings.Contains(e.OutputExtension, "{{") {
+		if _, err := ParseArgs([]string{e.OutputExtension}, s.Params); err != nil {
+			return fmt.Errorf("outputExtension: %w", err)
+		}
+	}
+	system := false
+	for _, p := range s.Permissions {
+		if p == PermSystem {
+			system = true
+		}
+	}
+	for name, value := range e.Env {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("env: invalid variable name %q", name)
+		}
+		if matchEnv(reservedEnv, name) {
+			return fmt.Errorf("env: %s may not be set by skills", name)
+		}
+		if !system && !matchEnv(tunableEnv, name) {
+			return fmt.Errorf("env: setting %s requires the %s permission", name, PermSystem)
+		}
+		if _, err := ParseArgs([]string{value}, s.Params); err != nil {
+			return fmt.Errorf("env %s: %w", name, err)
+		}
+	}
+	switch e.WorkingDir {
+	case "", "output", "input", "temp":
+	default:
+		return fmt.Errorf("workingDir must be output, input or temp")
+	}
+	if e.Outputs != "" {
+		if _, err := path.Match(e.Outputs, ""); err != nil || strings.Contains(e.Outputs, "/") || strings.Contains(e.Outputs, `\`) {
+			return fmt.Errorf("outputs must be a file name pattern, got %q", e.Outputs)
+		}
+		if e.Stdout {
+			return fmt.Errorf("outputs and stdout cannot be combined")
+		}
+	}
+	return validateProgress(s)
+}
+
+func matchEnv(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, strings.ToUpper(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputExtensionFor returns the executor's output extension (".png") with
+// params substituted, or "" when none is declared.
+func (s Skill) OutputExtensionFor(params map[string]any) (string, error) {
+	ext := strings.TrimSpace(s.Executor.OutputExtension)
+	if ext == "" {
+		return "", nil
+	}
+	if strings.Contains(ext, "{{") {
+		rendered, err := RenderValue(ext, nil, params, s.Params)
+		if err != nil {
+			return "", fmt.Errorf("output extension: %w", err)
+		}
+		ext = rendered
+	}
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	if !extensionPattern.MatchString(ext) {
+		return "", fmt.Errorf("output extension %q is not a valid file extension", ext)
+	}
+	return ext, nil
+}
@@ -0,0 +1,393 @@
+package skills
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxTypoDistance bounds edit distance for whole-name typo matches.
+	maxTypoDistance = 2
+	// maxTokenTypoDistance bounds edit distance for single-word typo matches.
+	maxTokenTypoDistance = 1
+)
+
+// searchIndex is rebuilt on every Loader.LoadAll so a keystroke only touches
+// the skills that can match instead of running Levenshtein over all of them.
+type searchIndex struct {
+	docs []indexedSkill
+	// trigrams maps each trigram of the searchable text to sorted doc indexes.
+	trigrams map[string][]int
+	// tokens maps each name/alias word to sorted doc indexes.
+	tokens map[string][]int
+	// sortedTokens allows prefix lookups by binary search.
+	sortedTokens []string
+	// acronyms maps the first two letters of each name acronym, nameLengths
+	// each name's length and tokenLengths each token's length to what has
+	// them, so the acronym and typo passes only visit possible matches.
+	acronyms     map[string][]int
+	nameLengths  map[int][]int
+	tokenLengths map[int][]string
+}
+
+type indexedSkill struct {
+	id string
+	// names holds the display name and, when localized, the English name.
+	names    []string
+	acronyms []string
+	aliases  []string
+	texts    []string
+}
+
+// newSearchIndex indexes skills for locale. A localized index covers both the
+// translated and the English strings.
+func newSearchIndex(skills map[string]Skill, locale string) *searchIndex {
+	ids := make([]string, 0, len(skills))
+	for id := range skills {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	idx := &searchIndex{
+		docs:         make([]indexedSkill, 0, len(ids)),
+		trigrams:     make(map[string][]int),
+		tokens:       make(map[string][]int),
+		acronyms:     make(map[string][]int),
+		nameLengths:  make(map[int][]int),
+		tokenLengths: make(map[int][]string),
+	}
+	for _, id := range ids {
+		idx.add(skills[id], locale)
+	}
+	for token := range idx.tokens {
+		idx.sortedTokens = append(idx.sortedTokens, token)
+	}
+	sort.Strings(idx.sortedTokens)
+	for _, token := range idx.sortedTokens {
+		n := len([]rune(token))
+		idx.tokenLengths[n] = append(idx.tokenLengths[n], token)
+	}
+	return idx
+}
+
+func (idx *searchIndex) add(skill Skill, locale string) {
+	localized := skill.Localized(locale)
+	doc := indexedSkill{id: skill.ID}
+	doc.names = append(doc.names, strings.ToLower(localized.Name))
+	if localized.Name != skill.Name {
+		doc.names = append(doc.names, strings.ToLower(skill.Name))
+	}
+	for _, name := range doc.names {
+		doc.acronyms = append(doc.acronyms, acronym(name))
+	}
+	for _, alias := range localized.Aliases {
+		doc.aliases = append(doc.aliases, strings.ToLower(alias))
+	}
+	doc.texts = append(append([]string{}, doc.names...), doc.aliases...)
+	doc.texts = append(doc.texts, strings.ToLower(localized.Description))
+	if localized.Description != skill.Description {
+		doc.texts = append(doc.texts, strings.ToLower(skill.Description))
+	}
+
+	n := len(idx.docs)
+	idx.docs = append(idx.docs, doc)
+
+	seenKey := make(map[string]struct{})
+	for _, ac := range doc.acronyms {
+		if r := []rune(ac); len(r) >= 2 {
+			key := string(r[:2])
+			if _, ok := seenKey[key]; !ok {
+				seenKey[key] = struct{}{}
+				idx.acronyms[key] = append(idx.acronyms[key], n)
+			}
+		}
+	}
+	seenLen := make(map[int]struct{})
+	for _, name := range doc.names {
+		length := len([]rune(name))
+		if _, ok := seenLen[length]; !ok {
+			seenLen[length] = struct{}{}
+			idx.nameLengths[length] = append(idx.nameLengths[length], n)
+		}
+	}
+
+	seenTri := make(map[string]struct{})
+	for _, text := range doc.texts {
+		for _, tri := range trigrams(text) {
+			if _, ok := seenTri[tri]; ok {
+				continue
+			}
+			seenTri[tri] = struct{}{}
+			idx.trigrams[tri] = append(idx.trigrams[tri], n)
+		}
+	}
+	seenTok := make(map[string]struct{})
+	for _, text := range append(append([]string{}, doc.names...), doc.aliases...) {
+		for _, token := range tokenize(text) {
+			if _, ok := seenTok[token]; ok {
+				continue
+			}
+			seenTok[token] = struct{}{}
+			idx.tokens[token] = append(idx.tokens[token], n)
+		}
+	}
+}
+
+// match returns the IDs of skills matching a lowercased, trimmed query by
+// substring, word prefix, acronym ("ctj" for Convert to JPEG) or a bounded
+// typo.
+func (idx *searchIndex) match(query string) map[string]struct{} {
+	out := make(map[string]struct{})
+	if idx == nil || query == "" {
+		return out
+	}
+	hit := func(n int) {
+		out[idx.docs[n].id] = struct{}{}
+	}
+
+	// Substring over name, aliases and description.
+	for _, n := range idx.substringCandidates(query) {
+		for _, text := range idx.docs[n].texts {
+			if strings.Contains(text, query) {
+				hit(n)
+				break
+			}
+		}
+	}
+
+	// Query words as prefixes of name/alias words.
+	words := tokenize(query)
+	for _, word := range words {
+		for _, token := range idx.tokensWithPrefix(word) {
+			for _, n := range idx.tokens[token] {
+				hit(n)
+			}
+		}
+	}
+
+	// Acronym of the name.
+	qRunes := []rune(query)
+	if len(words) == 1 && len(qRunes) >= 2 {
+		for _, n := range idx.acronyms[string(qRunes[:2])] {
+			for _, ac := range idx.docs[n].acronyms {
+				if strings.HasPrefix(ac, query) {
+					hit(n)
+					break
+				}
+			}
+		}
+	}
+
+	// Typos: whole name, then single words, with bounded distance.
+	qLen := len(qRunes)
+	if qLen >= 3 {
+		for _, n := range idx.typoCandidates(query) {
+			doc := idx.docs[n]
+			if _, ok := out[doc.id]; ok {
+				continue
+			}
+			for _, name := range doc.names {
+				if abs(len([]rune(name))-qLen) > maxTypoDistance {
+					continue
+				}
+				if _, ok := boundedLevenshtein(name, query, maxTypoDistance); ok {
+					hit(n)
+					break
+				}
+			}
+		}
+	}
+	for _, word := range words {
+		wLen := len([]rune(word))
+		if wLen < 4 {
+			continue
+		}
+		for length := wLen - maxTokenTypoDistance; length <= wLen+maxTokenTypoDistance; length++ {
+			for _, token := range idx.tokenLengths[length] {
+				if _, ok := boundedLevenshtein(token, word, maxTokenTypoDistance); ok {
+					for _, n := range idx.tokens[token] {
+						hit(n)
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// typoCandidates returns the docs that can have a name within
+// maxTypoDistance of query. Each edit changes at most three trigrams, so a
+// long enough query shares the rest with such a name and the trigram
+// postings narrow the search; shorter queries fall back to names of a
+// similar length.
+func (idx *searchIndex) typoCandidates(query string) []int {
+	tris := make(map[string]struct{})
+	for _, tri := range trigrams(query) {
+		tris[tri] = struct{}{}
+	}
+	if need := len(tris) - 3*maxTypoDistance; need > 0 {
+		shared := make(map[int]int)
+		for tri := range tris {
+			for _, n := range idx.trigrams[tri] {
+				shared[n]++
+			}
+		}
+		var out []int
+		for n, count := range shared {
+			if count >= need {
+				out = append(out, n)
+			}
+		}
+		sort.Ints(out)
+		return out
+	}
+	qLen := len([]rune(query))
+	var out []int
+	for length := qLen - maxTypoDistance; length <= qLen+maxTypoDistance; length++ {
+		out = append(out, idx.nameLengths[length]...)
+	}
+	return out
+}
+
+// substringCandidates narrows the docs that can contain query using the
+// trigram postings. Queries shorter than a trigram check every doc.
+func (idx *searchIndex) substringCandidates(query string) []int {
+	tris := trigrams(query)
+	if len(tris) == 0 {
+		all := make([]int, len(idx.docs))
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+	var result []int
+	for i, tri := range tris {
+		postings, ok := idx.trigrams[tri]
+		if !ok {
+			return nil
+		}
+		if i == 0 {
+			result = postings
+			continue
+		}
+		result = intersectSorted(result, postings)
+		if len(result) == 0 {
+			return nil
+		}
+	}
+	return result
+}
+
+func (idx *searchIndex) tokensWithPrefix(prefix string) []string {
+	start := sort.SearchStrings(idx.sortedTokens, prefix)
+	end := start
+	for end < len(idx.sortedTokens) && strings.HasPrefix(idx.sortedTokens[end], prefix) {
+		end++
+	}
+	return idx.sortedTokens[start:end]
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func trigrams(text string) []string {
+	runes := []rune(text)
+	if len(runes) < 3 {
+		return nil
+	}
+	out := make([]string, 0, len(runes)-2)
+	for i := 0; i+3 <= len(runes); i++ {
+		out = append(out, string(runes[i:i+3]))
+	}
+	return out
+}
+
+// acronym returns the first letter of every word: "convert to jpeg" -> "ctj".
+func acronym(text string) string {
+	var b strings.Builder
+	for _, word := range tokenize(text) {
+		r := []rune(word)
+		b.WriteRune(r[0])
+	}
+	return b.String()
+}
+
+func intersectSorted(a, b []int) []int {
+	out := make([]int, 0, min(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, a[i])
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return out
+}
+
+// levenshtein is the edit distance between a and b, counted in runes.
+func levenshtein(a, b string) int {
+	d, _ := boundedLevenshtein(a, b, -1)
+	return d
+}
+
+// boundedLevenshtein computes the edit distance between a and b and gives up
+// as soon as it must exceed limit (ok=false). A negative limit is unbounded.
+func boundedLevenshtein(a, b string, limit int) (int, bool) {
+	if a == b {
+		return 0, true
+	}
+	ra, rb := []rune(a), []rune(b)
+	if limit >= 0 && abs(len(ra)-len(rb)) > limit {
+		return limit + 1, false
+	}
+	if len(ra) == 0 {
+		return len(rb), limit < 0 || len(rb) <= limit
+	}
+	if len(rb) == 0 {
+		return len(ra), limit < 0 || len(ra) <= limit
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := 0; j <= len(rb); j++ {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 0
+			if ra[i-1] != rb[j-1] {
+				cost = 1
+			}
+			curr[j] = min(
+				curr[j-1]+1,
+				prev[j]+1,
+				prev[j-1]+cost,
+			)
+			rowMin = min(rowMin, curr[j])
+		}
+		if limit >= 0 && rowMin > limit {
+			return limit + 1, false
+		}
+		prev, curr = curr, prev
+	}
+	d := prev[len(rb)]
+	return d, limit < 0 || d <= limit
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,234 @@
+package skills
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func indexFixture() map[string]Skill {
+	list := []Skill{
+		{ID: "convert-jpeg", Name: "Convert to JPEG", Aliases: []string{"jpg", "to jpeg"}, Description: "Re-encode images as JPEG.",
+			Locales: map[string]LocalizedText{"de": {Name: "In JPEG umwandeln", Aliases: []string{"jpg"}}}},
+		{ID: "convert-png", Name: "Convert to PNG", Description: "Lossless PNG output."},
+		{ID: "resize", Name: "Resize Image", Aliases: []string{"scale", "shrink"}, Description: "Change the pixel dimensions.",
+			Locales: map[string]LocalizedText{"de": {Name: "Bildgröße ändern", Description: "Ändert die Pixelmaße."}}},
+		{ID: "strip-metadata", Name: "Strip Metadata", Aliases: []string{"remove exif"}, Description: "Drop EXIF, IPTC and XMP."},
+		{ID: "extract-audio", Name: "Extract Audio", Aliases: []string{"rip sound"}, Description: "Save the audio track of a video."},
+		{ID: "trim-video", Name: "Trim Video", Description: "Cut a clip by start and end time."},
+		{ID: "rotate", Name: "Rotate", Aliases: []string{"turn"}, Description: "Rotate by 90 degrees."},
+		{ID: "ocr", Name: "OCR", Description: "Recognize text in scans."},
+		{ID: "compress-pdf", Name: "Compress PDF", Aliases: []string{"shrink pdf"}, Description: "Smaller PDF documents."},
+		{ID: "make-gif", Name: "Make GIF", Description: "Animate frames into a GIF."},
+	}
+	out := make(map[string]Skill, len(list))
+	for _, s := range list {
+		out[s.ID] = s
+	}
+	return out
+}
+
+// matchKinds are full scans over every indexed skill, one per pass of
+// searchIndex.match, without the postings that narrow the passes.
+var matchKinds = map[string]func(doc indexedSkill, query string) bool{
+	"substring": func(doc indexedSkill, query string) bool {
+		return slices.ContainsFunc(doc.texts, func(text string) bool { return strings.Contains(text, query) })
+	},
+	"prefix": func(doc indexedSkill, query string) bool {
+		for _, word := range tokenize(query) {
+			for _, token := range docTokens(doc) {
+				if strings.HasPrefix(token, word) {
+					return true
+				}
+			}
+		}
+		return false
+	},
+	"acronym": func(doc indexedSkill, query string) bool {
+		if len(tokenize(query)) != 1 || len([]rune(query)) < 2 {
+			return false
+		}
+		return slices.ContainsFunc(doc.acronyms, func(ac string) bool { return strings.HasPrefix(ac, query) })
+	},
+	"typo": func(doc indexedSkill, query string) bool {
+		if len([]rune(query)) >= 3 {
+			for _, name := range doc.names {
+				if levenshtein(name, query) <= maxTypoDistance {
+					return true
+				}
+			}
+		}
+		for _, word := range tokenize(query) {
+			if len([]rune(word)) < 4 {
+				continue
+			}
+			for _, token := range docTokens(doc) {
+				if levenshtein(token, word) <= maxTokenTypoDistance {
+					return true
+				}
+			}
+		}
+		return false
+	},
+}
+
+func docTokens(doc indexedSkill) []string {
+	var out []string
+	for _, text := range append(slices.Clone(doc.names), doc.aliases...) {
+		out = append(out, tokenize(text)...)
+	}
+	return out
+}
+
+func scan(idx *searchIndex, query string, kinds ...string) []string {
+	var out []string
+	for _, doc := range idx.docs {
+		for _, kind := range kinds {
+			if matchKinds[kind](doc, query) {
+				out = append(out, doc.id)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func TestSearchIndexMatch(t *testing.T) {
+	tests := []struct {
+		kind   string
+		locale string
+		query  string
+		want   []string
+	}{
+		{"substring", "", "pixel dim", []string{"resize"}},
+		{"substring", "", "o p", []string{"convert-png"}},
+		{"substring", "", "xif", []string{"strip-metadata"}},
+		{"substring", "", "pd", []string{"compress-pdf"}},
+		{"substring", "de", "größe", []string{"resize"}},
+		{"substring", "de", "resize", []string{"resize"}},
+		{"prefix", "", "conv", []string{"convert-jpeg", "convert-png"}},
+		{"prefix", "", "tri", []string{"trim-video"}},
+		{"prefix", "", "ext aud", []string{"extract-audio"}},
+		{"prefix", "", "shr", []string{"compress-pdf", "resize"}},
+		{"prefix", "de", "umw", []string{"convert-jpeg"}},
+		{"acronym", "", "ctj", []string{"convert-jpeg"}},
+		{"acronym", "", "ct", []string{"convert-jpeg", "convert-png"}},
+		{"acronym", "", "sm", []string{"strip-metadata"}},
+		{"acronym", "", "mg", []string{"make-gif"}},
+		{"acronym", "de", "ij", []string{"convert-jpeg"}},
+		{"acronym", "de", "ctj", []string{"convert-jpeg"}},
+		{"acronym", "", "c", nil},
+		{"typo", "", "rotte", []string{"rotate"}},
+		{"typo", "", "ocrr", []string{"ocr"}},
+		{"typo", "", "trim vidoe", []string{"trim-video"}},
+		{"typo", "", "metadat", []string{"strip-metadata"}},
+		{"typo", "", "compres", []string{"compress-pdf"}},
+		{"typo", "", "extrcat", nil},
+		{"typo", "de", "bildgroße", []string{"resize"}},
+		{"typo", "", "ocx", []string{"ocr"}},
+	}
+	indexes := map[string]*searchIndex{}
+	for _, tt := range tests {
+		t.Run(tt.kind+"/"+tt.query, func(t *testing.T) {
+			idx, ok := indexes[tt.locale]
+			if !ok {
+				idx = newSearchIndex(indexFixture(), tt.locale)
+				indexes[tt.locale] = idx
+			}
+			if got := scan(idx, tt.query, tt.kind); !slices.Equal(got, tt.want) {
+				t.Fatalf("%s scan of %q = %v, want %v", tt.kind, tt.query, got, tt.want)
+			}
+			got := idx.match(tt.query)
+			for _, id := range tt.want {
+				if _, ok := got[id]; !ok {
+					t.Errorf("match(%q) misses %s: %v", tt.query, id, slices.Sorted(maps.Keys(got)))
+				}
+			}
+		})
+	}
+}
+
+// TestSearchIndexMatchesScan checks that narrowing by postings never loses
+// or adds a skill: match must equal the full scan for substrings, typos and
+// partial words of every name, alias and description.
+func TestSearchIndexMatchesScan(t *testing.T) {
+	kinds := slices.Sorted(maps.Keys(matchKinds))
+	for _, locale := range []string{"", "de"} {
+		idx := newSearchIndex(indexFixture(), locale)
+		queries := map[string]struct{}{}
+		for _, doc := range idx.docs {
+			for _, text := range doc.texts {
+				for _, q := range queryVariants(text) {
+					queries[q] = struct{}{}
+				}
+			}
+		}
+		for query := range queries {
+			want := scan(idx, query, kinds...)
+			got := slices.Sorted(maps.Keys(idx.match(query)))
+			if !slices.Equal(got, want) {
+				t.Errorf("locale %q: match(%q) = %v, full scan = %v", locale, query, got, want)
+			}
+			for _, kind := range kinds {
+				for _, id := range scan(idx, query, kind) {
+					if !slices.Contains(got, id) {
+						t.Errorf("locale %q: %s pass of %q misses %s", locale, kind, query, id)
+					}
+				}
+			}
+		}
+	}
+}
+
+// queryVariants returns short substrings of text, its words and their
+// prefixes, and each word and the whole text with one or two edits.
+func queryVariants(text string) []string {
+	runes := []rune(text)
+	var out []string
+	for i := range runes {
+		for n := 1; n <= 6 && i+n <= len(runes); n++ {
+			out = append(out, strings.TrimSpace(string(runes[i:i+n])))
+		}
+	}
+	words := tokenize(text)
+	for _, word := range words {
+		out = append(out, edits(word)...)
+	}
+	if len(runes) <= 24 {
+		for _, once := range edits(text) {
+			twice := edits(once)
+			out = append(out, once)
+			out = append(out, twice[:min(3, len(twice))]...)
+		}
+		// Two edits far apart remove the most trigrams a typo can.
+		for i := 0; i < len(runes)/2; i++ {
+			replaced := slices.Clone(runes)
+			replaced[i], replaced[len(runes)-1-i] = 'x', 'x'
+			out = append(out, string(replaced))
+		}
+	}
+	if len(words) > 1 {
+		out = append(out, acronym(text), words[0][:1]+" "+words[1])
+	}
+	return slices.DeleteFunc(out, func(q string) bool { return q == "" })
+}
+
+// edits returns text with one rune deleted, replaced by 'x' or swapped with
+// the next one, at every position.
+func edits(text string) []string {
+	runes := []rune(text)
+	var out []string
+	for i := range runes {
+		out = append(out, string(slices.Delete(slices.Clone(runes), i, i+1)))
+		replaced := slices.Clone(runes)
+		replaced[i] = 'x'
+		out = append(out, string(replaced))
+		if i+1 < len(runes) {
+			swapped := slices.Clone(runes)
+			swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+			out = append(out, string(swapped))
+		}
+	}
+	return out
+}
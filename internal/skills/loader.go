@@ -7,10 +7,13 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"asteria/internal/filetype"
+
 	"github.com/fsnotify/fsnotify"
 )
 
@@ -25,13 +28,37 @@ type LoaderOptions struct {
 
 	// CommunityRoot is an on-disk directory that users can add skills/packs to.
 	CommunityRoot string
+
+	// AppVersion is checked against a pack's minAppVersion; empty skips the
+	// check.
+	AppVersion string
+	// DisabledPacks lists pack IDs whose skills are not loaded.
+	DisabledPacks []string
+	// TrustedPublishers are the keys pack signatures are verified against.
+	TrustedPublishers []Publisher
+	// Policy keeps skills and packs an administrator disallows out of the
+	// loaded set.
+	Policy Policy
+	// ToolChecker probes the tools skills require; nil treats every tool as
+	// available.
+	ToolChecker ToolChecker
 }
 
 type Loader struct {
 	opts LoaderOptions
 
-	mu      sync.RWMutex
-	skills  map[string]Skill
+	mu            sync.RWMutex
+	skills        map[string]Skill
+	categories    map[string]CategoryDef
+	packs         map[string]Pack
+	disabledPacks map[string]bool
+	publishers    []Publisher
+	policy        Policy
+	blocked       map[string]BlockedSkill
+	toolChecker   ToolChecker
+	// indexes holds the search index per normalized locale ("" is English);
+	// non-English indexes are built on first use after each LoadAll.
+	indexes map[string]*searchIndex
 	lastErr error
 
 	watchMu sync.Mutex
@@ -41,10 +68,20 @@ type Loader struct {
 }
 
 func NewLoader(opts LoaderOptions) *Loader {
+	disabled := make(map[string]bool, len(opts.DisabledPacks))
+	for _, id := range opts.DisabledPacks {
+		disabled[id] = true
+	}
 	return &Loader{
-		opts:    opts,
-		skills:  make(map[string]Skill),
-		changed: make(chan struct{}, 1),
+		opts:          opts,
+		skills:        make(map[string]Skill),
+		categories:    make(map[string]CategoryDef),
+		packs:         make(map[string]Pack),
+		disabledPacks: disabled,
+		publishers:    opts.TrustedPublishers,
+		policy:        opts.Policy,
+		toolChecker:   opts.ToolChecker,
+		changed:       make(chan struct{}, 1),
 	}
 }
 
@@ -68,6 +105,43 @@ func (l *Loader) List() []Skill {
 	return out
 }
 
+// Categories returns the declared categories keyed by ID.
+func (l *Loader) Categories() map[string]CategoryDef {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make(map[string]CategoryDef, len(l.categories))
+	for k, v := range l.categories {
+		out[k] = v
+	}
+	return out
+}
+
+// matchQuery returns the IDs of skills matching a lowercased query in the
+// English and locale strings, using the index built after the last LoadAll.
+func (l *Loader) matchQuery(query string, locale string) map[string]struct{} {
+	return l.index(NormalizeLocale(locale)).match(query)
+}
+
+func (l *Loader) index(locale string) *searchIndex {
+	l.mu.RLock()
+	index, ok := l.indexes[locale]
+	l.mu.RUnlock()
+	if ok {
+		return index
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if index, ok := l.indexes[locale]; ok {
+		return index
+	}
+	index = newSearchIndex(l.skills, locale)
+	if l.indexes == nil {
+		l.indexes = make(map[string]*searchIndex)
+	}
+	l.indexes[locale] = index
+	return index
+}
+
 func (l *Loader) GetByID(id string) (Skill, bool) {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
@@ -78,7 +152,7 @@ func (l *Loader) GetByID(id string) (Skill, bool) {
 // LoadAll loads skills from embedded core, disk core (optional), and community.
 // Precedence: embedded < disk core < community.
 func (l *Loader) LoadAll() error {
-	merged := make(map[string]Skill)
+	merged := newLoadResult()
 	var errOut error
 
 	if l.opts.EmbeddedFS != nil && strings.TrimSpace(l.opts.EmbeddedRoot) != "" {
@@ -86,7 +160,7 @@ func (l *Loader) LoadAll() error {
 		if err != nil {
 			errOut = joinErr(errOut, fmt.Errorf("skills: invalid embedded root %q: %w", l.opts.EmbeddedRoot, err))
 		} else {
-			skills, err := l.collectFromFS(sub, SkillSourceCoreEmbedded)
+			skills, err := l.collect(sub, "", SkillSourceCoreEmbedded)
 			if err != nil {
 				errOut = joinErr(errOut, err)
 			}
@@ -97,7 +171,7 @@ func (l *Loader) LoadAll() error {
 	// Disk core (dev override)
 	if root := strings.TrimSpace(l.opts.DiskCoreRoot); root != "" {
 		if info, err := os.Stat(root); err == nil && info.IsDir() {
-			skills, err := l.collectFromDisk(root, SkillSourceCoreDisk)
+			skills, err := l.collect(os.DirFS(root), root, SkillSourceCoreDisk)
 			if err != nil {
 				errOut = joinErr(errOut, err)
 			}
@@ -108,7 +182,7 @@ func (l *Loader) LoadAll() error {
 	// Community
 	if root := strings.TrimSpace(l.opts.CommunityRoot); root != "" {
 		if info, err := os.Stat(root); err == nil && info.IsDir() {
-			skills, err := l.collectFromDisk(root, SkillSourceCommunity)
+			skills, err := l.collect(os.DirFS(root), root, SkillSourceCommunity)
 			if err != nil {
 				errOut = joinErr(errOut, err)
 			}
@@ -117,13 +191,21 @@ func (l *Loader) LoadAll() error {
 	}
 
 	// Normalize and basic validation.
-	for id, s := range merged {
+	for id, s := range merged.skills {
 		s.Permissions = NormalizePermissions(s.Permissions)
-		merged[id] = s
+		merged.skills[id] = s
 	}
+	blocked := l.applyPolicy(merged)
+	checkTools(merged.skills, l.currentToolChecker())
+
+	index := newSearchIndex(merged.skills, "")
 
 	l.mu.Lock()
-	l.skills = merged
+	l.skills = merged.skills
+	l.categories = merged.categories
+	l.packs = merged.packs
+	l.blocked = blocked
+	l.indexes = map[string]*searchIndex{"": index}
 	l.lastErr = errOut
 	l.mu.Unlock()
 
@@ -135,9 +217,100 @@ func (l *Loader) LoadAll() error {
 	return errOut
 }
 
-func (l *Loader) mergeInto(dst map[string]Skill, src map[string]Skill) {
-	for k, v := range src {
-		dst[k] = v
+// applyPolicy removes the skills the policy blocks from loaded and marks
+// blocked packs, returning the blocked skills with their reasons.
+func (l *Loader) applyPolicy(loaded loadResult) map[string]BlockedSkill {
+	policy := l.Policy()
+	for id, p := range loaded.packs {
+		if reason := policy.BlocksPack(p); reason != "" && p.Error == "" {
+			p.Error = reason
+			loaded.packs[id] = p
+		}
+	}
+	blocked := make(map[string]BlockedSkill)
+	for id, s := range loaded.skills {
+		var pack *Pack
+		if p, ok := loaded.packs[s.PackID]; ok {
+			pack = &p
+		}
+		if reason := policy.Blocks(s, pack); reason != "" {
+			blocked[id] = BlockedSkill{ID: id, Name: s.Name, PackID: s.PackID, Reason: reason}
+			delete(loaded.skills, id)
+		}
+	}
+	return blocked
+}
+
+// SetPolicy replaces the administrator policy. Call LoadAll to apply it.
+func (l *Loader) SetPolicy(policy Policy) {
+	l.mu.Lock()
+	l.policy = policy
+	l.mu.Unlock()
+}
+
+func (l *Loader) Policy() Policy {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.policy
+}
+
+// SetToolChecker replaces how required tools are probed. Call LoadAll to
+// apply it.
+func (l *Loader) SetToolChecker(checker ToolChecker) {
+	l.mu.Lock()
+	l.toolChecker = checker
+	l.mu.Unlock()
+}
+
+func (l *Loader) currentToolChecker() ToolChecker {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.toolChecker
+}
+
+// Blocked returns the skills the policy kept out of the last LoadAll.
+func (l *Loader) Blocked() []BlockedSkill {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make([]BlockedSkill, 0, len(l.blocked))
+	for _, b := range l.blocked {
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// BlockedReason explains why the policy blocked a skill ID, or returns "".
+func (l *Loader) BlockedReason(id string) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.blocked[id].Reason
+}
+
+// loadResult is everything collected from one skills root.
+type loadResult struct {
+	skills     map[string]Skill
+	categories map[string]CategoryDef
+	packs      map[string]Pack
+}
+
+func newLoadResult() loadResult {
+	return loadResult{
+		skills:     make(map[string]Skill),
+		categories: make(map[string]CategoryDef),
+		packs:      make(map[string]Pack),
+	}
+}
+
+func (l *Loader) mergeInto(dst loadResult, src loadResult) {
+	for k, v := range src.skills {
+		dst.skills[k] = v
+	}
+	for k, v := range src.categories {
+		dst.categories[k] = v
+	}
+	for k, v := range src.packs {
+		dst.packs[k] = v
 	}
 }
 
@@ -256,8 +429,8 @@ func isSkillJSONFilename(name string) bool {
 	if strings.HasPrefix(lower, ".") {
 		return false
 	}
-	// Reserved files for future pack infrastructure.
-	if lower == "manifest.json" || lower == "pack.json" {
+	// Pack manifests describe a pack, not a skill.
+	if isPackManifestFilename(lower) {
 		return false
 	}
 	// Convention: files starting with '_' are metadata, not skills.
@@ -267,8 +440,32 @@ func isSkillJSONFilename(name string) bool {
 	return true
 }
 
-func (l *Loader) collectFromFS(fsys fs.FS, source SkillSource) (map[string]Skill, error) {
-	loaded := make(map[string]Skill)
+func isCategoriesFilename(name string) bool {
+	return strings.ToLower(name) == categoriesFilename
+}
+
+// parseCategories reads a categories metadata file into loaded.
+func parseCategories(b []byte, path string, loaded loadResult) error {
+	var file CategoriesFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return fmt.Errorf("skills: parse %s: %w", path, err)
+	}
+	for _, c := range file.Categories {
+		id := strings.ToLower(strings.TrimSpace(c.ID))
+		if id == "" {
+			return fmt.Errorf("skills: invalid %s: category missing id", path)
+		}
+		c.ID = id
+		loaded.categories[id] = c
+	}
+	return nil
+}
+
+// collect walks fsys for skills, category metadata and packs. diskRoot is the
+// on-disk directory fsys was opened from ("" for embedded skills) and is used
+// to record definition paths.
+func (l *Loader) collect(fsys fs.FS, diskRoot string, source SkillSource) (loadResult, error) {
+	loaded := newLoadResult()
 	var errOut error
 	_ = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -276,71 +473,67 @@ func (l *Loader) collectFromFS(fsys fs.FS, source SkillSource) (map[string]Skill
 			return nil
 		}
 		if d.IsDir() {
+			if manifest, ok := packManifestPath(fsys, path); ok {
+				errOut = joinErr(errOut, l.collectPack(fsys, path, manifest, diskRoot, source, loaded))
+				return fs.SkipDir
+			}
 			return nil
 		}
-		if !isSkillJSONFilename(d.Name()) {
-			return nil
-		}
-		b, err := fs.ReadFile(fsys, path)
-		if err != nil {
-			errOut = joinErr(errOut, err)
-			return nil
-		}
-		var s Skill
-		if err := json.Unmarshal(b, &s); err != nil {
-			errOut = joinErr(errOut, fmt.Errorf("skills: parse %s: %w", path, err))
-			return nil
-		}
-		s.Source = source
-		s.DefinitionPath = path
-		norm, err := normalizeSkill(s)
-		if err == nil {
-			loaded[norm.ID] = norm
-		} else {
-			errOut = joinErr(errOut, fmt.Errorf("skills: invalid %s: %w", path, err))
-		}
-		return nil
-	})
-	return loaded, errOut
-}
-
-func (l *Loader) collectFromDisk(root string, source SkillSource) (map[string]Skill, error) {
-	loaded := make(map[string]Skill)
-	var errOut error
-	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
+		if isCategoriesFilename(d.Name()) {
+			b, err := fs.ReadFile(fsys, path)
+			if err == nil {
+				err = parseCategories(b, path, loaded)
+			}
 			errOut = joinErr(errOut, err)
 			return nil
 		}
-		if d.IsDir() {
-			return nil
-		}
 		if !isSkillJSONFilename(d.Name()) {
 			return nil
 		}
-		b, err := os.ReadFile(path)
+		s, err := readSkill(fsys, path, definitionPath(diskRoot, path), source)
 		if err != nil {
 			errOut = joinErr(errOut, err)
 			return nil
 		}
-		var s Skill
-		if err := json.Unmarshal(b, &s); err != nil {
-			errOut = joinErr(errOut, fmt.Errorf("skills: parse %s: %w", path, err))
-			return nil
+		// Loose community skills carry no signature.
+		if source == SkillSourceCommunity {
+			s.Verification = VerificationUnverified
 		}
-		s.Source = source
-		s.DefinitionPath = path
-		norm, err := normalizeSkill(s)
-		if err != nil {
-			errOut = joinErr(errOut, fmt.Errorf("skills: invalid %s: %w", path, err))
-			return nil
-		}
-		loaded[norm.ID] = norm
+		loaded.skills[s.ID] = s
 		return nil
 	})
 	return loaded, errOut
 }
 
+// readSkill parses and normalizes one skill definition file.
+func readSkill(fsys fs.FS, path string, defPath string, source SkillSource) (Skill, error) {
+	b, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return Skill{}, err
+	}
+	var s Skill
+	if err := json.Unmarshal(b, &s); err != nil {
+		return Skill{}, fmt.Errorf("skills: parse %s: %w", defPath, err)
+	}
+	s.Source = source
+	s.DefinitionPath = defPath
+	s.PackID = ""
+	norm, err := normalizeSkill(s)
+	if err != nil {
+		return Skill{}, fmt.Errorf("skills: invalid %s: %w", defPath, err)
+	}
+	norm.Definition = b
+	norm.DefinitionHash = HashDefinition(b)
+	return norm, nil
+}
+
+func definitionPath(diskRoot string, path string) string {
+	if diskRoot == "" {
+		return path
+	}
+	return filepath.Join(diskRoot, filepath.FromSlash(path))
+}
+
 func normalizeSkill(s Skill) (Skill, error) {
 	if strings.TrimSpace(s.ID) == "" {
 		return Skill{}, fmt.Errorf("missing id")
@@ -376,6 +569,18 @@ func normalizeSkill(s Skill) (Skill, error) {
 		s.Params = nil
 	}
 	s.Permissions = NormalizePermissions(s.Permissions)
+	for i, pattern := range s.InputMimeTypes {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if !filetype.ValidMIMEPattern(pattern) {
+			return Skill{}, fmt.Errorf("invalid inputMimeTypes entry %q", s.InputMimeTypes[i])
+		}
+		s.InputMimeTypes[i] = pattern
+	}
+	if s.Executor.Type == "cli" {
+		if err := validateCLI(s); err != nil {
+			return Skill{}, err
+		}
+	}
 	return s, nil
 }
 
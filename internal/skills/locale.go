@@ -0,0 +1,53 @@
+package skills
+
+import "strings"
+
+// NormalizeLocale lowercases a locale and uses '-' as separator: "pt_BR" ->
+// "pt-br".
+func NormalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(locale)), "_", "-")
+}
+
+// Translation returns the translation for locale, falling back from a
+// regional locale ("de-at") to its language ("de").
+func (s Skill) Translation(locale string) (LocalizedText, bool) {
+	locale = NormalizeLocale(locale)
+	if locale == "" || len(s.Locales) == 0 {
+		return LocalizedText{}, false
+	}
+	for key, text := range s.Locales {
+		if NormalizeLocale(key) == locale {
+			return text, true
+		}
+	}
+	if base, _, ok := strings.Cut(locale, "-"); ok {
+		for key, text := range s.Locales {
+			if NormalizeLocale(key) == base {
+				return text, true
+			}
+		}
+	}
+	return LocalizedText{}, false
+}
+
+// Localized returns a copy of the skill showing the translated name and
+// description. The English name and aliases are kept as aliases so typing
+// in English still ranks the skill.
+func (s Skill) Localized(locale string) Skill {
+	text, ok := s.Translation(locale)
+	if !ok {
+		return s
+	}
+	aliases := make([]string, 0, len(s.Aliases)+len(text.Aliases)+1)
+	aliases = append(aliases, text.Aliases...)
+	if strings.TrimSpace(text.Name) != "" && text.Name != s.Name {
+		aliases = append(aliases, s.Name)
+		s.Name = text.Name
+	}
+	aliases = append(aliases, s.Aliases...)
+	s.Aliases = aliases
+	if strings.TrimSpace(text.Description) != "" {
+		s.Description = text.Description
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package skills
+
+import "strings"
+
+// Overlay is the user's personal layer over the loaded skills: extra aliases,
+// skills pinned to the top of empty-query results and skills never shown.
+// It is applied at search time; skill JSON is never modified.
+type Overlay struct {
+	Aliases map[string][]string `json:"aliases"`
+	Pinned  []string            `json:"pinned"`
+	Hidden  []string            `json:"hidden"`
+}
+
+func (o Overlay) isHidden(skillID string) bool {
+	for _, id := range o.Hidden {
+		if id == skillID {
+			return true
+		}
+	}
+	return false
+}
+
+// pinRank returns the position of skillID among the pins, or -1.
+func (o Overlay) pinRank(skillID string) int {
+	for i, id := range o.Pinned {
+		if id == skillID {
+			return i
+		}
+	}
+	return -1
+}
+
+// withAliases returns skill with the user's aliases appended.
+func (o Overlay) withAliases(skill Skill) Skill {
+	extra := o.Aliases[skill.ID]
+	if len(extra) == 0 {
+		return skill
+	}
+	aliases := make([]string, 0, len(skill.Aliases)+len(extra))
+	aliases = append(aliases, skill.Aliases...)
+	aliases = append(aliases, extra...)
+	skill.Aliases = aliases
+	return skill
+}
+
+// matchAliases returns the IDs of skills whose user aliases match a
+// lowercased query the same way built-in aliases do.
+func (o Overlay) matchAliases(query string) map[string]struct{} {
+	out := make(map[string]struct{})
+	for id, aliases := range o.Aliases {
+		for _, alias := range aliases {
+			a := strings.ToLower(alias)
+			if strings.Contains(a, query) || acronym(a) == query {
+				out[id] = struct{}{}
+				break
+			}
+		}
+	}
+	return out
+}
+
+// pinFirst moves pinned results to the front in pin order, keeping the rest
+// in ranked order.
+func pinFirst(ranked []ExplainedSkill, overlay Overlay) []ExplainedSkill {
+	if len(overlay.Pinned) == 0 {
+		return ranked
+	}
+	pinned := make([]ExplainedSkill, len(overlay.Pinned))
+	found := make([]bool, len(overlay.Pinned))
+	rest := make([]ExplainedSkill, 0, len(ranked))
+	for _, item := range ranked {
+		if i := overlay.pinRank(item.Skill.ID); i >= 0 {
+			item.Pinned = true
+			pinned[i] = item
+			found[i] = true
+			continue
+		}
+		rest = append(rest, item)
+	}
+	out := make([]ExplainedSkill, 0, len(ranked))
+	for i, item := range pinned {
+		if found[i] {
+			out = append(out, item)
+		}
+	}
+	return append(out, rest...)
+}
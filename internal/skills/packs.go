@@ -0,0 +1,344 @@
+package skills
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PackManifest is the manifest.json (or pack.json) at the root of a pack
+// folder.
+type PackManifest struct {
+	ID            string `json:"id"`
+	Name          string `json:"name,omitempty"`
+	Version       string `json:"version"`
+	Author        string `json:"author,omitempty"`
+	Description   string `json:"description,omitempty"`
+	MinAppVersion string `json:"minAppVersion,omitempty"`
+	// Permissions declares the elevated permissions skills in the pack may
+	// request; base permissions are always allowed.
+	Permissions []string `json:"permissions,omitempty"`
+	// Skills lists skill files relative to the pack folder. Empty means every
+	// skill JSON file in the folder.
+	Skills     []string      `json:"skills,omitempty"`
+	Categories []CategoryDef `json:"categories,omitempty"`
+}
+
+// Pack is a loaded pack and the skills it contributed.
+type Pack struct {
+	Manifest PackManifest `json:"manifest"`
+	Source   SkillSource  `json:"source"`
+	// Dir is the on-disk pack folder (empty for embedded packs).
+	Dir      string   `json:"dir,omitempty"`
+	Enabled  bool     `json:"enabled"`
+	SkillIDs []string `json:"skillIds"`
+	// Verification is the signature status of the pack's files and
+	// Publisher the signer's name.
+	Verification Verification `json:"verification"`
+	Publisher    string       `json:"publisher,omitempty"`
+	PublisherKey string       `json:"publisherKey,omitempty"`
+	// ContentHash is the sha256 of the pack's PackDigest; pack trust
+	// approvals are pinned to it.
+	ContentHash string `json:"contentHash,omitempty"`
+	// Error explains why the pack's skills were not loaded.
+	Error string `json:"error,omitempty"`
+}
+
+var packIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// PackSkillID namespaces a skill ID with the ID of the pack it ships in.
+func PackSkillID(packID string, skillID string) string {
+	return packID + "/" + skillID
+}
+
+func isPackManifestFilename(name string) bool {
+	lower := strings.ToLower(name)
+	return lower == "manifest.json" || lower == "pack.json"
+}
+
+// packManifestPath returns the manifest inside dir when dir is a pack.
+func packManifestPath(fsys fs.FS, dir string) (string, bool) {
+	for _, name := range []string{"manifest.json", "pack.json"} {
+		p := joinFS(dir, name)
+		if info, err := fs.Stat(fsys, p); err == nil && !info.IsDir() {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+func joinFS(dir string, name string) string {
+	if dir == "." || dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
+func (l *Loader) packDisabled(id string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.disabledPacks[id]
+}
+
+// SetDisabledPacks replaces the set of disabled pack IDs. Call LoadAll to
+// apply it.
+func (l *Loader) SetDisabledPacks(ids []string) {
+	disabled := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		disabled[id] = true
+	}
+	l.mu.Lock()
+	l.disabledPacks = disabled
+	l.mu.Unlock()
+}
+
+// SetTrustedPublishers replaces the keyring packs are verified against. Call
+// LoadAll to apply it.
+func (l *Loader) SetTrustedPublishers(publishers []Publisher) {
+	l.mu.Lock()
+	l.publishers = append([]Publisher(nil), publishers...)
+	l.mu.Unlock()
+}
+
+func (l *Loader) trustedPublishers() []Publisher {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.publishers
+}
+
+// Packs returns the packs found by the last LoadAll, including disabled and
+// broken ones.
+func (l *Loader) Packs() []Pack {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make([]Pack, 0, len(l.packs))
+	for _, p := range l.packs {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Manifest.ID < out[j].Manifest.ID })
+	return out
+}
+
+// collectPack loads the pack rooted at dir into loaded. The pack is always
+// recorded so it can be listed; its skills only when it is enabled and valid.
+func (l *Loader) collectPack(fsys fs.FS, dir string, manifestPath string, diskRoot string, source SkillSource, loaded loadResult) error {
+	b, err := fs.ReadFile(fsys, manifestPath)
+	if err != nil {
+		return err
+	}
+	manifest, err := ParsePackManifest(b)
+	if err != nil {
+		return fmt.Errorf("skills: invalid pack %s: %w", definitionPath(diskRoot, manifestPath), err)
+	}
+
+	pack := Pack{
+		Manifest: manifest,
+		Source:   source,
+		Enabled:  !l.packDisabled(manifest.ID),
+	}
+	if diskRoot != "" {
+		pack.Dir = definitionPath(diskRoot, dir)
+	}
+	defer func() {
+		loaded.packs[manifest.ID] = pack
+	}()
+
+	// Core packs ship inside the app and are verified with it.
+	pack.Verification = VerificationVerified
+	var verifyErr error
+	if source == SkillSourceCommunity {
+		if digest, err := PackDigest(fsys, dir); err == nil {
+			pack.ContentHash = HashDefinition(digest)
+		}
+		if verifyErr = verifyPack(fsys, dir, l.trustedPublishers(), &pack); verifyErr != nil {
+			verifyErr = fmt.Errorf("skills: pack %s is tampered: %w", manifest.ID, verifyErr)
+		}
+	}
+
+	if v := strings.TrimSpace(l.opts.AppVersion); v != "" && manifest.MinAppVersion != "" && CompareVersions(v, manifest.MinAppVersion) < 0 {
+		pack.Error = fmt.Sprintf("requires app version %s or newer", manifest.MinAppVersion)
+		return nil
+	}
+	if !pack.Enabled {
+		return nil
+	}
+
+	files, err := packSkillFiles(fsys, dir, manifest)
+	if err != nil {
+		pack.Error = err.Error()
+		return fmt.Errorf("skills: pack %s: %w", manifest.ID, err)
+	}
+
+	packSkills := make(map[string]Skill, len(files))
+	var errOut error
+	for _, f := range files {
+		if isCategoriesFilename(f[strings.LastIndex(f, "/")+1:]) {
+			b, err := fs.ReadFile(fsys, f)
+			if err == nil {
+				err = parseCategories(b, f, loaded)
+			}
+			errOut = joinErr(errOut, err)
+			continue
+		}
+		s, err := readSkill(fsys, f, definitionPath(diskRoot, f), source)
+		if err != nil {
+			errOut = joinErr(errOut, err)
+			continue
+		}
+		if extra := permissionsOutside(s.Permissions, manifest.Permissions); len(extra) > 0 {
+			errOut = joinErr(errOut, fmt.Errorf("skills: pack %s: skill %s requests undeclared permissions: %s", manifest.ID, s.ID, strings.Join(extra, ", ")))
+			continue
+		}
+		packSkills[s.ID] = s
+	}
+
+	for _, c := range manifest.Categories {
+		id := strings.ToLower(strings.TrimSpace(c.ID))
+		if id == "" {
+			continue
+		}
+		c.ID = id
+		loaded.categories[id] = c
+	}
+
+	for localID, s := range packSkills {
+		s.ID = PackSkillID(manifest.ID, localID)
+		s.PackID = manifest.ID
+		if source == SkillSourceCommunity {
+			s.Verification = pack.Verification
+		}
+		// Pipeline steps may refer to siblings by their local ID.
+		if len(s.Executor.Steps) > 0 {
+			steps := make([]PipelineStep, len(s.Executor.Steps))
+			for i, step := range s.Executor.Steps {
+				if _, ok := packSkills[step.SkillID]; ok {
+					step.SkillID = PackSkillID(manifest.ID, step.SkillID)
+				}
+				steps[i] = step
+			}
+			s.Executor.Steps = steps
+		}
+		loaded.skills[s.ID] = s
+		pack.SkillIDs = append(pack.SkillIDs, s.ID)
+	}
+	sort.Strings(pack.SkillIDs)
+	return joinErr(verifyErr, errOut)
+}
+
+// ParsePackManifest parses and validates a pack manifest.
+func ParsePackManifest(b []byte) (PackManifest, error) {
+	var manifest PackManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return PackManifest{}, err
+	}
+	manifest.ID = strings.TrimSpace(manifest.ID)
+	manifest.Permissions = NormalizePermissions(manifest.Permissions)
+	if err := validateManifest(manifest); err != nil {
+		return PackManifest{}, err
+	}
+	return manifest, nil
+}
+
+func validateManifest(m PackManifest) error {
+	if m.ID == "" {
+		return fmt.Errorf("missing id")
+	}
+	if !packIDPattern.MatchString(m.ID) {
+		return fmt.Errorf("id %q must be lowercase letters, digits, '.', '_' or '-'", m.ID)
+	}
+	if strings.TrimSpace(m.Version) == "" {
+		return fmt.Errorf("missing version")
+	}
+	return nil
+}
+
+// packSkillFiles returns the skill files of a pack: the listed ones, or every
+// skill and category file below dir.
+func packSkillFiles(fsys fs.FS, dir string, manifest PackManifest) ([]string, error) {
+	if len(manifest.Skills) > 0 {
+		out := make([]string, 0, len(manifest.Skills))
+		for _, rel := range manifest.Skills {
+			clean := strings.TrimPrefix(strings.ReplaceAll(rel, "\\", "/"), "./")
+			if clean == "" || strings.HasPrefix(clean, "/") || strings.Contains(clean, "..") {
+				return nil, fmt.Errorf("invalid skill path %q", rel)
+			}
+			out = append(out, joinFS(dir, clean))
+		}
+		return out, nil
+	}
+	var out []string
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if isCategoriesFilename(d.Name()) || isSkillJSONFilename(d.Name()) {
+			out = append(out, p)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// permissionsOutside returns the non-base permissions in perms that allowed
+// lacks.
+func permissionsOutside(perms []string, allowed []string) []string {
+	var out []string
+	for _, p := range perms {
+		if IsBasePermission(p) {
+			continue
+		}
+		found := false
+		for _, a := range allowed {
+			if a == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// CompareVersions compares dotted numeric versions ("1.2", "1.10.0"),
+// ignoring any pre-release suffix. It returns -1, 0 or 1.
+func CompareVersions(a string, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var out []int
+	for _, part := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(part)
+		out = append(out, n)
+	}
+	return out
+}
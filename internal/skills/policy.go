@@ -0,0 +1,225 @@
+package skills
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Policy is an administrator's policy.json. Deny rules win over allow rules;
+// a non-empty allow list admits only what it matches. Patterns use
+// path.Match syntax ("acme.audio/*").
+type Policy struct {
+	Skills RuleSet `json:"skills"`
+	Packs  RuleSet `json:"packs"`
+	// Publishers match a pack's signing key, or its publisher name when the
+	// signature is verified.
+	Publishers RuleSet `json:"publishers"`
+	// Permissions rules apply to elevated permissions; base permissions are
+	// always allowed.
+	Permissions RuleSet `json:"permissions"`
+	// Commands match the executable name of CLI skills.
+	Commands RuleSet `json:"commands"`
+	// AllowedCommands replaces the CLI allowlist for community skills without
+	// tools.exec.any. In a user policy it can only narrow the allowlist; an
+	// empty, non-nil list allows no commands.
+	AllowedCommands []string `json:"allowedCommands,omitempty"`
+	MaxDangerLevel  *int     `json:"maxDangerLevel,omitempty"`
+
+	// CoreOnly admits only core skills and packs. It is how an unreadable
+	// system policy fails closed (see LockdownPolicy).
+	CoreOnly bool `json:"coreOnly,omitempty"`
+}
+
+// DefaultAllowedCommands is the CLI allowlist for community skills without
+// tools.exec.any when no policy sets one.
+var DefaultAllowedCommands = []string{"ffmpeg", "magick", "convert", "identify"}
+
+// LockdownPolicy is used in place of a system policy that exists but can't
+// be read: only core skills load and no elevated permission is granted.
+func LockdownPolicy() Policy {
+	return Policy{
+		Permissions:     RuleSet{Deny: []string{"*"}},
+		AllowedCommands: []string{},
+		CoreOnly:        true,
+	}
+}
+
+type RuleSet struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+
+	// userAllow is a user policy's allow list merged under a system one; a
+	// value must pass both.
+	userAllow []string
+}
+
+// BlockedSkill is a skill the policy kept out of the registry.
+type BlockedSkill struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	PackID string `json:"packId,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// permits reports whether value passes the rule set; values is every name
+// the subject goes by.
+func (r RuleSet) permits(values ...string) bool {
+	for _, v := range values {
+		if matchAny(r.Deny, v) {
+			return false
+		}
+	}
+	return allowedBy(r.Allow, values) && allowedBy(r.userAllow, values)
+}
+
+// allowedBy reports whether an allow list admits one of values; an empty
+// list admits everything.
+func allowedBy(allow []string, values []string) bool {
+	if len(allow) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if matchAny(allow, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RuleSet) isZero() bool {
+	return len(r.Allow) == 0 && len(r.Deny) == 0 && len(r.userAllow) == 0
+}
+
+func matchAny(patterns []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), strings.ToLower(value)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge combines a system-wide policy with a user one. The result is at
+// least as strict as either: deny lists are joined, a value must pass both
+// allow lists, the user's command allowlist only removes commands from
+// the system (or default) one, and the lower danger cap wins.
+func (p Policy) Merge(user Policy) Policy {
+	merged := Policy{
+		Skills:          mergeRules(p.Skills, user.Skills),
+		Packs:           mergeRules(p.Packs, user.Packs),
+		Publishers:      mergeRules(p.Publishers, user.Publishers),
+		Permissions:     mergeRules(p.Permissions, user.Permissions),
+		Commands:        mergeRules(p.Commands, user.Commands),
+		AllowedCommands: p.AllowedCommands,
+		MaxDangerLevel:  p.MaxDangerLevel,
+		CoreOnly:        p.CoreOnly || user.CoreOnly,
+	}
+	if user.AllowedCommands != nil {
+		base := p.AllowedCommands
+		if base == nil {
+			base = DefaultAllowedCommands
+		}
+		merged.AllowedCommands = []string{}
+		for _, c := range user.AllowedCommands {
+			if containsFold(base, c) {
+				merged.AllowedCommands = append(merged.AllowedCommands, c)
+			}
+		}
+	}
+	if user.MaxDangerLevel != nil && (merged.MaxDangerLevel == nil || *user.MaxDangerLevel < *merged.MaxDangerLevel) {
+		merged.MaxDangerLevel = user.MaxDangerLevel
+	}
+	return merged
+}
+
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeRules(system RuleSet, user RuleSet) RuleSet {
+	out := RuleSet{
+		Allow: system.Allow,
+		Deny:  append(append([]string(nil), system.Deny...), user.Deny...),
+	}
+	if len(out.Allow) == 0 {
+		out.Allow = user.Allow
+	} else {
+		out.userAllow = user.Allow
+	}
+	return out
+}
+
+// BlocksPack explains why the policy rejects a pack, or returns "".
+func (p Policy) BlocksPack(pack Pack) string {
+	if p.CoreOnly && pack.Source == SkillSourceCommunity {
+		return fmt.Sprintf("pack %s is not allowed: only core skills are allowed by policy", pack.Manifest.ID)
+	}
+	if !p.Packs.permits(pack.Manifest.ID) {
+		return fmt.Sprintf("pack %s is not allowed by policy", pack.Manifest.ID)
+	}
+	if p.Publishers.isZero() || pack.Source != SkillSourceCommunity {
+		return ""
+	}
+	names := []string{pack.PublisherKey}
+	if pack.Verification == VerificationVerified {
+		names = append(names, pack.Publisher)
+	}
+	if !p.Publishers.permits(names...) {
+		publisher := pack.Publisher
+		if publisher == "" {
+			publisher = "an unsigned publisher"
+		}
+		return fmt.Sprintf("publisher %s of pack %s is not allowed by policy", publisher, pack.Manifest.ID)
+	}
+	return ""
+}
+
+// Blocks explains why the policy rejects a skill, or returns "". pack is the
+// skill's pack, if any.
+func (p Policy) Blocks(skill Skill, pack *Pack) string {
+	if p.CoreOnly && skill.Source == SkillSourceCommunity {
+		return fmt.Sprintf("skill %s is not allowed: only core skills are allowed by policy", skill.ID)
+	}
+	if !p.Skills.permits(skill.ID) {
+		return fmt.Sprintf("skill %s is not allowed by policy", skill.ID)
+	}
+	if pack != nil {
+		if reason := p.BlocksPack(*pack); reason != "" {
+			return reason
+		}
+	} else if skill.Source == SkillSourceCommunity && len(p.Publishers.Allow) > 0 {
+		return fmt.Sprintf("skill %s has no allowed publisher", skill.ID)
+	}
+	for _, perm := range ElevatedPermissions(skill.Permissions) {
+		if !p.Permissions.permits(perm) {
+			return fmt.Sprintf("permission %s is not allowed by policy", perm)
+		}
+	}
+	for _, cmd := range skill.Commands() {
+		if reason := p.BlocksCommand(cmd); reason != "" {
+			return reason
+		}
+	}
+	if p.MaxDangerLevel != nil && skill.DangerLevel > *p.MaxDangerLevel {
+		return fmt.Sprintf("danger level %d exceeds the policy maximum of %d", skill.DangerLevel, *p.MaxDangerLevel)
+	}
+	return ""
+}
+
+// BlocksCommand explains why the policy forbids running command, or returns
+// "".
+func (p Policy) BlocksCommand(command string) string {
+	if base := toolName(command); !p.Commands.permits(base) {
+		return fmt.Sprintf("command %s is not allowed by policy", base)
+	}
+	return ""
+}
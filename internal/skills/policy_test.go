@@ -0,0 +1,159 @@
+package skills
+
+import (
+	"slices"
+	"testing"
+)
+
+func dangerLevel(n int) *int {
+	return &n
+}
+
+func policySkill(id string, source SkillSource, command string, danger int, perms ...string) Skill {
+	skill := Skill{ID: id, Source: source, Permissions: perms, DangerLevel: danger}
+	if command != "" {
+		skill.Executor = Executor{Type: "cli", Command: command}
+	}
+	return skill
+}
+
+func TestPolicyBlocks(t *testing.T) {
+	core := policySkill("core.resize", SkillSourceCoreEmbedded, "", 0)
+	audio := policySkill("acme.audio.normalize", SkillSourceCommunity, "ffmpeg", 1, PermToolsExec)
+	fetch := policySkill("acme.web.fetch", SkillSourceCommunity, "/usr/bin/curl", 3, PermNetwork)
+	pack := &Pack{
+		Manifest:     PackManifest{ID: "acme.audio"},
+		Source:       SkillSourceCommunity,
+		Verification: VerificationVerified,
+		Publisher:    "Acme",
+		PublisherKey: "key-acme",
+	}
+	unverified := *pack
+	unverified.Verification = VerificationUnverified
+
+	tests := []struct {
+		name    string
+		policy  Policy
+		skill   Skill
+		pack    *Pack
+		blocked bool
+	}{
+		{"empty policy", Policy{}, fetch, nil, false},
+		{"core only keeps core", Policy{CoreOnly: true}, core, nil, false},
+		{"core only blocks community", Policy{CoreOnly: true}, audio, pack, true},
+		{"skill deny pattern", Policy{Skills: RuleSet{Deny: []string{"acme.*"}}}, audio, nil, true},
+		{"skill deny is case insensitive", Policy{Skills: RuleSet{Deny: []string{"ACME.AUDIO.*"}}}, audio, nil, true},
+		{"skill allow list admits", Policy{Skills: RuleSet{Allow: []string{"acme.audio.*"}}}, audio, nil, false},
+		{"skill allow list excludes", Policy{Skills: RuleSet{Allow: []string{"acme.audio.*"}}}, fetch, nil, true},
+		{"deny wins over allow", Policy{Skills: RuleSet{Allow: []string{"acme.*"}, Deny: []string{"acme.web.*"}}}, fetch, nil, true},
+		{"pack denied", Policy{Packs: RuleSet{Deny: []string{"acme.audio"}}}, audio, pack, true},
+		{"publisher name when verified", Policy{Publishers: RuleSet{Allow: []string{"Acme"}}}, audio, pack, false},
+		{"publisher name needs a verified signature", Policy{Publishers: RuleSet{Allow: []string{"Acme"}}}, audio, &unverified, true},
+		{"publisher key when unverified", Policy{Publishers: RuleSet{Allow: []string{"key-acme"}}}, audio, &unverified, false},
+		{"publisher allow list without a pack", Policy{Publishers: RuleSet{Allow: []string{"Acme"}}}, fetch, nil, true},
+		{"publisher rules skip core", Policy{Publishers: RuleSet{Allow: []string{"Acme"}}}, core, nil, false},
+		{"elevated permission denied", Policy{Permissions: RuleSet{Deny: []string{PermNetwork}}}, fetch, nil, true},
+		{"base permissions always allowed", Policy{Permissions: RuleSet{Allow: []string{PermNetwork}}}, audio, nil, false},
+		{"lockdown denies elevated", LockdownPolicy(), core, nil, false},
+		{"command denied by name", Policy{Commands: RuleSet{Deny: []string{"curl"}}}, fetch, nil, true},
+		{"command allow list", Policy{Commands: RuleSet{Allow: []string{"ffmpeg"}}}, fetch, nil, true},
+		{"danger level over the cap", Policy{MaxDangerLevel: dangerLevel(2)}, fetch, nil, true},
+		{"danger level at the cap", Policy{MaxDangerLevel: dangerLevel(3)}, fetch, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := tt.policy.Blocks(tt.skill, tt.pack)
+			if (reason != "") != tt.blocked {
+				t.Errorf("Blocks = %q, want blocked %v", reason, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestPolicyMergeAllowedCommands(t *testing.T) {
+	tests := []struct {
+		name   string
+		system []string
+		user   []string
+		want   []string
+	}{
+		{"neither sets one", nil, nil, nil},
+		{"system only", []string{"ffmpeg", "sox"}, nil, []string{"ffmpeg", "sox"}},
+		{"user narrows the default", nil, []string{"ffmpeg"}, []string{"ffmpeg"}},
+		{"user can't add to the default", nil, []string{"ffmpeg", "curl"}, []string{"ffmpeg"}},
+		{"user narrows the system list", []string{"ffmpeg", "sox"}, []string{"SOX", "magick"}, []string{"SOX"}},
+		{"user empty list allows none", []string{"ffmpeg"}, []string{}, []string{}},
+		{"system empty list stays empty", []string{}, []string{"ffmpeg"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Policy{AllowedCommands: tt.system}.Merge(Policy{AllowedCommands: tt.user}).AllowedCommands
+			if !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("AllowedCommands = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyMergeDangerLevel(t *testing.T) {
+	tests := []struct {
+		system, user *int
+		want         *int
+	}{
+		{nil, nil, nil},
+		{dangerLevel(2), nil, dangerLevel(2)},
+		{nil, dangerLevel(1), dangerLevel(1)},
+		{dangerLevel(2), dangerLevel(3), dangerLevel(2)},
+		{dangerLevel(2), dangerLevel(0), dangerLevel(0)},
+	}
+	for _, tt := range tests {
+		got := Policy{MaxDangerLevel: tt.system}.Merge(Policy{MaxDangerLevel: tt.user}).MaxDangerLevel
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("Merge(%v, %v) danger = %v, want %v", tt.system, tt.user, got, tt.want)
+		}
+	}
+}
+
+// TestPolicyMergeStrictest checks that whatever either policy blocks, the
+// merged one blocks too.
+func TestPolicyMergeStrictest(t *testing.T) {
+	policies := []Policy{
+		{},
+		LockdownPolicy(),
+		{Skills: RuleSet{Allow: []string{"acme.*"}}},
+		{Skills: RuleSet{Allow: []string{"acme.audio.*"}}},
+		{Skills: RuleSet{Allow: []string{"other.*"}, Deny: []string{"acme.web.*"}}},
+		{Packs: RuleSet{Deny: []string{"acme.audio"}}},
+		{Publishers: RuleSet{Allow: []string{"Acme"}}},
+		{Publishers: RuleSet{Allow: []string{"key-other"}}},
+		{Permissions: RuleSet{Deny: []string{PermNetwork}}},
+		{Permissions: RuleSet{Allow: []string{PermFilesAnywhere}}},
+		{Commands: RuleSet{Allow: []string{"ffmpeg"}}},
+		{Commands: RuleSet{Deny: []string{"ffmpeg"}}},
+		{MaxDangerLevel: dangerLevel(1)},
+		{CoreOnly: true},
+	}
+	pack := &Pack{Manifest: PackManifest{ID: "acme.audio"}, Source: SkillSourceCommunity, Verification: VerificationVerified, Publisher: "Acme", PublisherKey: "key-acme"}
+	subjects := []struct {
+		skill Skill
+		pack  *Pack
+	}{
+		{policySkill("core.resize", SkillSourceCoreEmbedded, "magick", 0), nil},
+		{policySkill("acme.audio.normalize", SkillSourceCommunity, "ffmpeg", 1, PermToolsExec), pack},
+		{policySkill("acme.web.fetch", SkillSourceCommunity, "curl", 3, PermNetwork), nil},
+		{policySkill("other.move", SkillSourceCommunity, "mv", 2, PermFilesAnywhere), nil},
+	}
+	for i, system := range policies {
+		for j, user := range policies {
+			merged := system.Merge(user)
+			for _, s := range subjects {
+				if system.Blocks(s.skill, s.pack) == "" && user.Blocks(s.skill, s.pack) == "" {
+					continue
+				}
+				if merged.Blocks(s.skill, s.pack) == "" {
+					t.Errorf("system %d + user %d admit %s, which one of them blocks", i, j, s.skill.ID)
+				}
+			}
+		}
+	}
+}
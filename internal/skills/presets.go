@@ -0,0 +1,151 @@
+package skills
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// maxLearnedPresets caps how many learned values are offered per param.
+	maxLearnedPresets = 4
+	// minLearnedCount is how often an unpinned value must be used before it
+	// is offered as a preset.
+	minLearnedCount = 2
+)
+
+// ParamValueStats tracks how often a parameter value was used.
+type ParamValueStats struct {
+	Value    any       `json:"value"`
+	Count    int       `json:"count"`
+	LastUsed time.Time `json:"lastUsed"`
+	Pinned   bool      `json:"pinned,omitempty"`
+}
+
+// ParamUsage maps a param name to its used values, keyed by ParamValueKey.
+type ParamUsage map[string]map[string]ParamValueStats
+
+// ParamPreset is a preset offered for Tab-cycling, learned or declared.
+type ParamPreset struct {
+	Value   any    `json:"value"`
+	Label   string `json:"label,omitempty"`
+	Learned bool   `json:"learned,omitempty"`
+	Pinned  bool   `json:"pinned,omitempty"`
+}
+
+// ParamValueKey returns the canonical key for a param value so 82 and 82.0
+// from the frontend are counted together.
+func ParamValueKey(value any) string {
+	return fmt.Sprint(value)
+}
+
+// ParamPresets returns the presets for each param of skillID: pinned values
+// first, then learned values by frecency, then the static ParamDef presets.
+func (r *Registry) ParamPresets(skillID string, learned ParamUsage) map[string][]ParamPreset {
+	skill, ok := r.GetByID(skillID)
+	if !ok {
+		return nil
+	}
+	ranker := r.currentRanker()
+	out := make(map[string][]ParamPreset, len(skill.Params))
+	for _, param := range skill.Params {
+		seen := make(map[string]struct{})
+		presets := make([]ParamPreset, 0, len(param.Presets)+maxLearnedPresets)
+		for _, stat := range ranker.preferredValues(learned[param.Name]) {
+			seen[ParamValueKey(stat.Value)] = struct{}{}
+			presets = append(presets, ParamPreset{Value: stat.Value, Learned: true, Pinned: stat.Pinned})
+		}
+		for _, raw := range param.Presets {
+			preset := staticPreset(raw)
+			key := ParamValueKey(preset.Value)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			presets = append(presets, preset)
+		}
+		out[param.Name] = presets
+	}
+	return out
+}
+
+// preferredValues orders used values with pinned ones first and the rest by
+// decayed frequency, dropping one-off values.
+func (r *Ranker) preferredValues(values map[string]ParamValueStats) []ParamValueStats {
+	type scored struct {
+		stat  ParamValueStats
+		score float64
+	}
+	candidates := make([]scored, 0, len(values))
+	for _, stat := range values {
+		if !stat.Pinned && stat.Count < minLearnedCount {
+			continue
+		}
+		candidates = append(candidates, scored{stat: stat, score: r.paramValueScore(stat)})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].stat.Pinned != candidates[j].stat.Pinned {
+			return candidates[i].stat.Pinned
+		}
+		if candidates[i].score == candidates[j].score {
+			return candidates[i].stat.LastUsed.After(candidates[j].stat.LastUsed)
+		}
+		return candidates[i].score > candidates[j].score
+	})
+	out := make([]ParamValueStats, 0, maxLearnedPresets)
+	for _, c := range candidates {
+		if !c.stat.Pinned && len(out) >= maxLearnedPresets {
+			break
+		}
+		out = append(out, c.stat)
+	}
+	return out
+}
+
+// PruneParamValues drops the unpinned values of one param beyond keep,
+// lowest frecency first, so a param fed ever-changing values doesn't grow the
+// usage file without bound. Pinned values are always kept.
+func (r *Ranker) PruneParamValues(values map[string]ParamValueStats, keep int) {
+	type scored struct {
+		key   string
+		stat  ParamValueStats
+		score float64
+	}
+	candidates := make([]scored, 0, len(values))
+	for key, stat := range values {
+		if !stat.Pinned {
+			candidates = append(candidates, scored{key: key, stat: stat, score: r.paramValueScore(stat)})
+		}
+	}
+	if len(candidates) <= keep {
+		return
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score == candidates[j].score {
+			return candidates[i].stat.LastUsed.After(candidates[j].stat.LastUsed)
+		}
+		return candidates[i].score > candidates[j].score
+	})
+	for _, c := range candidates[max(keep, 0):] {
+		delete(values, c.key)
+	}
+}
+
+func (r *Ranker) paramValueScore(stat ParamValueStats) float64 {
+	return float64(stat.Count) * r.decay(r.ageInDays(stat.LastUsed))
+}
+
+// staticPreset accepts both plain preset values and {"label", "value"} objects.
+func staticPreset(raw any) ParamPreset {
+	if m, ok := raw.(map[string]any); ok {
+		preset := ParamPreset{Value: m["value"]}
+		if label, ok := m["label"].(string); ok {
+			preset.Label = label
+		}
+		if preset.Value == nil {
+			preset.Value = preset.Label
+		}
+		return preset
+	}
+	return ParamPreset{Value: raw}
+}
@@ -0,0 +1,112 @@
+package skills
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// ProgressSpec tells the CLI driver how to read a command's progress.
+type ProgressSpec struct {
+	// Type is "regex" (lines matching Pattern) or "keyvalue" (a key=value
+	// stream such as ffmpeg's -progress output, read at Key).
+	Type string `json:"type"`
+	// Stream is "stderr" (default) or "stdout".
+	Stream string `json:"stream,omitempty"`
+	// Pattern's first group captures the current value and an optional
+	// second group the total ("frame (\d+)/(\d+)").
+	Pattern string `json:"pattern,omitempty"`
+	Key     string `json:"key,omitempty"`
+	// Unit is how values are read: "number" (default), "percent",
+	// "duration" (hh:mm:ss.ff or seconds), "milliseconds" or
+	// "microseconds"; time units are compared in seconds.
+	Unit string `json:"unit,omitempty"`
+
+	// The total is Total, the first group of TotalPattern on the same
+	// stream (ffmpeg's "Duration: ..."), or the output of TotalProbe.
+	// Percent values need none.
+	Total        float64        `json:"total,omitempty"`
+	TotalPattern string         `json:"totalPattern,omitempty"`
+	TotalProbe   *ProgressProbe `json:"totalProbe,omitempty"`
+}
+
+// ProgressProbe is a command run before the skill to find the total, such
+// as ffprobe printing the input's duration.
+type ProgressProbe struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	// Pattern's first group captures the total (default: the first number).
+	Pattern string `json:"pattern,omitempty"`
+	Unit    string `json:"unit,omitempty"`
+}
+
+func validateProgress(s Skill) error {
+	p := s.Executor.Progress
+	if p == nil {
+		return nil
+	}
+	switch p.Type {
+	case "regex":
+		if err := validatePattern(p.Pattern, true); err != nil {
+			return fmt.Errorf("progress.pattern: %w", err)
+		}
+	case "keyvalue":
+		if p.Key == "" {
+			return fmt.Errorf("progress.key is required for keyvalue progress")
+		}
+	default:
+		return fmt.Errorf("progress.type must be regex or keyvalue")
+	}
+	switch p.Stream {
+	case "", "stderr":
+	case "stdout":
+		if s.Executor.Stdout {
+			return fmt.Errorf("progress cannot read stdout when it is the output")
+		}
+	default:
+		return fmt.Errorf("progress.stream must be stderr or stdout")
+	}
+	if err := validateUnit(p.Unit); err != nil {
+		return fmt.Errorf("progress.unit: %w", err)
+	}
+	if p.TotalPattern != "" {
+		if err := validatePattern(p.TotalPattern, true); err != nil {
+			return fmt.Errorf("progress.totalPattern: %w", err)
+		}
+	}
+	if probe := p.TotalProbe; probe != nil {
+		if probe.Command == "" {
+			return fmt.Errorf("progress.totalProbe.command is required")
+		}
+		if _, err := ParseArgs(probe.Args, s.Params); err != nil {
+			return fmt.Errorf("progress.totalProbe: %w", err)
+		}
+		if probe.Pattern != "" {
+			if err := validatePattern(probe.Pattern, true); err != nil {
+				return fmt.Errorf("progress.totalProbe.pattern: %w", err)
+			}
+		}
+		if err := validateUnit(probe.Unit); err != nil {
+			return fmt.Errorf("progress.totalProbe.unit: %w", err)
+		}
+	}
+	return nil
+}
+
+func validatePattern(pattern string, group bool) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	if group && re.NumSubexp() < 1 {
+		return fmt.Errorf("%q needs a capture group", pattern)
+	}
+	return nil
+}
+
+func validateUnit(unit string) error {
+	switch unit {
+	case "", "number", "percent", "duration", "milliseconds", "microseconds":
+		return nil
+	}
+	return fmt.Errorf("unknown unit %q", unit)
+}
@@ -5,6 +5,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"asteria/internal/filetype"
 )
 
 type UsageStats struct {
@@ -12,13 +14,76 @@ type UsageStats struct {
 	LastUsed time.Time `json:"lastUsed"`
 }
 
+// ChainStats records which skills followed which. The outer key is built by
+// TransitionKey; the inner map is keyed by the skill that was applied next.
+type ChainStats map[string]map[string]UsageStats
+
+// ChainContext describes the step that was just applied so the ranker can
+// suggest what usually comes next.
+type ChainContext struct {
+	PreviousSkillID string
+	InputExt        string
+	Transitions     ChainStats
+}
+
+// ChainInputExt returns the extension a chain is narrowed to: the only
+// extension among inputTypes, which may also list MIME types, or "".
+func ChainInputExt(inputTypes []string) string {
+	ext := ""
+	for _, t := range inputTypes {
+		if strings.Contains(t, "/") {
+			continue
+		}
+		if ext != "" {
+			return ""
+		}
+		ext = t
+	}
+	return ext
+}
+
+// TransitionKey returns the ChainStats key for a previous skill, optionally
+// narrowed to the extension the next skill was applied to. An empty
+// previous skill ID stands for the first step on a file.
+func TransitionKey(previousSkillID string, inputExt string) string {
+	ext := strings.ToLower(strings.TrimSpace(inputExt))
+	if ext == "" {
+		return previousSkillID
+	}
+	return ext + "|" + previousSkillID
+}
+
 type Ranker struct {
 	HalfLifeDays           float64
 	RecentBoost            float64
 	AdaptiveLearningWeight float64
 	InputMatchBoost        float64
 	AliasMatchBoost        float64
-	BaseCategoryBoost      map[string]float64
+	NextStepWeight         float64
+	// DefaultCategoryBoost applies to categories nobody declared.
+	DefaultCategoryBoost float64
+	BaseCategoryBoost    map[string]float64
+	// Categories are declared by skill packs; their Priority wins over
+	// BaseCategoryBoost.
+	Categories map[string]CategoryDef
+	// CategoryOverrides come from user settings and win over everything.
+	CategoryOverrides map[string]float64
+	// Now is the clock used for decay; nil means time.Now. Replays set it to
+	// the time of the recorded event.
+	Now func() time.Time
+}
+
+// RankerOverrides is the user-settings view of the ranker weights. Nil
+// fields keep the default.
+type RankerOverrides struct {
+	HalfLifeDays           *float64           `json:"halfLifeDays,omitempty"`
+	RecentBoost            *float64           `json:"recentBoost,omitempty"`
+	AdaptiveLearningWeight *float64           `json:"adaptiveLearningWeight,omitempty"`
+	InputMatchBoost        *float64           `json:"inputMatchBoost,omitempty"`
+	AliasMatchBoost        *float64           `json:"aliasMatchBoost,omitempty"`
+	NextStepWeight         *float64           `json:"nextStepWeight,omitempty"`
+	DefaultCategoryBoost   *float64           `json:"defaultCategoryBoost,omitempty"`
+	CategoryBoost          map[string]float64 `json:"categoryBoost,omitempty"`
 }
 
 func DefaultRanker() *Ranker {
@@ -28,6 +93,8 @@ func DefaultRanker() *Ranker {
 		AdaptiveLearningWeight: 400,
 		InputMatchBoost:        600,
 		AliasMatchBoost:        500,
+		NextStepWeight:         900,
+		DefaultCategoryBoost:   500,
 		BaseCategoryBoost: map[string]float64{
 			"convert":   800,
 			"transform": 700,
@@ -38,49 +105,130 @@ func DefaultRanker() *Ranker {
 	}
 }
 
-func (r *Ranker) Rank(skills []Skill, query string, inputTypes []string, usage map[string]UsageStats) []Skill {
-	type scored struct {
-		skill Skill
-		score float64
+// ScoreBreakdown is the per-component score of a ranked skill. Total is the
+// sum of all components and is what results are ordered by.
+type ScoreBreakdown struct {
+	Category   float64 `json:"category"`
+	Meta       float64 `json:"meta"`
+	Fuzzy      float64 `json:"fuzzy"`
+	Alias      float64 `json:"alias"`
+	InputMatch float64 `json:"inputMatch"`
+	Frecency   float64 `json:"frecency"`
+	NextStep   float64 `json:"nextStep"`
+	Total      float64 `json:"total"`
+}
+
+// ExplainedSkill pairs a ranked skill with the reason for its position.
+type ExplainedSkill struct {
+	Skill Skill          `json:"skill"`
+	Score ScoreBreakdown `json:"score"`
+	// Pinned is set when the user's overlay moved the skill to the top.
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+// WithOverrides returns a copy of r with the non-nil overrides applied.
+func (r *Ranker) WithOverrides(o RankerOverrides) *Ranker {
+	out := r.clone()
+	apply := func(dst *float64, v *float64) {
+		if v != nil {
+			*dst = *v
+		}
+	}
+	apply(&out.HalfLifeDays, o.HalfLifeDays)
+	apply(&out.RecentBoost, o.RecentBoost)
+	apply(&out.AdaptiveLearningWeight, o.AdaptiveLearningWeight)
+	apply(&out.InputMatchBoost, o.InputMatchBoost)
+	apply(&out.AliasMatchBoost, o.AliasMatchBoost)
+	apply(&out.NextStepWeight, o.NextStepWeight)
+	apply(&out.DefaultCategoryBoost, o.DefaultCategoryBoost)
+	if len(o.CategoryBoost) > 0 {
+		overrides := make(map[string]float64, len(out.CategoryOverrides)+len(o.CategoryBoost))
+		for k, v := range out.CategoryOverrides {
+			overrides[k] = v
+		}
+		for k, v := range o.CategoryBoost {
+			overrides[strings.ToLower(k)] = v
+		}
+		out.CategoryOverrides = overrides
 	}
-	scoredSkills := make([]scored, 0, len(skills))
+	return out
+}
+
+// WithCategories returns a copy of r that knows the declared categories.
+func (r *Ranker) WithCategories(categories map[string]CategoryDef) *Ranker {
+	out := r.clone()
+	out.Categories = categories
+	return out
+}
+
+func (r *Ranker) clone() *Ranker {
+	out := *r
+	return &out
+}
+
+func (r *Ranker) categoryBoost(category string) float64 {
+	key := strings.ToLower(strings.TrimSpace(category))
+	if v, ok := r.CategoryOverrides[key]; ok {
+		return v
+	}
+	if c, ok := r.Categories[key]; ok {
+		return c.Priority
+	}
+	if v, ok := r.BaseCategoryBoost[key]; ok {
+		return v
+	}
+	return r.DefaultCategoryBoost
+}
+
+func (r *Ranker) Rank(skills []Skill, query string, inputTypes []string, usage map[string]UsageStats, chain ChainContext) []Skill {
+	explained := r.RankExplained(skills, query, inputTypes, usage, chain)
+	ranked := make([]Skill, 0, len(explained))
+	for _, item := range explained {
+		ranked = append(ranked, item.Skill)
+	}
+	return ranked
+}
+
+// RankExplained orders skills like Rank and keeps each score breakdown.
+func (r *Ranker) RankExplained(skills []Skill, query string, inputTypes []string, usage map[string]UsageStats, chain ChainContext) []ExplainedSkill {
+	scoredSkills := make([]ExplainedSkill, 0, len(skills))
 	for _, skill := range skills {
-		score := r.scoreSkill(skill, query, inputTypes, usage)
-		scoredSkills = append(scoredSkills, scored{skill: skill, score: score})
+		score := r.scoreSkill(skill, query, inputTypes, usage, chain)
+		scoredSkills = append(scoredSkills, ExplainedSkill{Skill: skill, Score: score})
 	}
 	sort.SliceStable(scoredSkills, func(i, j int) bool {
-		if scoredSkills[i].score == scoredSkills[j].score {
-			return scoredSkills[i].skill.Name < scoredSkills[j].skill.Name
+		if scoredSkills[i].Score.Total == scoredSkills[j].Score.Total {
+			return scoredSkills[i].Skill.Name < scoredSkills[j].Skill.Name
 		}
-		return scoredSkills[i].score > scoredSkills[j].score
+		return scoredSkills[i].Score.Total > scoredSkills[j].Score.Total
 	})
-	ranked := make([]Skill, 0, len(scoredSkills))
-	for _, item := range scoredSkills {
-		ranked = append(ranked, item.skill)
-	}
-	return ranked
+	return scoredSkills
 }
 
-func (r *Ranker) scoreSkill(skill Skill, query string, inputTypes []string, usage map[string]UsageStats) float64 {
-	base := r.BaseCategoryBoost[skill.Category]
+func (r *Ranker) scoreSkill(skill Skill, query string, inputTypes []string, usage map[string]UsageStats, chain ChainContext) ScoreBreakdown {
+	var b ScoreBreakdown
+	b.Category = r.categoryBoost(skill.Category)
 	if skill.IsMeta {
-		base += 150
+		b.Meta = 150
 	}
 
-	matchScore := 0.0
 	if strings.TrimSpace(query) != "" {
-		matchScore = fuzzyScore(skill.Name, query)
+		b.Fuzzy = fuzzyScore(skill.Name, query)
 		if aliasMatch(skill.Aliases, query) {
-			matchScore += r.AliasMatchBoost
+			b.Alias = r.AliasMatchBoost
 		}
 	}
 
 	if inputMatches(skill, inputTypes) {
-		matchScore += r.InputMatchBoost
+		b.InputMatch = r.InputMatchBoost
 	}
 
-	adaptive := r.frecencyBoost(skill.ID, usage)
-	return base + matchScore + adaptive
+	b.Frecency = r.frecencyBoost(skill.ID, usage)
+	if strings.TrimSpace(query) == "" {
+		b.NextStep = r.nextStepBoost(skill.ID, chain)
+	}
+	b.Total = b.Category + b.Meta + b.Fuzzy + b.Alias + b.InputMatch + b.Frecency + b.NextStep
+	return b
 }
 
 func (r *Ranker) frecencyBoost(skillID string, usage map[string]UsageStats) float64 {
@@ -91,13 +239,9 @@ func (r *Ranker) frecencyBoost(skillID string, usage map[string]UsageStats) floa
 	if stat.Count == 0 {
 		return 0
 	}
-	ageDays := time.Since(stat.LastUsed).Hours() / 24
-	if ageDays < 0 {
-		ageDays = 0
-	}
-	lambda := math.Log(2) / r.HalfLifeDays
+	ageDays := r.ageInDays(stat.LastUsed)
 	freq := float64(stat.Count)
-	decay := math.Exp(-lambda * ageDays)
+	decay := r.decay(ageDays)
 	recent := 0.0
 	if ageDays < 1 {
 		recent = r.RecentBoost
@@ -105,8 +249,78 @@ func (r *Ranker) frecencyBoost(skillID string, usage map[string]UsageStats) floa
 	return (freq * r.AdaptiveLearningWeight * decay) + recent
 }
 
+// nextStepBoost blends the decayed probability that skillID follows the
+// previous step, averaging the generic and the per-extension transitions.
+func (r *Ranker) nextStepBoost(skillID string, chain ChainContext) float64 {
+	if r.NextStepWeight == 0 || len(chain.Transitions) == 0 {
+		return 0
+	}
+	keys := make([]string, 0, 2)
+	if chain.PreviousSkillID != "" {
+		keys = append(keys, TransitionKey(chain.PreviousSkillID, ""))
+	}
+	if strings.TrimSpace(chain.InputExt) != "" {
+		keys = append(keys, TransitionKey(chain.PreviousSkillID, chain.InputExt))
+	}
+	total := 0.0
+	n := 0
+	for _, key := range keys {
+		p, ok := r.transitionProbability(chain.Transitions[key], skillID)
+		if !ok {
+			continue
+		}
+		total += p
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return r.NextStepWeight * total / float64(n)
+}
+
+func (r *Ranker) transitionProbability(next map[string]UsageStats, skillID string) (float64, bool) {
+	sum := 0.0
+	hit := 0.0
+	for id, stat := range next {
+		w := float64(stat.Count) * r.decay(r.ageInDays(stat.LastUsed))
+		sum += w
+		if id == skillID {
+			hit = w
+		}
+	}
+	if sum == 0 {
+		return 0, false
+	}
+	return hit / sum, true
+}
+
+// decay applies the exponential half-life decay shared by frecency and
+// transition weights.
+func (r *Ranker) decay(ageDays float64) float64 {
+	if r.HalfLifeDays <= 0 {
+		return 1
+	}
+	lambda := math.Log(2) / r.HalfLifeDays
+	return math.Exp(-lambda * ageDays)
+}
+
+func (r *Ranker) ageInDays(t time.Time) float64 {
+	now := time.Now()
+	if r.Now != nil {
+		now = r.Now()
+	}
+	ageDays := now.Sub(t).Hours() / 24
+	if ageDays < 0 {
+		return 0
+	}
+	return ageDays
+}
+
+// inputMatches reports whether skill accepts any of inputTypes, which are
+// extensions (".png") or detected MIME types ("image/png"). Extensions are
+// also matched against InputMimeTypes through the type they stand for.
 func inputMatches(skill Skill, inputTypes []string) bool {
-	if len(skill.InputTypes) == 0 {
+	if len(skill.InputTypes) == 0 && len(skill.InputMimeTypes) == 0 {
 		return len(inputTypes) == 0
 	}
 	for _, t := range skill.InputTypes {
@@ -118,8 +332,29 @@ func inputMatches(skill Skill, inputTypes []string) bool {
 		return false
 	}
 	for _, t := range inputTypes {
-		for _, supported := range skill.InputTypes {
-			if strings.EqualFold(t, supported) {
+		mimeType := t
+		if strings.Contains(t, "/") {
+			if ft, ok := filetype.ByMIME(t); ok {
+				for _, supported := range skill.InputTypes {
+					if ft.HasExtension(supported) {
+						return true
+					}
+				}
+			}
+		} else {
+			for _, supported := range skill.InputTypes {
+				if strings.EqualFold(t, supported) {
+					return true
+				}
+			}
+			ft, ok := filetype.ByExtension(t)
+			if !ok {
+				continue
+			}
+			mimeType = ft.MIME
+		}
+		for _, pattern := range skill.InputMimeTypes {
+			if filetype.MatchMIME(pattern, mimeType) {
 				return true
 			}
 		}
@@ -153,11 +388,16 @@ func fuzzyScore(text string, query string) float64 {
 	if strings.HasPrefix(t, q) {
 		return 700
 	}
+	if ac := acronym(t); ac == q {
+		return 650
+	} else if len(q) >= 2 && strings.HasPrefix(ac, q) {
+		return 500
+	}
 	if strings.Contains(t, q) {
 		return 450
 	}
 	dist := levenshtein(t, q)
-	maxLen := float64(max(len(t), len(q)))
+	maxLen := float64(max(len([]rune(t)), len([]rune(q))))
 	if maxLen == 0 {
 		return 0
 	}
@@ -167,53 +407,3 @@ func fuzzyScore(text string, query string) float64 {
 	}
 	return sim * 400
 }
-
-func levenshtein(a, b string) int {
-	if a == b {
-		return 0
-	}
-	if len(a) == 0 {
-		return len(b)
-	}
-	if len(b) == 0 {
-		return len(a)
-	}
-	prev := make([]int, len(b)+1)
-	curr := make([]int, len(b)+1)
-	for j := 0; j <= len(b); j++ {
-		prev[j] = j
-	}
-	for i := 1; i <= len(a); i++ {
-		curr[0] = i
-		for j := 1; j <= len(b); j++ {
-			cost := 0
-			if a[i-1] != b[j-1] {
-				cost = 1
-			}
-			curr[j] = min(
-				curr[j-1]+1,
-				prev[j]+1,
-				prev[j-1]+cost,
-			)
-		}
-		copy(prev, curr)
-	}
-	return prev[len(b)]
-}
-
-func min(a, b, c int) int {
-	if a <= b && a <= c {
-		return a
-	}
-	if b <= a && b <= c {
-		return b
-	}
-	return c
-}
-
-func max(a, b int) int {
-	if a > b {
-		return a
-	}
-	return b
-}
@@ -0,0 +1,131 @@
+package skills
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestChainInputExt(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{".png"}, ".png"},
+		{[]string{".png", "image/png"}, ".png"},
+		{[]string{".png", ".jpg"}, ""},
+		{[]string{"image/png"}, ""},
+	}
+	for _, tt := range tests {
+		if got := ChainInputExt(tt.in); got != tt.want {
+			t.Errorf("ChainInputExt(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNextStepBoost(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+	transitions := ChainStats{
+		// After resize: compress 3 times, sharpen once, both today.
+		TransitionKey("resize", ""): {
+			"compress": {Count: 3, LastUsed: now},
+			"sharpen":  {Count: 1, LastUsed: now},
+		},
+		// On PNGs after resize only sharpen was used.
+		TransitionKey("resize", ".PNG"): {
+			"sharpen": {Count: 2, LastUsed: now},
+		},
+		// After blur: rotate 4 times two half-lives ago weighs as much as
+		// crop once today.
+		TransitionKey("blur", ""): {
+			"rotate": {Count: 4, LastUsed: daysAgo(28)},
+			"crop":   {Count: 1, LastUsed: now},
+		},
+		// First steps on JPEGs.
+		TransitionKey("", ".jpg"): {
+			"convert": {Count: 1, LastUsed: now},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		skill string
+		chain ChainContext
+		want  float64 // fraction of NextStepWeight
+	}{
+		{"generic transition", "compress", ChainContext{PreviousSkillID: "resize"}, 0.75},
+		{"generic minority", "sharpen", ChainContext{PreviousSkillID: "resize"}, 0.25},
+		{"per extension averaged with generic", "sharpen", ChainContext{PreviousSkillID: "resize", InputExt: ".png"}, (0.25 + 1) / 2},
+		{"missing from the per extension key", "compress", ChainContext{PreviousSkillID: "resize", InputExt: ".png"}, 0.75 / 2},
+		{"unknown extension uses generic", "compress", ChainContext{PreviousSkillID: "resize", InputExt: ".gif"}, 0.75},
+		{"decayed count", "rotate", ChainContext{PreviousSkillID: "blur"}, 0.5},
+		{"recent single use", "crop", ChainContext{PreviousSkillID: "blur"}, 0.5},
+		{"first step on a file", "convert", ChainContext{InputExt: ".jpg"}, 1},
+		{"no previous step or extension", "convert", ChainContext{}, 0},
+		{"unknown previous step", "compress", ChainContext{PreviousSkillID: "trim"}, 0},
+		{"never followed", "rotate", ChainContext{PreviousSkillID: "resize"}, 0},
+	}
+	ranker := DefaultRanker()
+	ranker.Now = func() time.Time { return now }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.chain.Transitions = transitions
+			got := ranker.nextStepBoost(tt.skill, tt.chain)
+			if want := tt.want * ranker.NextStepWeight; math.Abs(got-want) > 1e-9 {
+				t.Errorf("nextStepBoost(%s) = %v, want %v", tt.skill, got, want)
+			}
+		})
+	}
+
+	off := ranker.clone()
+	off.NextStepWeight = 0
+	if got := off.nextStepBoost("compress", ChainContext{PreviousSkillID: "resize", Transitions: transitions}); got != 0 {
+		t.Errorf("nextStepBoost with no weight = %v", got)
+	}
+}
+
+// TestRankLearnedNextStep checks that with an empty query the step usually
+// taken after the previous one outranks a skill that is used more often.
+func TestRankLearnedNextStep(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ranker := DefaultRanker()
+	ranker.Now = func() time.Time { return now }
+	candidates := []Skill{
+		{ID: "convert", Name: "Convert", Category: "convert"},
+		{ID: "compress", Name: "Compress", Category: "convert"},
+		{ID: "resize", Name: "Resize", Category: "convert"},
+	}
+	usage := map[string]UsageStats{
+		"convert":  {Count: 2, LastUsed: now.Add(-time.Hour)},
+		"compress": {Count: 1, LastUsed: now.AddDate(0, 0, -3)},
+		"resize":   {Count: 1, LastUsed: now.AddDate(0, 0, -1)},
+	}
+	chain := ChainContext{
+		PreviousSkillID: "resize",
+		InputExt:        ".png",
+		Transitions: ChainStats{
+			TransitionKey("resize", ""):     {"compress": {Count: 1, LastUsed: now.AddDate(0, 0, -3)}},
+			TransitionKey("resize", ".png"): {"compress": {Count: 1, LastUsed: now.AddDate(0, 0, -3)}},
+		},
+	}
+
+	ids := func(ranked []Skill) []string {
+		out := make([]string, len(ranked))
+		for i, s := range ranked {
+			out[i] = s.ID
+		}
+		return out
+	}
+	if got := ids(ranker.Rank(candidates, "", nil, usage, ChainContext{})); got[0] != "convert" {
+		t.Fatalf("frecency order = %v, want convert first", got)
+	}
+	if got := ids(ranker.Rank(candidates, "", nil, usage, chain)); got[0] != "compress" {
+		t.Errorf("order after resize = %v, want the learned next step compress first", got)
+	}
+	// A typed query ranks on the query alone.
+	if got := ids(ranker.Rank(candidates, "c", nil, usage, chain)); got[0] != "convert" {
+		t.Errorf("order for a query = %v, want convert first", got)
+	}
+}
@@ -3,7 +3,9 @@ package skills
 import (
 	"context"
 	"io/fs"
+	"sort"
 	"strings"
+	"sync"
 )
 
 type RegistryOptions struct {
@@ -11,19 +13,34 @@ type RegistryOptions struct {
 	EmbeddedRoot  string
 	DiskCoreRoot  string
 	CommunityRoot string
+	AppVersion    string
+	DisabledPacks []string
+	// TrustedPublishers are the keys pack signatures are verified against.
+	TrustedPublishers []Publisher
+	Policy            Policy
+	ToolChecker       ToolChecker
 }
 
 type Registry struct {
 	loader *Loader
-	ranker *Ranker
+
+	mu      sync.RWMutex
+	ranker  *Ranker
+	overlay Overlay
+	locale  string
 }
 
 func NewRegistry(opts RegistryOptions) *Registry {
 	loader := NewLoader(LoaderOptions{
-		EmbeddedFS:    opts.EmbeddedFS,
-		EmbeddedRoot:  opts.EmbeddedRoot,
-		DiskCoreRoot:  opts.DiskCoreRoot,
-		CommunityRoot: opts.CommunityRoot,
+		EmbeddedFS:        opts.EmbeddedFS,
+		EmbeddedRoot:      opts.EmbeddedRoot,
+		DiskCoreRoot:      opts.DiskCoreRoot,
+		CommunityRoot:     opts.CommunityRoot,
+		AppVersion:        opts.AppVersion,
+		DisabledPacks:     opts.DisabledPacks,
+		TrustedPublishers: opts.TrustedPublishers,
+		Policy:            opts.Policy,
+		ToolChecker:       opts.ToolChecker,
 	})
 	_ = loader.LoadAll()
 	return &Registry{loader: loader, ranker: DefaultRanker()}
@@ -36,6 +53,180 @@ func (r *Registry) List() []Skill {
 	return r.loader.List()
 }
 
+// Packs lists the installed packs, including disabled and broken ones.
+func (r *Registry) Packs() []Pack {
+	if r.loader == nil {
+		return nil
+	}
+	return r.loader.Packs()
+}
+
+// GetPack returns an installed pack by ID.
+func (r *Registry) GetPack(id string) (Pack, bool) {
+	for _, p := range r.Packs() {
+		if p.Manifest.ID == id {
+			return p, true
+		}
+	}
+	return Pack{}, false
+}
+
+// SetDisabledPacks replaces the disabled pack IDs and reloads skills.
+func (r *Registry) SetDisabledPacks(ids []string) error {
+	if r.loader == nil {
+		return nil
+	}
+	r.loader.SetDisabledPacks(ids)
+	return r.Reload()
+}
+
+// SetTrustedPublishers replaces the publisher keyring and reloads skills so
+// their verification status is refreshed.
+func (r *Registry) SetTrustedPublishers(publishers []Publisher) error {
+	if r.loader == nil {
+		return nil
+	}
+	r.loader.SetTrustedPublishers(publishers)
+	return r.Reload()
+}
+
+// SetPolicy replaces the administrator policy and reloads skills.
+func (r *Registry) SetPolicy(policy Policy) error {
+	if r.loader == nil {
+		return nil
+	}
+	r.loader.SetPolicy(policy)
+	return r.Reload()
+}
+
+// SetToolChecker replaces how required tools are probed and reloads skills
+// so their availability is refreshed.
+func (r *Registry) SetToolChecker(checker ToolChecker) error {
+	if r.loader == nil {
+		return nil
+	}
+	r.loader.SetToolChecker(checker)
+	return r.Reload()
+}
+
+// Blocked lists the skills the policy keeps out of the registry.
+func (r *Registry) Blocked() []BlockedSkill {
+	if r.loader == nil {
+		return nil
+	}
+	return r.loader.Blocked()
+}
+
+// PolicyBlocks explains why the policy forbids running a skill, or returns
+// "". It covers skills blocked at load time and re-checks loaded ones.
+func (r *Registry) PolicyBlocks(id string) string {
+	if r.loader == nil {
+		return ""
+	}
+	if reason := r.loader.BlockedReason(id); reason != "" {
+		return reason
+	}
+	skill, ok := r.loader.GetByID(id)
+	if !ok {
+		return ""
+	}
+	var pack *Pack
+	if p, ok := r.GetPack(skill.PackID); ok {
+		pack = &p
+	}
+	return r.loader.Policy().Blocks(skill, pack)
+}
+
+// PolicyBlocksCommand explains why the policy forbids running command, or
+// returns "".
+func (r *Registry) PolicyBlocksCommand(command string) string {
+	if r.loader == nil {
+		return ""
+	}
+	return r.loader.Policy().BlocksCommand(command)
+}
+
+// Reload reloads all skills and packs from their roots.
+func (r *Registry) Reload() error {
+	if r.loader == nil {
+		return nil
+	}
+	return r.loader.LoadAll()
+}
+
+// SetRankerOverrides replaces the ranker weights with the defaults plus the
+// user's overrides.
+func (r *Registry) SetRankerOverrides(o RankerOverrides) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ranker = DefaultRanker().WithOverrides(o)
+}
+
+// Ranker returns a copy of the ranker SetRankerOverrides configured.
+func (r *Registry) Ranker() *Ranker {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.ranker.clone()
+}
+
+// SetOverlay replaces the user's aliases, pins and hidden skills.
+func (r *Registry) SetOverlay(o Overlay) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.overlay = o
+}
+
+// SetLocale selects the language skills are shown and matched in, in
+// addition to English.
+func (r *Registry) SetLocale(locale string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.locale = NormalizeLocale(locale)
+}
+
+func (r *Registry) currentLocale() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.locale
+}
+
+func (r *Registry) currentOverlay() Overlay {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.overlay
+}
+
+// Categories returns the declared categories, highest priority first.
+func (r *Registry) Categories() []CategoryDef {
+	if r.loader == nil {
+		return nil
+	}
+	cats := r.loader.Categories()
+	out := make([]CategoryDef, 0, len(cats))
+	for _, c := range cats {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Priority == out[j].Priority {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].Priority > out[j].Priority
+	})
+	return out
+}
+
+// currentRanker returns the ranker combined with the currently declared
+// categories.
+func (r *Registry) currentRanker() *Ranker {
+	r.mu.RLock()
+	ranker := r.ranker
+	r.mu.RUnlock()
+	if r.loader == nil {
+		return ranker
+	}
+	return ranker.WithCategories(r.loader.Categories())
+}
+
 func (r *Registry) StartHotReload(ctx context.Context) error {
 	if r.loader == nil {
 		return nil
@@ -52,11 +243,59 @@ func (r *Registry) Changes() <-chan struct{} {
 	return r.loader.Changes()
 }
 
-func (r *Registry) Search(query string, inputTypes []string, usage map[string]UsageStats) []Skill {
-	candidates := r.List()
+func (r *Registry) Search(query string, inputTypes []string, usage map[string]UsageStats, chain ChainContext) []Skill {
+	explained := r.SearchExplained(query, inputTypes, usage, chain)
+	out := make([]Skill, 0, len(explained))
+	for _, item := range explained {
+		out = append(out, item.Skill)
+	}
+	return out
+}
+
+// SearchExplained returns the same results as Search together with the score
+// breakdown of each one, for tuning the ranker.
+func (r *Registry) SearchExplained(query string, inputTypes []string, usage map[string]UsageStats, chain ChainContext) []ExplainedSkill {
+	filtered, trimmed := r.candidates(query, inputTypes)
+	return r.order(r.currentRanker().RankExplained(filtered, trimmed, inputTypes, usage, chain), trimmed)
+}
+
+// order applies what the command bar does after ranking: pins lead
+// empty-query results and skills with missing tools go last.
+func (r *Registry) order(ranked []ExplainedSkill, trimmed string) []ExplainedSkill {
+	if trimmed == "" {
+		ranked = pinFirst(ranked, r.currentOverlay())
+	}
+	return availableFirst(ranked)
+}
+
+// availableFirst moves skills whose tools are missing after the runnable
+// ones, keeping the order within each group. They stay listed so the user
+// sees the install hint.
+func availableFirst(ranked []ExplainedSkill) []ExplainedSkill {
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Skill.Available() && !ranked[j].Skill.Available()
+	})
+	return ranked
+}
+
+// candidates returns the skills eligible for ranking and the trimmed query.
+func (r *Registry) candidates(query string, inputTypes []string) ([]Skill, string) {
+	if r.loader == nil {
+		return nil, strings.TrimSpace(query)
+	}
+	overlay := r.currentOverlay()
+	locale := r.currentLocale()
+	candidates := make([]Skill, 0)
+	for _, skill := range r.List() {
+		if overlay.isHidden(skill.ID) {
+			continue
+		}
+		candidates = append(candidates, overlay.withAliases(skill.Localized(locale)))
+	}
 	trimmed := strings.TrimSpace(query)
 
-	// If no query, return all skills ranked by frecency and input match
+	// If no query, return all skills ranked by frecency, input match and the
+	// learned next step in the current chain
 	if trimmed == "" {
 		filtered := make([]Skill, 0, len(candidates))
 		for _, skill := range candidates {
@@ -64,113 +303,72 @@ func (r *Registry) Search(query string, inputTypes []string, usage map[string]Us
 				filtered = append(filtered, skill)
 			}
 		}
-		return r.ranker.Rank(filtered, "", inputTypes, usage)
+		return filtered, ""
 	}
 
 	// With a query, filter to only matching skills
 	filtered := make([]Skill, 0, len(candidates))
 	q := strings.ToLower(trimmed)
+	matched := r.loader.matchQuery(q, locale)
+	for id := range overlay.matchAliases(q) {
+		matched[id] = struct{}{}
+	}
 
 	for _, skill := range candidates {
-		// Check if skill matches the query
-		if matchesQuery(skill, q) {
-			// Also check input type compatibility (or meta skills)
-			if skill.IsMeta || inputMatches(skill, inputTypes) || len(inputTypes) == 0 {
-				filtered = append(filtered, skill)
-			}
+		if _, ok := matched[skill.ID]; !ok {
+			continue
+		}
+		// Also check input type compatibility (or meta skills)
+		if skill.IsMeta || inputMatches(skill, inputTypes) || len(inputTypes) == 0 {
+			filtered = append(filtered, skill)
 		}
 	}
 
-	return r.ranker.Rank(filtered, trimmed, inputTypes, usage)
+	return filtered, trimmed
 }
 
-// matchesQuery checks if a skill matches the search query
-func matchesQuery(skill Skill, query string) bool {
-	// Check name
-	name := strings.ToLower(skill.Name)
-	if strings.Contains(name, query) || strings.HasPrefix(name, query) {
-		return true
-	}
-
-	// Check aliases
-	for _, alias := range skill.Aliases {
-		a := strings.ToLower(alias)
-		if strings.Contains(a, query) || strings.HasPrefix(a, query) {
-			return true
-		}
-	}
-
-	// Check description
-	desc := strings.ToLower(skill.Description)
-	if strings.Contains(desc, query) {
+// IsNamePrefix reports whether query, ignoring case and surrounding space,
+// starts the name or an alias of a visible skill, in English or the current
+// locale. Such a query says nothing the skill list doesn't.
+func (r *Registry) IsNamePrefix(query string) bool {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
 		return true
 	}
-
-	// Check individual words in query against name words
-	queryWords := strings.Fields(query)
-	nameWords := strings.Fields(name)
-	for _, qw := range queryWords {
-		for _, nw := range nameWords {
-			if strings.HasPrefix(nw, qw) {
+	candidates, _ := r.candidates("", nil)
+	for _, skill := range candidates {
+		for _, name := range append([]string{skill.Name}, skill.Aliases...) {
+			if strings.HasPrefix(strings.ToLower(name), q) {
 				return true
 			}
 		}
 	}
-
-	// Fuzzy: allow typos if query is close enough
-	if len(query) >= 3 {
-		dist := levenshteinDistance(name, query)
-		if dist <= 2 {
-			return true
-		}
-	}
-
 	return false
 }
 
-// levenshteinDistance calculates edit distance between two strings
-func levenshteinDistance(a, b string) int {
-	if a == b {
-		return 0
-	}
-	if len(a) == 0 {
-		return len(b)
-	}
-	if len(b) == 0 {
-		return len(a)
-	}
-	prev := make([]int, len(b)+1)
-	curr := make([]int, len(b)+1)
-	for j := 0; j <= len(b); j++ {
-		prev[j] = j
-	}
-	for i := 1; i <= len(a); i++ {
-		curr[0] = i
-		for j := 1; j <= len(b); j++ {
-			cost := 0
-			if a[i-1] != b[j-1] {
-				cost = 1
-			}
-			del := curr[j-1] + 1
-			ins := prev[j] + 1
-			sub := prev[j-1] + cost
-			curr[j] = minInt(del, minInt(ins, sub))
-		}
-		copy(prev, curr)
-	}
-	return prev[len(b)]
-}
-
-func minInt(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
 func (r *Registry) GetByID(id string) (Skill, bool) {
 	if r.loader == nil {
 		return Skill{}, false
 	}
 	return r.loader.GetByID(id)
 }
+
+// PipelineSkills returns skill followed by every skill its pipeline steps
+// run, nested pipelines included, each once. Steps that don't resolve are
+// left out; running the pipeline reports them.
+func (r *Registry) PipelineSkills(skill Skill) []Skill {
+	out := []Skill{skill}
+	seen := map[string]bool{skill.ID: true}
+	for i := 0; i < len(out); i++ {
+		for _, step := range out[i].Executor.Steps {
+			if seen[step.SkillID] {
+				continue
+			}
+			seen[step.SkillID] = true
+			if s, ok := r.GetByID(step.SkillID); ok {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
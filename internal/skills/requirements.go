@@ -0,0 +1,122 @@
+package skills
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ToolRequirement declares an external tool a skill needs.
+type ToolRequirement struct {
+	Tool string `json:"tool"`
+	// Version is an optional constraint (">=7", "6.1", ">=4.4 <8").
+	Version string `json:"version,omitempty"`
+	// Hint overrides the install hint shown when the tool is missing.
+	Hint string `json:"hint,omitempty"`
+}
+
+// ToolChecker reports whether the tools skills require can be run.
+type ToolChecker interface {
+	// CheckTool returns an error when name is missing or outside the
+	// version constraint.
+	CheckTool(name string, version string) error
+	// InstallHint tells the user how to provide a missing tool.
+	InstallHint(name string) string
+}
+
+// Commands returns the executables a CLI skill runs: its command and the
+// command of its progress total probe.
+func (s Skill) Commands() []string {
+	if s.Executor.Type != "cli" {
+		return nil
+	}
+	var out []string
+	if cmd := strings.TrimSpace(s.Executor.Command); cmd != "" {
+		out = append(out, cmd)
+	}
+	if p := s.Executor.Progress; p != nil && p.TotalProbe != nil {
+		if cmd := strings.TrimSpace(p.TotalProbe.Command); cmd != "" {
+			out = append(out, cmd)
+		}
+	}
+	return out
+}
+
+// RequiredTools returns the tools a skill declares plus, for CLI skills, the
+// commands it runs.
+func (s Skill) RequiredTools() []ToolRequirement {
+	out := append([]ToolRequirement(nil), s.Requires...)
+	for _, cmd := range s.Commands() {
+		declared := false
+		for _, req := range out {
+			if toolName(req.Tool) == toolName(cmd) {
+				declared = true
+				break
+			}
+		}
+		if !declared {
+			out = append(out, ToolRequirement{Tool: cmd})
+		}
+	}
+	return out
+}
+
+// Available reports whether every tool the skill needs was found at the last
+// load.
+func (s Skill) Available() bool {
+	return s.Unavailable == ""
+}
+
+func toolName(command string) string {
+	return strings.TrimSuffix(strings.ToLower(filepath.Base(command)), ".exe")
+}
+
+// checkTools marks skills whose tools are missing, and pipelines with such a
+// step, as unavailable. Each command and constraint is checked once; "magick"
+// and "/opt/bin/magick" are different tools.
+func checkTools(loaded map[string]Skill, checker ToolChecker) {
+	if checker == nil {
+		return
+	}
+	type probe struct{ tool, version string }
+	results := make(map[probe]error)
+	for id, s := range loaded {
+		for _, req := range s.RequiredTools() {
+			key := probe{strings.TrimSpace(req.Tool), strings.TrimSpace(req.Version)}
+			err, ok := results[key]
+			if !ok {
+				err = checker.CheckTool(req.Tool, key.version)
+				results[key] = err
+			}
+			if err == nil {
+				continue
+			}
+			s.Unavailable = err.Error()
+			s.InstallHint = req.Hint
+			if s.InstallHint == "" {
+				s.InstallHint = checker.InstallHint(req.Tool)
+			}
+			loaded[id] = s
+			break
+		}
+	}
+
+	// Pipelines are unavailable when a step is; repeat for nested ones.
+	for changed := true; changed; {
+		changed = false
+		for id, s := range loaded {
+			if s.Executor.Type != "pipeline" || !s.Available() {
+				continue
+			}
+			for _, step := range s.Executor.Steps {
+				if dep, ok := loaded[step.SkillID]; ok && !dep.Available() {
+					s.Unavailable = fmt.Sprintf("step %s: %s", dep.Name, dep.Unavailable)
+					s.InstallHint = dep.InstallHint
+					loaded[id] = s
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
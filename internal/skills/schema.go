@@ -1,5 +1,7 @@
 package skills
 
+import "encoding/json"
+
 type Skill struct {
 	Version     string     `json:"version,omitempty"`
 	Author      string     `json:"author,omitempty"`
@@ -16,11 +18,58 @@ type Skill struct {
 	Executor    Executor   `json:"executor,omitempty"`
 	Permissions []string   `json:"permissions,omitempty"`
 	DangerLevel int        `json:"dangerLevel"`
+	// Requires lists external tools the skill needs; a CLI skill's command
+	// is required implicitly.
+	Requires []ToolRequirement `json:"requires,omitempty"`
+	// InputMimeTypes matches files by detected content type ("image/png",
+	// "image/*") in addition to InputTypes.
+	InputMimeTypes []string `json:"inputMimeTypes,omitempty"`
+	// Locales holds optional translations keyed by locale ("de", "pt-BR").
+	// The skill ID and the English strings above stay canonical.
+	Locales map[string]LocalizedText `json:"locales,omitempty"`
 
 	// Source is runtime metadata (not part of the JSON schema).
 	Source SkillSource `json:"-"`
 	// DefinitionPath is the on-disk path (if loaded from disk).
 	DefinitionPath string `json:"-"`
+	// Definition is the raw definition file and DefinitionHash its sha256;
+	// trust approvals are pinned to them.
+	Definition     json.RawMessage `json:"-"`
+	DefinitionHash string          `json:"definitionHash,omitempty"`
+	// PackID is set at load time for skills that ship in a pack; their ID is
+	// then namespaced as "<packId>/<id>".
+	PackID string `json:"packId,omitempty"`
+	// Verification is set at load time for community skills.
+	Verification Verification `json:"verification,omitempty"`
+	// Unavailable is set at load time when a required tool is missing or
+	// too old, with InstallHint telling the user how to fix it.
+	Unavailable string `json:"unavailable,omitempty"`
+	InstallHint string `json:"installHint,omitempty"`
+}
+
+// categoriesFilename declares categories for the skills next to it. Like
+// other '_' files it is metadata, not a skill.
+const categoriesFilename = "_categories.json"
+
+// CategoriesFile is the format of a _categories.json metadata file.
+type CategoriesFile struct {
+	Categories []CategoryDef `json:"categories"`
+}
+
+// CategoryDef declares a skill category. Priority is the ranker's base boost
+// for skills in the category.
+type CategoryDef struct {
+	ID          string  `json:"id"`
+	Priority    float64 `json:"priority"`
+	Icon        string  `json:"icon,omitempty"`
+	Description string  `json:"description,omitempty"`
+}
+
+// LocalizedText is the translated name, aliases and description of a skill.
+type LocalizedText struct {
+	Name        string   `json:"name,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Description string   `json:"description,omitempty"`
 }
 
 type SkillSource string
@@ -38,10 +87,28 @@ type Executor struct {
 	Handler string `json:"handler,omitempty"`
 
 	// CLI
-	Command         string   `json:"command,omitempty"`
-	Args            []string `json:"args,omitempty"`
-	OutputExtension string   `json:"outputExtension,omitempty"`
-	TimeoutMs       int      `json:"timeoutMs,omitempty"`
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	// OutputExtension may be derived from params ("{{format}}").
+	OutputExtension string `json:"outputExtension,omitempty"`
+	TimeoutMs       int    `json:"timeoutMs,omitempty"`
+	// Stdin feeds the input file to the command's standard input; Stdout
+	// writes its standard output to the output file.
+	Stdin  bool `json:"stdin,omitempty"`
+	Stdout bool `json:"stdout,omitempty"`
+	// Outputs is a glob, relative to {{outputDir}}, matching the files the
+	// command writes. The first in natural name order becomes the output and
+	// the rest are kept as additional outputs.
+	Outputs string `json:"outputs,omitempty"`
+	// WorkingDir is where the command runs: "output" (default), "input" or
+	// "temp" (a fresh directory removed afterwards).
+	WorkingDir string `json:"workingDir,omitempty"`
+	// Env adds variables to the command's environment; values are templates.
+	Env map[string]string `json:"env,omitempty"`
+	// Progress declares where the command reports how far it is.
+	Progress *ProgressSpec `json:"progress,omitempty"`
+	// Limits caps the resources the command may use.
+	Limits ResourceLimits `json:"limits,omitempty"`
 
 	// Lua
 	Script string `json:"script,omitempty"`
@@ -50,6 +117,35 @@ type Executor struct {
 	Steps []PipelineStep `json:"steps,omitempty"`
 }
 
+// ResourceLimits caps what a CLI command may use; zero means unlimited.
+type ResourceLimits struct {
+	MemoryMB   int `json:"memoryMb,omitempty"`
+	CPUSeconds int `json:"cpuSeconds,omitempty"`
+	// OutputMB caps the size of any file the command writes.
+	OutputMB  int `json:"outputMb,omitempty"`
+	Processes int `json:"processes,omitempty"`
+}
+
+// Within caps each limit at the matching maximum; a maximum of zero leaves
+// the limit as declared, and an undeclared limit takes the maximum.
+func (l ResourceLimits) Within(maximum ResourceLimits) ResourceLimits {
+	capAt := func(v int, m int) int {
+		if m <= 0 {
+			return max(v, 0)
+		}
+		if v <= 0 || v > m {
+			return m
+		}
+		return v
+	}
+	return ResourceLimits{
+		MemoryMB:   capAt(l.MemoryMB, maximum.MemoryMB),
+		CPUSeconds: capAt(l.CPUSeconds, maximum.CPUSeconds),
+		OutputMB:   capAt(l.OutputMB, maximum.OutputMB),
+		Processes:  capAt(l.Processes, maximum.Processes),
+	}
+}
+
 type PipelineStep struct {
 	SkillID string         `json:"skillId"`
 	Params  map[string]any `json:"params,omitempty"`
@@ -0,0 +1,151 @@
+package skills
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// SignatureFilename holds a pack's ed25519 signature. Like other '_' files it
+// is metadata, not a skill, and it is excluded from the signed digest.
+const SignatureFilename = "_signature.json"
+
+// Verification says whether a community skill's contents are vouched for.
+type Verification string
+
+const (
+	// VerificationVerified: the pack is signed by a trusted publisher and
+	// its files match the signature.
+	VerificationVerified Verification = "verified"
+	// VerificationUnverified: unsigned, or signed by a key not in the
+	// keyring.
+	VerificationUnverified Verification = "unverified"
+	// VerificationTampered: the pack is signed but its files no longer match
+	// the signature.
+	VerificationTampered Verification = "tampered"
+)
+
+// Publisher is a trusted signing key in the user's keyring.
+type Publisher struct {
+	Name string `json:"name"`
+	// PublicKey is the base64-encoded ed25519 public key.
+	PublicKey string `json:"publicKey"`
+}
+
+// PackSignature is the content of a pack's _signature.json.
+type PackSignature struct {
+	Publisher string `json:"publisher"`
+	PublicKey string `json:"publicKey"`
+	// Signature is the base64-encoded ed25519 signature of PackDigest.
+	Signature string `json:"signature"`
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key: want %d bytes, got %d", ed25519.PublicKeySize, len(b))
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// PackDigest lists "<sha256>  <path>" for every file below dir except the
+// signature, sorted by path. This listing is what publishers sign.
+func PackDigest(fsys fs.FS, dir string) ([]byte, error) {
+	var lines []string
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, dir), "/")
+		if dir == "." {
+			rel = p
+		}
+		if rel == SignatureFilename {
+			return nil
+		}
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(b)
+		lines = append(lines, hex.EncodeToString(sum[:])+"  "+rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][66:] < lines[j][66:] })
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// SignPack signs the pack rooted at dir with key.
+func SignPack(fsys fs.FS, dir string, publisher string, key ed25519.PrivateKey) (PackSignature, error) {
+	digest, err := PackDigest(fsys, dir)
+	if err != nil {
+		return PackSignature{}, err
+	}
+	return PackSignature{
+		Publisher: publisher,
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, digest)),
+	}, nil
+}
+
+// verifyPack checks the pack rooted at dir against its signature and the
+// trusted publishers, recording the status, the publisher name (from the
+// keyring when trusted, as claimed otherwise) and the signing key on pack.
+func verifyPack(fsys fs.FS, dir string, trusted []Publisher, pack *Pack) error {
+	pack.Verification = VerificationTampered
+	b, err := fs.ReadFile(fsys, joinFS(dir, SignatureFilename))
+	if errors.Is(err, fs.ErrNotExist) {
+		pack.Verification = VerificationUnverified
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var sig PackSignature
+	if err := json.Unmarshal(b, &sig); err != nil {
+		return fmt.Errorf("invalid signature file: %w", err)
+	}
+	pack.Publisher = sig.Publisher
+	key, err := ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return err
+	}
+	pack.PublisherKey = sig.PublicKey
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	digest, err := PackDigest(fsys, dir)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, digest, raw) {
+		return fmt.Errorf("contents do not match the signature")
+	}
+	pack.Verification = VerificationUnverified
+	for _, p := range trusted {
+		if k, err := ParsePublicKey(p.PublicKey); err == nil && k.Equal(key) {
+			pack.Verification = VerificationVerified
+			pack.Publisher = p.Name
+			break
+		}
+	}
+	return nil
+}
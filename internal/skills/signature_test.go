@@ -0,0 +1,80 @@
+package skills
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVerifyPack(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted := []Publisher{{
+		Name:      "Acme",
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+	}}
+
+	pack := func() fstest.MapFS {
+		return fstest.MapFS{
+			"pack/pack.json":          {Data: []byte(`{"id":"acme"}`)},
+			"pack/skills/resize.json": {Data: []byte(`{"id":"resize"}`)},
+		}
+	}
+	signed := func(key ed25519.PrivateKey, publisher string) fstest.MapFS {
+		fsys := pack()
+		sig, err := SignPack(fsys, "pack", publisher, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := json.Marshal(sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys["pack/"+SignatureFilename] = &fstest.MapFile{Data: b}
+		return fsys
+	}
+	tampered := signed(key, "Acme")
+	tampered["pack/skills/resize.json"] = &fstest.MapFile{Data: []byte(`{"id":"resize","executor":{}}`)}
+	added := signed(key, "Acme")
+	added["pack/skills/extra.json"] = &fstest.MapFile{Data: []byte(`{"id":"extra"}`)}
+	invalid := pack()
+	invalid["pack/"+SignatureFilename] = &fstest.MapFile{Data: []byte(`{`)}
+
+	tests := []struct {
+		name          string
+		fsys          fstest.MapFS
+		want          Verification
+		wantPublisher string
+		wantErr       bool
+	}{
+		{name: "unsigned", fsys: pack(), want: VerificationUnverified},
+		{name: "trusted key", fsys: signed(key, "Someone Else"), want: VerificationVerified, wantPublisher: "Acme"},
+		{name: "untrusted key", fsys: signed(otherKey, "Mallory"), want: VerificationUnverified, wantPublisher: "Mallory"},
+		{name: "modified file", fsys: tampered, want: VerificationTampered, wantErr: true},
+		{name: "added file", fsys: added, want: VerificationTampered, wantErr: true},
+		{name: "invalid signature file", fsys: invalid, want: VerificationTampered, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Pack
+			err := verifyPack(tt.fsys, "pack", trusted, &p)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyPack() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if p.Verification != tt.want {
+				t.Errorf("Verification = %q, want %q", p.Verification, tt.want)
+			}
+			if !tt.wantErr && p.Publisher != tt.wantPublisher {
+				t.Errorf("Publisher = %q, want %q", p.Publisher, tt.wantPublisher)
+			}
+		})
+	}
+}
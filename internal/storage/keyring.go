@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"asteria/internal/skills"
+)
+
+// KeyringStore persists the publishers whose pack signatures the user trusts.
+type KeyringStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+type Keyring struct {
+	Publishers []skills.Publisher `json:"publishers"`
+}
+
+func NewKeyringStore() (*KeyringStore, error) {
+	dir, err := AppConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return &KeyringStore{path: filepath.Join(dir, "publishers.json")}, nil
+}
+
+func (k *KeyringStore) Load() (Keyring, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.loadLocked()
+}
+
+func (k *KeyringStore) loadLocked() (Keyring, error) {
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Keyring{}, nil
+		}
+		return Keyring{}, err
+	}
+	var keyring Keyring
+	if err := json.Unmarshal(data, &keyring); err != nil {
+		return Keyring{}, err
+	}
+	return keyring, nil
+}
+
+func (k *KeyringStore) saveLocked(keyring Keyring) error {
+	data, err := json.MarshalIndent(keyring, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(k.path, data, 0o644)
+}
+
+// AddPublisher trusts publicKey under name, renaming it if already present.
+func (k *KeyringStore) AddPublisher(name string, publicKey string) (Keyring, error) {
+	name = strings.TrimSpace(name)
+	publicKey = strings.TrimSpace(publicKey)
+	if name == "" {
+		return Keyring{}, fmt.Errorf("publisher name is required")
+	}
+	if _, err := skills.ParsePublicKey(publicKey); err != nil {
+		return Keyring{}, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keyring, err := k.loadLocked()
+	if err != nil {
+		return Keyring{}, err
+	}
+	found := false
+	for i, p := range keyring.Publishers {
+		if p.PublicKey == publicKey {
+			keyring.Publishers[i].Name = name
+			found = true
+		}
+	}
+	if !found {
+		keyring.Publishers = append(keyring.Publishers, skills.Publisher{Name: name, PublicKey: publicKey})
+	}
+	return keyring, k.saveLocked(keyring)
+}
+
+// RemovePublisher stops trusting publicKey.
+func (k *KeyringStore) RemovePublisher(publicKey string) (Keyring, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keyring, err := k.loadLocked()
+	if err != nil {
+		return Keyring{}, err
+	}
+	kept := keyring.Publishers[:0]
+	for _, p := range keyring.Publishers {
+		if p.PublicKey != strings.TrimSpace(publicKey) {
+			kept = append(kept, p)
+		}
+	}
+	keyring.Publishers = kept
+	return keyring, k.saveLocked(keyring)
+}
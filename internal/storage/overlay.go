@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"asteria/internal/skills"
+)
+
+// OverlayStore persists the user's personal aliases, pinned and hidden skills
+// next to the usage stats.
+type OverlayStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewOverlayStore() (*OverlayStore, error) {
+	dir, err := AppConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return &OverlayStore{path: filepath.Join(dir, "overlay.json")}, nil
+}
+
+func (o *OverlayStore) Load() (skills.Overlay, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.load()
+}
+
+func (o *OverlayStore) load() (skills.Overlay, error) {
+	data, err := os.ReadFile(o.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return skills.Overlay{Aliases: map[string][]string{}}, nil
+		}
+		return skills.Overlay{}, err
+	}
+	var overlay skills.Overlay
+	if err := json.Unmarshal(data, &overlay); err != nil {
+		return skills.Overlay{}, err
+	}
+	if overlay.Aliases == nil {
+		overlay.Aliases = map[string][]string{}
+	}
+	return overlay, nil
+}
+
+func (o *OverlayStore) save(overlay skills.Overlay) error {
+	data, err := json.MarshalIndent(overlay, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(o.path, data, 0o644)
+}
+
+// update loads the overlay, applies change and saves it, returning the result.
+func (o *OverlayStore) update(change func(overlay *skills.Overlay)) (skills.Overlay, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	overlay, err := o.load()
+	if err != nil {
+		return skills.Overlay{}, err
+	}
+	change(&overlay)
+	if err := o.save(overlay); err != nil {
+		return skills.Overlay{}, err
+	}
+	return overlay, nil
+}
+
+func (o *OverlayStore) AddAlias(skillID string, alias string) (skills.Overlay, error) {
+	alias = strings.TrimSpace(alias)
+	return o.update(func(overlay *skills.Overlay) {
+		if alias == "" {
+			return
+		}
+		for _, existing := range overlay.Aliases[skillID] {
+			if strings.EqualFold(existing, alias) {
+				return
+			}
+		}
+		overlay.Aliases[skillID] = append(overlay.Aliases[skillID], alias)
+	})
+}
+
+func (o *OverlayStore) RemoveAlias(skillID string, alias string) (skills.Overlay, error) {
+	return o.update(func(overlay *skills.Overlay) {
+		kept := overlay.Aliases[skillID][:0]
+		for _, existing := range overlay.Aliases[skillID] {
+			if !strings.EqualFold(existing, strings.TrimSpace(alias)) {
+				kept = append(kept, existing)
+			}
+		}
+		if len(kept) == 0 {
+			delete(overlay.Aliases, skillID)
+		} else {
+			overlay.Aliases[skillID] = kept
+		}
+	})
+}
+
+func (o *OverlayStore) SetPinned(skillID string, pinned bool) (skills.Overlay, error) {
+	return o.update(func(overlay *skills.Overlay) {
+		overlay.Pinned = setMember(overlay.Pinned, skillID, pinned)
+	})
+}
+
+func (o *OverlayStore) SetHidden(skillID string, hidden bool) (skills.Overlay, error) {
+	return o.update(func(overlay *skills.Overlay) {
+		overlay.Hidden = setMember(overlay.Hidden, skillID, hidden)
+	})
+}
+
+// setMember adds or removes id from list, keeping the existing order.
+func setMember(list []string, id string, member bool) []string {
+	out := make([]string, 0, len(list)+1)
+	found := false
+	for _, existing := range list {
+		if existing == id {
+			found = true
+			if !member {
+				continue
+			}
+		}
+		out = append(out, existing)
+	}
+	if member && !found {
+		out = append(out, id)
+	}
+	return out
+}
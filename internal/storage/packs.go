@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// PackStore persists which skill packs the user disabled.
+type PackStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+type PackState struct {
+	DisabledPacks []string `json:"disabledPacks"`
+}
+
+func NewPackStore() (*PackStore, error) {
+	dir, err := AppConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return &PackStore{path: filepath.Join(dir, "packs.json")}, nil
+}
+
+func (p *PackStore) Load() (PackState, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.load()
+}
+
+func (p *PackStore) load() (PackState, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return PackState{DisabledPacks: []string{}}, nil
+		}
+		return PackState{}, err
+	}
+	var state PackState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return PackState{}, err
+	}
+	if state.DisabledPacks == nil {
+		state.DisabledPacks = []string{}
+	}
+	return state, nil
+}
+
+// SetEnabled enables or disables a pack and returns the new disabled list.
+func (p *PackStore) SetEnabled(packID string, enabled bool) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	state, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	disabled := make(map[string]bool, len(state.DisabledPacks)+1)
+	for _, id := range state.DisabledPacks {
+		disabled[id] = true
+	}
+	if enabled {
+		delete(disabled, packID)
+	} else {
+		disabled[packID] = true
+	}
+	state.DisabledPacks = state.DisabledPacks[:0]
+	for id := range disabled {
+		state.DisabledPacks = append(state.DisabledPacks, id)
+	}
+	sort.Strings(state.DisabledPacks)
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(p.path, data, 0o644); err != nil {
+		return nil, err
+	}
+	return state.DisabledPacks, nil
+}
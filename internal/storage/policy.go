@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"asteria/internal/skills"
+)
+
+const policyFilename = "policy.json"
+
+// SystemPolicyPath is where administrators install a machine-wide
+// policy.json.
+func SystemPolicyPath() string {
+	switch runtime.GOOS {
+	case "windows":
+		base := os.Getenv("ProgramData")
+		if base == "" {
+			base = `C:\ProgramData`
+		}
+		return filepath.Join(base, "Asteria", policyFilename)
+	case "darwin":
+		return filepath.Join("/Library/Application Support/Asteria", policyFilename)
+	default:
+		return filepath.Join("/etc/asteria", policyFilename)
+	}
+}
+
+// UserPolicyPath is the policy.json in AppConfigDir.
+func UserPolicyPath() (string, error) {
+	dir, err := AppConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, policyFilename), nil
+}
+
+// LoadPolicy reads the system-wide and user policies and merges them; the
+// user policy can only add restrictions. Missing files are an empty policy.
+// A system policy that exists but can't be read or parsed fails closed: the
+// lockdown policy applies and the error is returned with it.
+func LoadPolicy() (skills.Policy, error) {
+	userPath, userErr := UserPolicyPath()
+	policy, err := loadPolicy(SystemPolicyPath(), userPath)
+	if err == nil {
+		err = userErr
+	}
+	return policy, err
+}
+
+// loadPolicy is LoadPolicy for the given files; an empty userPath skips the
+// user policy.
+func loadPolicy(systemPath string, userPath string) (skills.Policy, error) {
+	system, err := readPolicy(systemPath)
+	if err != nil {
+		return skills.LockdownPolicy(), err
+	}
+	if userPath == "" {
+		return system, nil
+	}
+	user, err := readPolicy(userPath)
+	if err != nil {
+		return system, err
+	}
+	return system.Merge(user), nil
+}
+
+func readPolicy(path string) (skills.Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return skills.Policy{}, nil
+		}
+		return skills.Policy{}, err
+	}
+	var policy skills.Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return skills.Policy{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return policy, nil
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"asteria/internal/skills"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	missing := filepath.Join(dir, "missing.json")
+	unreadable := filepath.Join(dir, "unreadable.json")
+	if err := os.Mkdir(unreadable, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	system := write("system.json", `{"skills": {"deny": ["acme.*"]}, "allowedCommands": ["ffmpeg", "sox"]}`)
+	user := write("user.json", `{"skills": {"deny": ["other.*"]}, "allowedCommands": ["sox", "curl"]}`)
+	malformed := write("malformed.json", `{"skills": `)
+
+	systemPolicy := skills.Policy{Skills: skills.RuleSet{Deny: []string{"acme.*"}}, AllowedCommands: []string{"ffmpeg", "sox"}}
+	tests := []struct {
+		name    string
+		system  string
+		user    string
+		want    skills.Policy
+		wantErr bool
+	}{
+		{name: "no policies", system: missing, user: missing, want: skills.Policy{}},
+		{name: "system only", system: system, user: missing, want: systemPolicy},
+		{name: "no user path", system: system, want: systemPolicy},
+		{
+			name:   "user narrows system",
+			system: system,
+			user:   user,
+			want:   skills.Policy{Skills: skills.RuleSet{Deny: []string{"acme.*", "other.*"}}, AllowedCommands: []string{"sox"}},
+		},
+		{name: "malformed system locks down", system: malformed, user: user, want: skills.LockdownPolicy(), wantErr: true},
+		{name: "unreadable system locks down", system: unreadable, user: missing, want: skills.LockdownPolicy(), wantErr: true},
+		{name: "malformed user keeps system", system: system, user: malformed, want: systemPolicy, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadPolicy(tt.system, tt.user)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadPolicy error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadPolicy = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"asteria/internal/skills"
+)
+
+// maxSearchLogBytes bounds the search log; when exceeded the oldest half of
+// the events is dropped.
+const maxSearchLogBytes = 4 << 20
+
+// SearchLog appends command bar choices to a local JSON-lines file so ranking
+// changes can be evaluated offline. Callers anonymize events first (see
+// skills.SearchEvent).
+type SearchLog struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewSearchLog() (*SearchLog, error) {
+	dir, err := AppConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return &SearchLog{path: filepath.Join(dir, "search_events.jsonl")}, nil
+}
+
+// OpenSearchLog opens a search log at an explicit path (for the evaluation
+// harness).
+func OpenSearchLog(path string) *SearchLog {
+	return &SearchLog{path: path}
+}
+
+func (s *SearchLog) Path() string {
+	return s.path
+}
+
+func (s *SearchLog) Append(event skills.SearchEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if info, err := os.Stat(s.path); err == nil && info.Size() > maxSearchLogBytes {
+		if err := s.compact(); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// Load reads all events, skipping lines that fail to parse.
+func (s *SearchLog) Load() ([]skills.SearchEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+func (s *SearchLog) load() ([]skills.SearchEvent, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	events := []skills.SearchEvent{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var ev skills.SearchEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		events = append(events, ev)
+	}
+	return events, scanner.Err()
+}
+
+func (s *SearchLog) compact() error {
+	events, err := s.load()
+	if err != nil {
+		return err
+	}
+	events = events[len(events)/2:]
+	var buf bytes.Buffer
+	for _, ev := range events {
+		line, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return os.WriteFile(s.path, buf.Bytes(), 0o644)
+}
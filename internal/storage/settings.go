@@ -4,12 +4,32 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+
+	"asteria/internal/skills"
+	"asteria/internal/tools"
 )
 
 type Settings struct {
 	OutputFolder  string `json:"outputFolder"`
 	NamingPattern string `json:"namingPattern"`
 	AccentColor   string `json:"accentColor"`
+	// Locale selects the language skills are shown and matched in ("de",
+	// "pt-BR"); empty means English only.
+	Locale string `json:"locale,omitempty"`
+	// Ranking overrides individual ranker weights and category priorities.
+	Ranking skills.RankerOverrides `json:"ranking"`
+	// Catalogs lists pack catalog sources: a folder, a catalog.json path or
+	// an HTTP(S) URL.
+	Catalogs []string `json:"catalogs,omitempty"`
+	// Sandbox is how CLI skills are isolated on Linux: "auto" (default),
+	// "required" or "off".
+	Sandbox string `json:"sandbox,omitempty"`
+	// Limits are app-wide maximums for the resource limits CLI skills
+	// declare; a skill without a limit gets the maximum.
+	Limits skills.ResourceLimits `json:"limits"`
+	// Tools maps tool names CLI skills run ("magick") to the executable to
+	// use, instead of whatever PATH finds first.
+	Tools tools.Tools `json:"tools,omitempty"`
 }
 
 type SettingsStore struct {
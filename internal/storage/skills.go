@@ -18,3 +18,17 @@ func SkillsDir() (string, error) {
 	}
 	return dir, nil
 }
+
+// PackStagingDir holds pack archives while they are downloaded, unpacked and
+// validated before moving into SkillsDir.
+func PackStagingDir() (string, error) {
+	base, err := AppConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, "pack-staging")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
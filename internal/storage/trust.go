@@ -2,20 +2,85 @@ package storage
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
+	"time"
+
+	"asteria/internal/skills"
 )
 
 // TrustStore persists user trust decisions for community skills.
 // Core (embedded) skills are implicitly trusted.
+//
+// Approvals are pinned to what the user saw: a skill's definition hash and
+// permissions, or a pack's content hash and permissions. Any edit makes the
+// approval stale until the user reviews the changes.
 type TrustStore struct {
 	path string
 	mu   sync.Mutex
+
+	// once holds "allow once" grants in memory, keyed by subject and the
+	// hash they were granted for; the next run reserves them.
+	onceMu sync.Mutex
+	once   map[string][]string
 }
 
+// GrantScope is how long a permission grant lasts.
+type GrantScope string
+
+const (
+	GrantOnce   GrantScope = "once"
+	GrantAlways GrantScope = "always"
+)
+
 type TrustState struct {
-	TrustedSkills map[string]bool `json:"trustedSkills"`
+	Skills map[string]TrustRecord `json:"skills"`
+	// Packs records grants that apply to every skill of a pack not signed by
+	// a trusted publisher.
+	Packs map[string]PackTrustRecord `json:"packs,omitempty"`
+}
+
+type TrustRecord struct {
+	DefinitionHash string `json:"definitionHash"`
+	// Permissions are the elevated permissions the skill requested when
+	// last approved; Granted those the user allowed always.
+	Permissions []string `json:"permissions"`
+	Granted     []string `json:"granted"`
+	// Definition is the approved definition, kept to diff later edits.
+	Definition json.RawMessage `json:"definition,omitempty"`
+	ApprovedAt time.Time       `json:"approvedAt"`
+}
+
+type PackTrustRecord struct {
+	Version     string    `json:"version"`
+	ContentHash string    `json:"contentHash"`
+	Permissions []string  `json:"permissions"`
+	Granted     []string  `json:"granted"`
+	ApprovedAt  time.Time `json:"approvedAt"`
+}
+
+// TrustReview compares a skill (and its pack) with the user's grants.
+type TrustReview struct {
+	// Trusted is set when every elevated permission is granted.
+	Trusted bool `json:"trusted"`
+	// Outstanding lists the elevated permissions still needing a grant.
+	Outstanding []string `json:"outstanding,omitempty"`
+	Granted     []string `json:"granted,omitempty"`
+	// Stale is set when an approval exists but no longer matches; its
+	// always-grants are void until approved again.
+	Stale              bool                      `json:"stale"`
+	Changes            []skills.DefinitionChange `json:"changes,omitempty"`
+	AddedPermissions   []string                  `json:"addedPermissions,omitempty"`
+	RemovedPermissions []string                  `json:"removedPermissions,omitempty"`
+	// ApprovedVersion is the pack version that was approved (packs only).
+	ApprovedVersion string `json:"approvedVersion,omitempty"`
+	// Steps holds, for a pipeline, the reviews of steps that need grants of
+	// their own, keyed by skill ID; their outstanding permissions are part
+	// of Outstanding.
+	Steps map[string]TrustReview `json:"steps,omitempty"`
 }
 
 func NewTrustStore() (*TrustStore, error) {
@@ -29,10 +94,14 @@ func NewTrustStore() (*TrustStore, error) {
 func (t *TrustStore) Load() (TrustState, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	return t.loadLocked()
+}
+
+func (t *TrustStore) loadLocked() (TrustState, error) {
 	data, err := os.ReadFile(t.path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return TrustState{TrustedSkills: map[string]bool{}}, nil
+			return TrustState{Skills: map[string]TrustRecord{}}, nil
 		}
 		return TrustState{}, err
 	}
@@ -40,17 +109,53 @@ func (t *TrustStore) Load() (TrustState, error) {
 	if err := json.Unmarshal(data, &state); err != nil {
 		return TrustState{}, err
 	}
-	if state.TrustedSkills == nil {
-		state.TrustedSkills = map[string]bool{}
+	if state.Skills == nil {
+		state.Skills = map[string]TrustRecord{}
 	}
+	migrateLegacyTrust(data, &state)
 	return state, nil
 }
 
+// migrateLegacyTrust carries over approvals from before they were pinned to
+// hashes ("trustedSkills" and "trustedPacks"). What was approved is unknown,
+// so they become approvals without a hash: reviews report them as stale and
+// the user approves the current definition again instead of losing track of
+// the decision. The legacy keys are dropped on the next Save.
+func migrateLegacyTrust(data []byte, state *TrustState) {
+	var legacy struct {
+		TrustedSkills map[string]bool `json:"trustedSkills"`
+		TrustedPacks  map[string]bool `json:"trustedPacks"`
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return
+	}
+	for id, trusted := range legacy.TrustedSkills {
+		if _, ok := state.Skills[id]; trusted && !ok {
+			state.Skills[id] = TrustRecord{}
+		}
+	}
+	for id, trusted := range legacy.TrustedPacks {
+		if !trusted {
+			continue
+		}
+		if state.Packs == nil {
+			state.Packs = map[string]PackTrustRecord{}
+		}
+		if _, ok := state.Packs[id]; !ok {
+			state.Packs[id] = PackTrustRecord{}
+		}
+	}
+}
+
 func (t *TrustStore) Save(state TrustState) error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	if state.TrustedSkills == nil {
-		state.TrustedSkills = map[string]bool{}
+	return t.saveLocked(state)
+}
+
+func (t *TrustStore) saveLocked(state TrustState) error {
+	if state.Skills == nil {
+		state.Skills = map[string]TrustRecord{}
 	}
 	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
@@ -59,26 +164,248 @@ func (t *TrustStore) Save(state TrustState) error {
 	return os.WriteFile(t.path, data, 0o644)
 }
 
-func (t *TrustStore) IsTrusted(skillID string) (bool, error) {
+// TrustSubject is a skill to review and the pack it ships in, if any.
+type TrustSubject struct {
+	Skill skills.Skill
+	Pack  *skills.Pack
+}
+
+// Review checks skill's elevated permissions against the grants for the
+// skill and, when it ships in one, its pack.
+func (t *TrustStore) Review(skill skills.Skill, pack *skills.Pack) (TrustReview, error) {
 	state, err := t.Load()
 	if err != nil {
-		return false, err
+		return TrustReview{}, err
 	}
-	return state.TrustedSkills[skillID], nil
+	t.onceMu.Lock()
+	defer t.onceMu.Unlock()
+	return t.review(state, skill, pack), nil
 }
 
-func (t *TrustStore) SetTrusted(skillID string, trusted bool) error {
+// Reserve reviews the subjects of one run together and, when all are
+// trusted, takes their "allow once" grants out of the store, so two runs
+// can't both use them. Call release with whether the run happened: the
+// grants of a run that never started are put back.
+func (t *TrustStore) Reserve(subjects ...TrustSubject) ([]TrustReview, func(ran bool), error) {
 	state, err := t.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	t.onceMu.Lock()
+	defer t.onceMu.Unlock()
+	reviews := make([]TrustReview, 0, len(subjects))
+	trusted := true
+	for _, s := range subjects {
+		review := t.review(state, s.Skill, s.Pack)
+		trusted = trusted && review.Trusted
+		reviews = append(reviews, review)
+	}
+	if !trusted {
+		return reviews, func(bool) {}, nil
+	}
+	taken := make(map[string][]string)
+	for _, s := range subjects {
+		keys := []string{skillKey(s.Skill)}
+		if s.Pack != nil {
+			keys = append(keys, packKey(*s.Pack))
+		}
+		for _, key := range keys {
+			if perms, ok := t.once[key]; ok {
+				taken[key] = perms
+				delete(t.once, key)
+			}
+		}
+	}
+	return reviews, func(ran bool) {
+		if ran {
+			return
+		}
+		for key, perms := range taken {
+			t.addOnce(key, perms)
+		}
+	}, nil
+}
+
+// review compares skill and pack with state and the "allow once" grants;
+// the caller holds onceMu.
+func (t *TrustStore) review(state TrustState, skill skills.Skill, pack *skills.Pack) TrustReview {
+	elevated := skills.ElevatedPermissions(skill.Permissions)
+	granted := make(map[string]bool)
+	var review TrustReview
+
+	if record, ok := state.Skills[skill.ID]; ok {
+		if record.DefinitionHash == skill.DefinitionHash {
+			for _, p := range record.Granted {
+				granted[p] = true
+			}
+		} else {
+			review.Stale = true
+			review.Changes = skills.DiffDefinitions(record.Definition, skill.Definition)
+			review.AddedPermissions, review.RemovedPermissions = skills.PermissionDiff(record.Permissions, elevated)
+		}
+	}
+	for _, p := range t.once[skillKey(skill)] {
+		granted[p] = true
+	}
+
+	if pack != nil {
+		if record, ok := state.Packs[pack.Manifest.ID]; ok {
+			if record.ContentHash == pack.ContentHash {
+				for _, p := range record.Granted {
+					granted[p] = true
+				}
+			} else {
+				review.Stale = true
+				review.ApprovedVersion = record.Version
+				if record.Version != "" && record.Version != pack.Manifest.Version {
+					review.Changes = append(review.Changes, skills.DefinitionChange{
+						Field:  "version",
+						Before: record.Version,
+						After:  pack.Manifest.Version,
+					})
+				}
+				added, removed := skills.PermissionDiff(record.Permissions, skills.ElevatedPermissions(pack.Manifest.Permissions))
+				review.AddedPermissions = append(review.AddedPermissions, added...)
+				review.RemovedPermissions = append(review.RemovedPermissions, removed...)
+			}
+		}
+		for _, p := range t.once[packKey(*pack)] {
+			granted[p] = true
+		}
+	}
+
+	for _, p := range elevated {
+		if granted[p] {
+			review.Granted = append(review.Granted, p)
+		} else {
+			review.Outstanding = append(review.Outstanding, p)
+		}
+	}
+	review.Trusted = len(review.Outstanding) == 0
+	return review
+}
+
+// GrantSkill allows perms (every elevated permission when empty) for the
+// skill's current definition. "always" grants are kept across runs until the
+// definition changes; "once" grants cover the next run only.
+func (t *TrustStore) GrantSkill(skill skills.Skill, perms []string, scope GrantScope) error {
+	elevated := skills.ElevatedPermissions(skill.Permissions)
+	perms, err := grantable(perms, elevated)
+	if err != nil {
+		return fmt.Errorf("skill %s: %w", skill.ID, err)
+	}
+	if scope == GrantOnce {
+		t.addOnce(skillKey(skill), perms)
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, err := t.loadLocked()
+	if err != nil {
+		return err
+	}
+	record := state.Skills[skill.ID]
+	if record.DefinitionHash != skill.DefinitionHash {
+		record = TrustRecord{}
+	}
+	state.Skills[skill.ID] = TrustRecord{
+		DefinitionHash: skill.DefinitionHash,
+		Permissions:    elevated,
+		Granted:        skills.NormalizePermissions(append(record.Granted, perms...)),
+		Definition:     skill.Definition,
+		ApprovedAt:     time.Now(),
+	}
+	return t.saveLocked(state)
+}
+
+func (t *TrustStore) RevokeSkill(skillID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, err := t.loadLocked()
 	if err != nil {
 		return err
 	}
-	if state.TrustedSkills == nil {
-		state.TrustedSkills = map[string]bool{}
+	delete(state.Skills, skillID)
+	return t.saveLocked(state)
+}
+
+// GrantPack allows perms (every elevated permission the pack declares when
+// empty) for all skills of the pack's current contents.
+func (t *TrustStore) GrantPack(pack skills.Pack, perms []string, scope GrantScope) error {
+	elevated := skills.ElevatedPermissions(pack.Manifest.Permissions)
+	perms, err := grantable(perms, elevated)
+	if err != nil {
+		return fmt.Errorf("pack %s: %w", pack.Manifest.ID, err)
+	}
+	if scope == GrantOnce {
+		t.addOnce(packKey(pack), perms)
+		return nil
 	}
-	if trusted {
-		state.TrustedSkills[skillID] = true
-	} else {
-		delete(state.TrustedSkills, skillID)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, err := t.loadLocked()
+	if err != nil {
+		return err
+	}
+	if state.Packs == nil {
+		state.Packs = map[string]PackTrustRecord{}
+	}
+	record := state.Packs[pack.Manifest.ID]
+	if record.ContentHash != pack.ContentHash {
+		record = PackTrustRecord{}
+	}
+	state.Packs[pack.Manifest.ID] = PackTrustRecord{
+		Version:     pack.Manifest.Version,
+		ContentHash: pack.ContentHash,
+		Permissions: elevated,
+		Granted:     skills.NormalizePermissions(append(record.Granted, perms...)),
+		ApprovedAt:  time.Now(),
+	}
+	return t.saveLocked(state)
+}
+
+func (t *TrustStore) RevokePack(packID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, err := t.loadLocked()
+	if err != nil {
+		return err
+	}
+	delete(state.Packs, packID)
+	return t.saveLocked(state)
+}
+
+func (t *TrustStore) addOnce(key string, perms []string) {
+	t.onceMu.Lock()
+	defer t.onceMu.Unlock()
+	if t.once == nil {
+		t.once = make(map[string][]string)
+	}
+	t.once[key] = skills.NormalizePermissions(append(t.once[key], perms...))
+}
+
+func skillKey(skill skills.Skill) string {
+	return "skill:" + skill.ID + "@" + skill.DefinitionHash
+}
+
+func packKey(pack skills.Pack) string {
+	return "pack:" + pack.Manifest.ID + "@" + pack.ContentHash
+}
+
+// grantable checks that every requested permission is an elevated one the
+// subject asks for; an empty request means all of them.
+func grantable(perms []string, elevated []string) ([]string, error) {
+	if len(perms) == 0 {
+		return elevated, nil
+	}
+	perms = skills.NormalizePermissions(perms)
+	for _, p := range perms {
+		if !slices.Contains(elevated, p) {
+			if !skills.IsElevatedPermission(p) {
+				return nil, fmt.Errorf("%s is not an elevated permission", p)
+			}
+			return nil, fmt.Errorf("%s is not requested", p)
+		}
 	}
-	return t.Save(state)
+	return perms, nil
 }
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"asteria/internal/skills"
+)
+
+func newTestTrustStore(t *testing.T) *TrustStore {
+	t.Helper()
+	return &TrustStore{path: filepath.Join(t.TempDir(), "trust.json")}
+}
+
+func communitySkill(id string, perms ...string) skills.Skill {
+	return skills.Skill{
+		ID:             id,
+		Source:         skills.SkillSourceCommunity,
+		Permissions:    perms,
+		DefinitionHash: "hash-" + id,
+	}
+}
+
+func TestTrustStoreConcurrentGrants(t *testing.T) {
+	store := newTestTrustStore(t)
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			skill := communitySkill(fmt.Sprintf("skill-%d", i), skills.PermNetwork)
+			if err := store.GrantSkill(skill, nil, GrantAlways); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	state, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Skills) != 20 {
+		t.Errorf("%d grants recorded, want 20", len(state.Skills))
+	}
+}
+
+func TestTrustStoreReserveOnce(t *testing.T) {
+	store := newTestTrustStore(t)
+	skill := communitySkill("fetch", skills.PermNetwork)
+	if err := store.GrantSkill(skill, nil, GrantOnce); err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent runs: only one gets the grant.
+	var mu sync.Mutex
+	var releases []func(bool)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reviews, release, err := store.Reserve(TrustSubject{Skill: skill})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if reviews[0].Trusted {
+				mu.Lock()
+				releases = append(releases, release)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(releases) != 1 {
+		t.Fatalf("%d runs reserved the once-grant, want 1", len(releases))
+	}
+
+	// A run that never started gives the grant back; one that ran uses it up.
+	releases[0](false)
+	reviews, release, err := store.Reserve(TrustSubject{Skill: skill})
+	if err != nil || !reviews[0].Trusted {
+		t.Fatalf("Reserve after release = %+v, %v, want trusted", reviews, err)
+	}
+	release(true)
+	if reviews, _, _ := store.Reserve(TrustSubject{Skill: skill}); reviews[0].Trusted {
+		t.Error("once-grant still usable after a run")
+	}
+}
+
+func TestTrustStoreReserveAllOrNothing(t *testing.T) {
+	store := newTestTrustStore(t)
+	granted := communitySkill("fetch", skills.PermNetwork)
+	missing := communitySkill("shell", skills.PermSystem)
+	if err := store.GrantSkill(granted, nil, GrantOnce); err != nil {
+		t.Fatal(err)
+	}
+	reviews, _, err := store.Reserve(TrustSubject{Skill: granted}, TrustSubject{Skill: missing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reviews[0].Trusted || reviews[1].Trusted {
+		t.Fatalf("reviews = %+v, want the first trusted only", reviews)
+	}
+	// The refused run must not have used up the first skill's grant.
+	if reviews, _, _ := store.Reserve(TrustSubject{Skill: granted}); !reviews[0].Trusted {
+		t.Error("once-grant was used up by a refused run")
+	}
+}
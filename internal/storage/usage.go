@@ -11,9 +11,13 @@ import (
 )
 
 type UsageStore struct {
-	mu   sync.Mutex
-	path string
-	data map[string]skills.UsageStats
+	mu         sync.Mutex
+	path       string
+	chainsPath string
+	paramsPath string
+	data       map[string]skills.UsageStats
+	chains     skills.ChainStats
+	params     map[string]skills.ParamUsage
 }
 
 func NewUsageStore() (*UsageStore, error) {
@@ -22,10 +26,16 @@ func NewUsageStore() (*UsageStore, error) {
 		return nil, err
 	}
 	store := &UsageStore{
-		path: filepath.Join(dir, "usage_stats.json"),
-		data: make(map[string]skills.UsageStats),
+		path:       filepath.Join(dir, "usage_stats.json"),
+		chainsPath: filepath.Join(dir, "usage_chains.json"),
+		paramsPath: filepath.Join(dir, "usage_params.json"),
+		data:       make(map[string]skills.UsageStats),
+		chains:     make(skills.ChainStats),
+		params:     make(map[string]skills.ParamUsage),
 	}
 	_ = store.load()
+	_ = store.loadChains()
+	_ = store.loadParams()
 	return store, nil
 }
 
@@ -45,6 +55,42 @@ func (u *UsageStore) load() error {
 	return nil
 }
 
+func (u *UsageStore) loadChains() error {
+	data, err := os.ReadFile(u.chainsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var chains skills.ChainStats
+	if err := json.Unmarshal(data, &chains); err != nil {
+		return err
+	}
+	if chains != nil {
+		u.chains = chains
+	}
+	return nil
+}
+
+func (u *UsageStore) loadParams() error {
+	data, err := os.ReadFile(u.paramsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var params map[string]skills.ParamUsage
+	if err := json.Unmarshal(data, &params); err != nil {
+		return err
+	}
+	if params != nil {
+		u.params = params
+	}
+	return nil
+}
+
 func (u *UsageStore) snapshot() map[string]skills.UsageStats {
 	u.mu.Lock()
 	defer u.mu.Unlock()
@@ -59,6 +105,21 @@ func (u *UsageStore) All() map[string]skills.UsageStats {
 	return u.snapshot()
 }
 
+// Chains returns a copy of the learned skill-to-skill transitions.
+func (u *UsageStore) Chains() skills.ChainStats {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	copied := make(skills.ChainStats, len(u.chains))
+	for key, next := range u.chains {
+		inner := make(map[string]skills.UsageStats, len(next))
+		for id, stat := range next {
+			inner[id] = stat
+		}
+		copied[key] = inner
+	}
+	return copied
+}
+
 func (u *UsageStore) Increment(skillID string) error {
 	u.mu.Lock()
 	stat := u.data[skillID]
@@ -72,3 +133,142 @@ func (u *UsageStore) Increment(skillID string) error {
 	}
 	return os.WriteFile(u.path, data, 0o644)
 }
+
+// RecordTransition notes that skillID was applied right after previousSkillID
+// on a file whose extension was inputExt. It is recorded both generically and
+// per extension; an empty previousSkillID marks the first step on a file.
+func (u *UsageStore) RecordTransition(previousSkillID string, inputExt string, skillID string) error {
+	if skillID == "" {
+		return nil
+	}
+	keys := make([]string, 0, 2)
+	if previousSkillID != "" {
+		keys = append(keys, skills.TransitionKey(previousSkillID, ""))
+	}
+	if inputExt != "" {
+		keys = append(keys, skills.TransitionKey(previousSkillID, inputExt))
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	u.mu.Lock()
+	now := time.Now()
+	for _, key := range keys {
+		next := u.chains[key]
+		if next == nil {
+			next = make(map[string]skills.UsageStats)
+			u.chains[key] = next
+		}
+		stat := next[skillID]
+		stat.Count++
+		stat.LastUsed = now
+		next[skillID] = stat
+	}
+	data, err := json.MarshalIndent(u.chains, "", "  ")
+	u.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(u.chainsPath, data, 0o644)
+}
+
+// ParamUsage returns a copy of the learned parameter values for skillID.
+func (u *UsageStore) ParamUsage(skillID string) skills.ParamUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	copied := make(skills.ParamUsage, len(u.params[skillID]))
+	for name, values := range u.params[skillID] {
+		inner := make(map[string]skills.ParamValueStats, len(values))
+		for key, stat := range values {
+			inner[key] = stat
+		}
+		copied[name] = inner
+	}
+	return copied
+}
+
+// maxParamValues is how many unpinned values are kept per param; the ones
+// with the lowest frecency are forgotten first.
+const maxParamValues = 32
+
+// RecordParams bumps the frecency of every value in params for skillID.
+func (u *UsageStore) RecordParams(skillID string, params map[string]any) error {
+	if skillID == "" || len(params) == 0 {
+		return nil
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	now := time.Now()
+	ranker := skills.DefaultRanker()
+	for name, value := range params {
+		if value == nil {
+			continue
+		}
+		values := u.paramValues(skillID, name)
+		key := skills.ParamValueKey(value)
+		stat := values[key]
+		stat.Value = value
+		stat.Count++
+		stat.LastUsed = now
+		// The value just used always stays, even when the others are all
+		// used more.
+		delete(values, key)
+		ranker.PruneParamValues(values, maxParamValues-1)
+		values[key] = stat
+	}
+	return u.saveParamsLocked()
+}
+
+// PinParamValue pins or unpins a value so it is always offered first.
+func (u *UsageStore) PinParamValue(skillID string, param string, value any, pinned bool) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	values := u.paramValues(skillID, param)
+	key := skills.ParamValueKey(value)
+	stat, ok := values[key]
+	if !ok {
+		stat = skills.ParamValueStats{Value: value}
+	}
+	stat.Pinned = pinned
+	if !pinned && stat.Count == 0 {
+		delete(values, key)
+	} else {
+		values[key] = stat
+	}
+	return u.saveParamsLocked()
+}
+
+// ForgetParamValue drops everything learned about a value, including a pin.
+func (u *UsageStore) ForgetParamValue(skillID string, param string, value any) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.paramValues(skillID, param), skills.ParamValueKey(value))
+	return u.saveParamsLocked()
+}
+
+// paramValues returns the mutable value map for a skill param. Callers must
+// hold u.mu.
+func (u *UsageStore) paramValues(skillID string, param string) map[string]skills.ParamValueStats {
+	usage := u.params[skillID]
+	if usage == nil {
+		usage = make(skills.ParamUsage)
+		u.params[skillID] = usage
+	}
+	values := usage[param]
+	if values == nil {
+		values = make(map[string]skills.ParamValueStats)
+		usage[param] = values
+	}
+	return values
+}
+
+// saveParamsLocked persists learned params. Callers must hold u.mu, which
+// also keeps an older snapshot from being written over a newer one.
+func (u *UsageStore) saveParamsLocked() error {
+	data, err := json.MarshalIndent(u.params, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(u.paramsPath, data, 0o644)
+}
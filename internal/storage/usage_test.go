@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"asteria/internal/skills"
+)
+
+func newTestUsageStore(t *testing.T) *UsageStore {
+	t.Helper()
+	dir := t.TempDir()
+	return &UsageStore{
+		path:       filepath.Join(dir, "usage_stats.json"),
+		chainsPath: filepath.Join(dir, "usage_chains.json"),
+		paramsPath: filepath.Join(dir, "usage_params.json"),
+		data:       make(map[string]skills.UsageStats),
+		chains:     make(skills.ChainStats),
+		params:     make(map[string]skills.ParamUsage),
+	}
+}
+
+func TestRecordParamsPrunes(t *testing.T) {
+	store := newTestUsageStore(t)
+	now := time.Now()
+	values := store.paramValues("resize", "label")
+	// Value i was used i times, i hours ago: more uses outweigh the older
+	// last use, so the low values go first.
+	for i := 1; i <= maxParamValues+8; i++ {
+		values[fmt.Sprint(i)] = skills.ParamValueStats{Value: fmt.Sprint(i), Count: i, LastUsed: now.Add(-time.Duration(i) * time.Hour)}
+	}
+	values["old pin"] = skills.ParamValueStats{Value: "old pin", Pinned: true, LastUsed: now.AddDate(-1, 0, 0)}
+
+	if err := store.RecordParams("resize", map[string]any{"label": "brand new", "width": 800.0}); err != nil {
+		t.Fatal(err)
+	}
+	got := store.ParamUsage("resize")
+	labels := got["label"]
+	if len(labels) != maxParamValues+1 {
+		t.Fatalf("kept %d label values, want %d unpinned and the pin", len(labels), maxParamValues)
+	}
+	for _, key := range []string{"brand new", "old pin", "40", "20", "10"} {
+		if _, ok := labels[key]; !ok {
+			t.Errorf("%q was pruned", key)
+		}
+	}
+	for _, key := range []string{"1", "2", "9"} {
+		if _, ok := labels[key]; ok {
+			t.Errorf("%q was kept over more frequent values", key)
+		}
+	}
+	if len(got["width"]) != 1 {
+		t.Errorf("width values = %v", got["width"])
+	}
+
+	data, err := os.ReadFile(store.paramsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]skills.ParamUsage
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved["resize"]["label"]) != len(labels) {
+		t.Errorf("saved %d label values, want %d", len(saved["resize"]["label"]), len(labels))
+	}
+}
+
+func TestRecordParamsSavesLatest(t *testing.T) {
+	store := newTestUsageStore(t)
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.RecordParams("resize", map[string]any{"width": float64(i)}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(store.paramsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]skills.ParamUsage
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	got := store.ParamUsage("resize")
+	if len(saved["resize"]["width"]) != 20 || !reflect.DeepEqual(keys(saved["resize"]["width"]), keys(got["width"])) {
+		t.Errorf("saved %d values, in memory %d", len(saved["resize"]["width"]), len(got["width"]))
+	}
+}
+
+func keys[V any](m map[string]V) map[string]struct{} {
+	out := make(map[string]struct{}, len(m))
+	for k := range m {
+		out[k] = struct{}{}
+	}
+	return out
+}
+
+func TestRecordTransition(t *testing.T) {
+	store := newTestUsageStore(t)
+	steps := []struct{ prev, ext, skill string }{
+		{"", ".PNG", "resize"},
+		{"resize", ".PNG", "compress"},
+		{"resize", ".jpg", "compress"},
+		{"resize", "", "sharpen"},
+		{"", "", "rotate"},
+		{"resize", ".png", ""},
+	}
+	for _, s := range steps {
+		if err := store.RecordTransition(s.prev, s.ext, s.skill); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counts := func(chains skills.ChainStats) map[string]map[string]int {
+		out := make(map[string]map[string]int, len(chains))
+		for key, next := range chains {
+			out[key] = make(map[string]int, len(next))
+			for id, stat := range next {
+				out[key][id] = stat.Count
+			}
+		}
+		return out
+	}
+	want := map[string]map[string]int{
+		".png|":       {"resize": 1},
+		"resize":      {"compress": 2, "sharpen": 1},
+		".png|resize": {"compress": 1},
+		".jpg|resize": {"compress": 1},
+	}
+	if got := counts(store.Chains()); !reflect.DeepEqual(got, want) {
+		t.Errorf("chains = %v, want %v", got, want)
+	}
+
+	data, err := os.ReadFile(store.chainsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved skills.ChainStats
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if got := counts(saved); !reflect.DeepEqual(got, want) {
+		t.Errorf("saved chains = %v, want %v", got, want)
+	}
+
+	// Chains hands out a copy.
+	store.Chains()["resize"]["compress"] = skills.UsageStats{Count: 99}
+	if got := store.Chains()["resize"]["compress"].Count; got != 2 {
+		t.Errorf("chains changed through a copy: count = %d", got)
+	}
+}
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// installHints tell users where to get the tools core skills use.
+var installHints = map[string]string{
+	"magick":   "Install ImageMagick 7 (https://imagemagick.org)",
+	"convert":  "Install ImageMagick (https://imagemagick.org)",
+	"identify": "Install ImageMagick (https://imagemagick.org)",
+	"ffmpeg":   "Install FFmpeg (https://ffmpeg.org)",
+	"ffprobe":  "Install FFmpeg (https://ffmpeg.org)",
+}
+
+// Check resolves name and, when constraint is set, verifies its version.
+func (t Tools) Check(name string, constraint string) error {
+	path, err := t.Resolve(name)
+	if err != nil {
+		return err
+	}
+	if constraint = strings.TrimSpace(constraint); constraint == "" {
+		return nil
+	}
+	version, err := ProbeVersion(Name(name), path)
+	if err != nil {
+		return err
+	}
+	if !MatchVersion(version, constraint) {
+		return fmt.Errorf("%s: version %s found, %s required", Name(name), version, constraint)
+	}
+	return nil
+}
+
+// Locate checks that name resolves to an executable without running it, for
+// tools whose version must not be probed. Registered tools are verified as
+// by Resolve.
+func (t Tools) Locate(name string) error {
+	if _, ok := t.Lookup(name); ok || !filepath.IsAbs(name) {
+		_, err := t.Resolve(name)
+		return err
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", Name(name), err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s: %s is a directory", Name(name), name)
+	}
+	return nil
+}
+
+// InstallHint tells the user how to make a missing tool available.
+func InstallHint(name string) string {
+	name = Name(name)
+	hint, ok := installHints[name]
+	if !ok {
+		hint = "Install " + name
+	}
+	return hint + ", or register its path under tools in settings."
+}
@@ -0,0 +1,167 @@
+// Package tools resolves the external programs CLI skills run.
+//
+// Users register tools in settings ("magick" → "/opt/im7/bin/magick") with an
+// optional version constraint or checksum; unregistered tools are looked up
+// on PATH, skipping entries a user or other programs can easily write to.
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tool is one entry of the tools registry in settings.
+type Tool struct {
+	// Path is the absolute path of the executable.
+	Path string `json:"path"`
+	// Version is an optional constraint the installed version must meet
+	// (">=6.0", "7.1").
+	Version string `json:"version,omitempty"`
+	// SHA256 optionally pins the executable's contents.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Tools maps tool names to their registered executables.
+type Tools map[string]Tool
+
+// Names lists the registered tool names.
+func (t Tools) Names() []string {
+	out := make([]string, 0, len(t))
+	for name := range t {
+		out = append(out, name)
+	}
+	return out
+}
+
+// Lookup returns the registry entry for a command name, ignoring case and a
+// Windows ".exe" suffix.
+func (t Tools) Lookup(name string) (Tool, bool) {
+	key := Name(name)
+	for n, tool := range t {
+		if Name(n) == key {
+			return tool, true
+		}
+	}
+	return Tool{}, false
+}
+
+// Name normalizes a command to the name tools are registered under.
+func Name(command string) string {
+	return strings.TrimSuffix(strings.ToLower(filepath.Base(command)), ".exe")
+}
+
+// Resolve returns the absolute path to run for command. Registered tools
+// are checked against their checksum and version; others are searched on
+// the safe part of PATH.
+func (t Tools) Resolve(command string) (string, error) {
+	if tool, ok := t.Lookup(command); ok {
+		return tool.verify(Name(command))
+	}
+	if filepath.IsAbs(command) {
+		return command, nil
+	}
+	if strings.ContainsAny(command, `/\`) {
+		return "", fmt.Errorf("%s: relative command paths are not allowed", command)
+	}
+	return SafeLookPath(command)
+}
+
+func (tool Tool) verify(name string) (string, error) {
+	if !filepath.IsAbs(tool.Path) {
+		return "", fmt.Errorf("%s: registered path %q is not absolute", name, tool.Path)
+	}
+	info, err := os.Stat(tool.Path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s: %s is a directory", name, tool.Path)
+	}
+	if want := strings.TrimSpace(tool.SHA256); want != "" {
+		// Hashed on every resolve: size and mtime are easy to keep while
+		// swapping the binary.
+		got, err := checksum(tool.Path)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		if !strings.EqualFold(got, want) {
+			return "", fmt.Errorf("%s: checksum of %s does not match the registered one", name, tool.Path)
+		}
+	}
+	if constraint := strings.TrimSpace(tool.Version); constraint != "" {
+		version, err := ProbeVersion(name, tool.Path)
+		if err != nil {
+			return "", err
+		}
+		if !MatchVersion(version, constraint) {
+			return "", fmt.Errorf("%s: version %s does not satisfy %s", name, version, constraint)
+		}
+	}
+	return tool.Path, nil
+}
+
+// SafeLookPath searches PATH for name, skipping relative entries and
+// entries inside the home or temp directory, where a stray or hostile
+// binary could shadow the system one. Register a tool to use such a copy.
+func SafeLookPath(name string) (string, error) {
+	home, _ := os.UserHomeDir()
+	temp := os.TempDir()
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" || !filepath.IsAbs(dir) || within(dir, home) || within(dir, temp) {
+			continue
+		}
+		for _, candidate := range executableNames(name) {
+			p := filepath.Join(dir, candidate)
+			if path, err := exec.LookPath(p); err == nil {
+				return path, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s: not found on PATH", name)
+}
+
+func executableNames(name string) []string {
+	if runtime.GOOS != "windows" || filepath.Ext(name) != "" {
+		return []string{name}
+	}
+	return []string{name + ".exe", name + ".cmd", name + ".bat"}
+}
+
+func within(dir string, root string) bool {
+	if root == "" {
+		return false
+	}
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// fileKey identifies a file version for the version cache.
+type fileKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+var cacheMu sync.Mutex
+
+func checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,215 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeTool writes a script printing version to dir/name.
+func writeTool(t *testing.T, dir string, name string, version string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	script := "#!/bin/sh\necho '" + name + " version " + version + " Copyright (c) the developers'\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func sha256File(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestResolve(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as tools")
+	}
+	dir := t.TempDir()
+	ffmpeg := writeTool(t, filepath.Join(dir, "opt"), "ffmpeg", "6.1.2")
+	magick := writeTool(t, filepath.Join(dir, "opt"), "magick", "7.1.1-29")
+	sum := sha256File(t, ffmpeg)
+	t.Setenv("PATH", filepath.Join(dir, "opt"))
+	// The test's temp dir would otherwise be skipped as the temp directory.
+	t.Setenv("TMPDIR", filepath.Join(dir, "tmp"))
+
+	tests := []struct {
+		name    string
+		tools   Tools
+		command string
+		want    string
+		wantErr string
+	}{
+		{name: "registered", tools: Tools{"ffmpeg": {Path: ffmpeg}}, command: "ffmpeg", want: ffmpeg},
+		{name: "registered name ignores case and exe", tools: Tools{"FFmpeg": {Path: ffmpeg}}, command: "FFMPEG.exe", want: ffmpeg},
+		{name: "registered overrides absolute command", tools: Tools{"ffmpeg": {Path: ffmpeg}}, command: "/usr/bin/ffmpeg", want: ffmpeg},
+		{name: "checksum matches", tools: Tools{"ffmpeg": {Path: ffmpeg, SHA256: strings.ToUpper(sum)}}, command: "ffmpeg", want: ffmpeg},
+		{name: "checksum mismatch", tools: Tools{"magick": {Path: magick, SHA256: sum}}, command: "magick", wantErr: "checksum"},
+		{name: "relative registered path", tools: Tools{"ffmpeg": {Path: "opt/ffmpeg"}}, command: "ffmpeg", wantErr: "not absolute"},
+		{name: "registered directory", tools: Tools{"ffmpeg": {Path: dir}}, command: "ffmpeg", wantErr: "directory"},
+		{name: "registered missing", tools: Tools{"ffmpeg": {Path: filepath.Join(dir, "missing")}}, command: "ffmpeg", wantErr: "no such file"},
+		{name: "version met", tools: Tools{"magick": {Path: magick, Version: ">=7.0, <8"}}, command: "magick", want: magick},
+		{name: "version not met", tools: Tools{"ffmpeg": {Path: ffmpeg, Version: ">=7"}}, command: "ffmpeg", wantErr: "does not satisfy"},
+		{name: "unregistered absolute", command: "/usr/local/bin/tool", want: "/usr/local/bin/tool"},
+		{name: "relative command path", command: "opt/ffmpeg", wantErr: "relative command paths"},
+		{name: "dot relative command path", command: "./ffmpeg", wantErr: "relative command paths"},
+		{name: "found on path", command: "ffmpeg", want: ffmpeg},
+		{name: "not on path", command: "sox", wantErr: "not found on PATH"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.tools.Resolve(tt.command)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve(%q) = %q, %v, want error containing %q", tt.command, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Resolve(%q) = %q, %v, want %q", tt.command, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeLookPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as tools")
+	}
+	base := t.TempDir()
+	home := filepath.Join(base, "home")
+	temp := filepath.Join(base, "tmp")
+	t.Setenv("HOME", home)
+	t.Setenv("TMPDIR", temp)
+	t.Chdir(base)
+
+	system := filepath.Join(base, "usr", "bin")
+	inHome := filepath.Join(home, ".local", "bin")
+	inTemp := filepath.Join(temp, "bin")
+	for _, dir := range []string{system, inHome, inTemp, filepath.Join(base, "rel")} {
+		writeTool(t, dir, "shadowed", "1.0")
+	}
+	writeTool(t, system, "systemonly", "1.0")
+	for _, dir := range []string{inHome, inTemp, filepath.Join(base, "rel")} {
+		writeTool(t, dir, "unsafeonly", "1.0")
+	}
+	// A plain file that is not executable is passed over.
+	local := filepath.Join(base, "usr", "local", "bin")
+	if err := os.MkdirAll(local, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(local, "notexec"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeTool(t, system, "notexec", "1.0")
+
+	tests := []struct {
+		name    string
+		path    []string
+		command string
+		want    string
+	}{
+		{"home, temp and relative entries are skipped", []string{"rel", "", inHome, inTemp, system}, "shadowed", filepath.Join(system, "shadowed")},
+		{"only unsafe entries", []string{"rel", inHome, inTemp}, "unsafeonly", ""},
+		{"system entry", []string{inHome, system}, "systemonly", filepath.Join(system, "systemonly")},
+		{"home dir itself", []string{home, inHome}, "shadowed", ""},
+		{"non-executable skipped", []string{local, system}, "notexec", filepath.Join(system, "notexec")},
+		{"missing", []string{system}, "sox", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PATH", strings.Join(tt.path, string(os.PathListSeparator)))
+			got, err := SafeLookPath(tt.command)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("SafeLookPath(%q) = %q, want not found", tt.command, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("SafeLookPath(%q) = %q, %v, want %q", tt.command, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithin(t *testing.T) {
+	tests := []struct {
+		dir, root string
+		want      bool
+	}{
+		{"/home/me/bin", "/home/me", true},
+		{"/home/me", "/home/me", true},
+		{"/home/me/../me/bin", "/home/me", true},
+		{"/home/meadow/bin", "/home/me", false},
+		{"/usr/bin", "/home/me", false},
+		{"/usr/bin", "", false},
+	}
+	for _, tt := range tests {
+		if got := within(tt.dir, tt.root); got != tt.want {
+			t.Errorf("within(%q, %q) = %v, want %v", tt.dir, tt.root, got, tt.want)
+		}
+	}
+}
+
+func TestMatchVersion(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"6.1.2", "", true},
+		{"6.1.2", ">=6.0", true},
+		{"5.9", ">=6.0", false},
+		{"6.0", ">6.0", false},
+		{"6.0.1", ">6.0", true},
+		{"7.1", "<7.1", false},
+		{"7.0.9", "<7.1", true},
+		{"7.1", "<=7.1", true},
+		{"7.1", "=7.1", true},
+		{"7.1.1", "=7.1", false},
+		{"7.1.1", "7.1", true},
+		{"7.10", "7.1", false},
+		{"7.1.1", "7.x", true},
+		{"8.0", "7.*", false},
+		{"6.1", ">=6.0, <7", true},
+		{"7.0", ">=6.0 <7", false},
+		{"10.2", ">=9.0", true},
+	}
+	for _, tt := range tests {
+		if got := MatchVersion(tt.version, tt.constraint); got != tt.want {
+			t.Errorf("MatchVersion(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func TestProbeVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as tools")
+	}
+	dir := t.TempDir()
+	ffmpeg := writeTool(t, dir, "ffmpeg", "6.1.2")
+	if got, err := ProbeVersion("ffmpeg", ffmpeg); err != nil || got != "6.1.2" {
+		t.Errorf("ProbeVersion = %q, %v, want 6.1.2", got, err)
+	}
+	silent := filepath.Join(dir, "silent")
+	if err := os.WriteFile(silent, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ProbeVersion("silent", silent); err == nil {
+		t.Errorf("ProbeVersion(silent) = %q, want an error", got)
+	}
+}
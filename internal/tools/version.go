@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"asteria/internal/sandbox"
+	"asteria/internal/skills"
+)
+
+// versionArgs are the flags that print a tool's version; others use
+// --version.
+var versionArgs = map[string][]string{
+	"ffmpeg":   {"-version"},
+	"ffprobe":  {"-version"},
+	"magick":   {"-version"},
+	"convert":  {"-version"},
+	"identify": {"-version"},
+}
+
+var versionPattern = regexp.MustCompile(`\d+\.\d+(?:\.\d+)?`)
+
+var versions = map[fileKey]string{}
+
+// ProbeVersion runs the tool's version flag, with the scrubbed environment of
+// unsandboxed commands, and extracts the first dotted version number.
+// Results are cached per executable file.
+func ProbeVersion(name string, path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	key := fileKey{path, info.Size(), info.ModTime()}
+	cacheMu.Lock()
+	version, ok := versions[key]
+	cacheMu.Unlock()
+	if ok {
+		return version, nil
+	}
+
+	args, ok := versionArgs[Name(name)]
+	if !ok {
+		args = []string{"--version"}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = sandbox.DirectEnv()
+	out, _ := cmd.CombinedOutput()
+	version = versionPattern.FindString(string(out))
+	if version == "" {
+		return "", fmt.Errorf("%s: could not determine the version of %s", name, path)
+	}
+	cacheMu.Lock()
+	versions[key] = version
+	cacheMu.Unlock()
+	return version, nil
+}
+
+// MatchVersion reports whether version meets constraint: clauses separated
+// by commas or spaces, each ">=1.2", ">1.2", "<=1.2", "<2", "=1.2" or a bare
+// "1.2" / "1.x" matching that version and its patch releases.
+func MatchVersion(version string, constraint string) bool {
+	clauses := strings.FieldsFunc(constraint, func(r rune) bool { return r == ',' || r == ' ' })
+	for _, clause := range clauses {
+		if !matchClause(version, clause) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchClause(version string, clause string) bool {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if !strings.HasPrefix(clause, op) {
+			continue
+		}
+		cmp := skills.CompareVersions(version, strings.TrimPrefix(clause, op))
+		switch op {
+		case ">=":
+			return cmp >= 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		case "<":
+			return cmp < 0
+		default:
+			return cmp == 0
+		}
+	}
+	prefix := strings.TrimSuffix(strings.TrimSuffix(clause, ".x"), ".*")
+	return version == prefix || strings.HasPrefix(version, prefix+".")
+}
@@ -5,13 +5,21 @@ import (
 	"log"
 	"net/http"
 
+	"asteria/internal/sandbox"
+
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 //go:embed all:frontend/dist all:skills/core
 var assets embed.FS
 
+// appVersion is compared against the minAppVersion of skill packs.
+const appVersion = "0.1.0"
+
 func main() {
+	// CLI skills re-run this binary to set up their sandbox.
+	sandbox.MaybeRunHelper()
+
 	// Create the App instance
 	appInstance := NewApp()
 